@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// annualReportTopFindings is how many of the most frequent findings of each
+// kind the report lists.
+const annualReportTopFindings = 10
+
+type annualReportQuery struct {
+	Year int
+	// OGRNs limits the report to these organizations; empty means all.
+	OGRNs []string
+}
+
+type annualReportTotals struct {
+	Inspections    int
+	Completed      int
+	Organizations  int
+	AvgWorkingDays *float64
+}
+
+type annualReportFinding struct {
+	Area        string
+	Item        string
+	Count       int
+	Inspections int
+}
+
+type annualReportOrganization struct {
+	OGRN        string
+	Name        string
+	Inspections int
+	Violations  int
+}
+
+type annualReport struct {
+	Query         annualReportQuery
+	Totals        annualReportTotals
+	ByQuarter     map[string][4]int
+	Violations    []annualReportFinding
+	Deficiencies  []annualReportFinding
+	Organizations []annualReportOrganization
+}
+
+// annualReportWhere selects the inspections started in the year, optionally
+// of the chosen organizations only.
+func annualReportWhere(query annualReportQuery) (string, []any) {
+	where, args := statsWhere(statsQuery{
+		From: time.Date(query.Year, time.January, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(query.Year, time.December, 31, 0, 0, 0, 0, time.UTC),
+	})
+	if len(query.OGRNs) > 0 {
+		where += " AND org.organization_ogrn IN (" + placeholders(len(query.OGRNs)) + ")"
+		for _, ogrn := range query.OGRNs {
+			args = append(args, ogrn)
+		}
+	}
+	return where, args
+}
+
+func (s *server) buildAnnualReport(ctx context.Context, query annualReportQuery) (annualReport, error) {
+	report := annualReport{Query: query, ByQuarter: make(map[string][4]int)}
+	where, args := annualReportWhere(query)
+
+	var avg sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(CASE WHEN `+inspectionStatusExpr+` = 'completed' THEN 1 END),
+			COUNT(DISTINCT org.organization_ogrn),
+			AVG(`+statsWorkingDaysExpr+`)
+		`+inspectionFromClause+`
+		`+where, args...).Scan(&report.Totals.Inspections, &report.Totals.Completed, &report.Totals.Organizations, &avg)
+	if err != nil {
+		return annualReport{}, err
+	}
+	report.Totals.AvgWorkingDays = roundedAverage(avg)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT insp.inspection_type, QUARTER(insp.date_start), COUNT(*)
+		`+inspectionFromClause+`
+		`+where+`
+		GROUP BY insp.inspection_type, QUARTER(insp.date_start)
+	`, args...)
+	if err != nil {
+		return annualReport{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			formType string
+			quarter  int
+			count    int
+		)
+		if err := rows.Scan(&formType, &quarter, &count); err != nil {
+			return annualReport{}, err
+		}
+		counts := report.ByQuarter[formType]
+		counts[quarter-1] = count
+		report.ByQuarter[formType] = counts
+	}
+	if err := rows.Err(); err != nil {
+		return annualReport{}, err
+	}
+
+	if report.Violations, err = s.annualReportFindings(ctx, query, findingKindViolation); err != nil {
+		return annualReport{}, err
+	}
+	if report.Deficiencies, err = s.annualReportFindings(ctx, query, findingKindDeficiency); err != nil {
+		return annualReport{}, err
+	}
+	if report.Organizations, err = s.annualReportOrganizations(ctx, query); err != nil {
+		return annualReport{}, err
+	}
+	return report, nil
+}
+
+func (s *server) annualReportFindings(ctx context.Context, query annualReportQuery, kind string) ([]annualReportFinding, error) {
+	where, args := annualReportWhere(query)
+	args = append([]any{kind}, args...)
+	args = append(args, annualReportTopFindings)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT finding.area, finding.item, COUNT(*) AS findings_count, COUNT(DISTINCT act.id)
+		`+inspectionFromClause+`
+		INNER JOIN inspection_finding finding ON finding.act_id = act.id AND finding.kind = ?
+		`+where+`
+		GROUP BY finding.area, finding.item
+		ORDER BY findings_count DESC, finding.area, finding.item
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []annualReportFinding
+	for rows.Next() {
+		var item annualReportFinding
+		if err := rows.Scan(&item.Area, &item.Item, &item.Count, &item.Inspections); err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, rows.Err()
+}
+
+func (s *server) annualReportOrganizations(ctx context.Context, query annualReportQuery) ([]annualReportOrganization, error) {
+	where, args := annualReportWhere(query)
+	args = append([]any{findingKindViolation}, args...)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			org.organization_ogrn,
+			MAX(COALESCE(NULLIF(org.organization_short_name, ''), org.organization_full_name)),
+			COUNT(*),
+			COALESCE(SUM(violations.total), 0)
+		`+inspectionFromClause+`
+		LEFT JOIN (
+			SELECT act_id, COUNT(*) AS total
+			FROM inspection_finding
+			WHERE kind = ?
+			GROUP BY act_id
+		) violations ON violations.act_id = act.id
+		`+where+`
+		GROUP BY org.organization_ogrn
+		ORDER BY 2
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []annualReportOrganization
+	for rows.Next() {
+		var item annualReportOrganization
+		if err := rows.Scan(&item.OGRN, &item.Name, &item.Inspections, &item.Violations); err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, rows.Err()
+}
+
+// annualReportBlocks lays the report out as DOCX paragraphs and tables.
+func annualReportBlocks(report annualReport, now time.Time) []docxBlock {
+	scope := "все организации"
+	if len(report.Query.OGRNs) > 0 {
+		scope = "ОГРН " + strings.Join(report.Query.OGRNs, ", ")
+	}
+	avg := "—"
+	if report.Totals.AvgWorkingDays != nil {
+		avg = strconv.FormatFloat(*report.Totals.AvgWorkingDays, 'f', 1, 64)
+	}
+
+	blocks := []docxBlock{
+		{Text: fmt.Sprintf("ГОДОВОЙ ОТЧЕТ О ПРОВЕДЕННЫХ ПРОВЕРКАХ ЗА %d ГОД", report.Query.Year), Bold: true},
+		{Text: "Охват: " + scope},
+		{Text: "Сформирован: " + now.Format("02.01.2006")},
+		{},
+		{Text: fmt.Sprintf("Всего проверок: %d, из них завершено: %d", report.Totals.Inspections, report.Totals.Completed)},
+		{Text: fmt.Sprintf("Проверено организаций: %d", report.Totals.Organizations)},
+		{Text: "Средняя продолжительность проверки, раб. дней: " + avg},
+		{},
+		{Text: "1. Проверки по формам и кварталам", Bold: true},
+	}
+
+	quarterTable := [][]string{{"Форма проверки", "I кв.", "II кв.", "III кв.", "IV кв.", "Всего"}}
+	var columnTotals [4]int
+	for _, formType := range inspectionFormTypes {
+		counts := report.ByQuarter[formType]
+		row := []string{formType}
+		total := 0
+		for i, count := range counts {
+			row = append(row, strconv.Itoa(count))
+			columnTotals[i] += count
+			total += count
+		}
+		quarterTable = append(quarterTable, append(row, strconv.Itoa(total)))
+	}
+	totalRow := []string{"Итого"}
+	grandTotal := 0
+	for _, count := range columnTotals {
+		totalRow = append(totalRow, strconv.Itoa(count))
+		grandTotal += count
+	}
+	quarterTable = append(quarterTable, append(totalRow, strconv.Itoa(grandTotal)))
+	blocks = append(blocks, docxBlock{Table: quarterTable}, docxBlock{})
+
+	blocks = append(blocks, docxBlock{Text: "2. Наиболее частые нарушения", Bold: true})
+	blocks = append(blocks, annualReportFindingBlocks(report.Violations, "Нарушения не зарегистрированы")...)
+	blocks = append(blocks, docxBlock{Text: "3. Наиболее частые недостатки", Bold: true})
+	blocks = append(blocks, annualReportFindingBlocks(report.Deficiencies, "Недостатки не зарегистрированы")...)
+
+	blocks = append(blocks, docxBlock{Text: "4. Проверенные организации", Bold: true})
+	if len(report.Organizations) == 0 {
+		blocks = append(blocks, docxBlock{Text: "Проверок за год нет"})
+	} else {
+		table := [][]string{{"ОГРН", "Организация", "Проверок", "Нарушений"}}
+		for _, item := range report.Organizations {
+			table = append(table, []string{item.OGRN, item.Name, strconv.Itoa(item.Inspections), strconv.Itoa(item.Violations)})
+		}
+		blocks = append(blocks, docxBlock{Table: table}, docxBlock{})
+	}
+	return blocks
+}
+
+func annualReportFindingBlocks(items []annualReportFinding, empty string) []docxBlock {
+	if len(items) == 0 {
+		return []docxBlock{{Text: empty}, {}}
+	}
+	table := [][]string{{"№", "Область проверки", "Пункт", "Выявлено", "Проверок"}}
+	for i, item := range items {
+		table = append(table, []string{
+			strconv.Itoa(i + 1),
+			item.Area,
+			formatValue(item.Item),
+			strconv.Itoa(item.Count),
+			strconv.Itoa(item.Inspections),
+		})
+	}
+	return []docxBlock{{Table: table}, {}}
+}
+
+func buildAnnualReportDocx(report annualReport, now time.Time) ([]byte, error) {
+	documentXML, err := buildDocxBlocksXML(annualReportBlocks(report, now))
+	if err != nil {
+		return nil, err
+	}
+	return packDocx(documentXML)
+}
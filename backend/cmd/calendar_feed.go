@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	icsContentType = "text/calendar; charset=utf-8"
+	icsDateLayout  = "20060102"
+	icsTimeLayout  = "20060102T150405Z"
+	// icsLineLimit is the maximum line length in octets before folding
+	// (RFC 5545, section 3.1).
+	icsLineLimit = 75
+	// calendarFeedYears is how many years back the feed goes; older
+	// inspections are of no use in a calendar app.
+	calendarFeedYears = 2
+)
+
+type calendarEvent struct {
+	ActID        int
+	Number       string
+	FormType     string
+	Organization string
+	Addresses    []string
+	Start        time.Time
+	End          time.Time
+	UpdatedAt    time.Time
+	inspectors   []string
+}
+
+// calendarFeedToken returns the secret token of the user's feed, creating it
+// on first use.
+func (s *server) calendarFeedToken(ctx context.Context, userID int) (string, error) {
+	var token sql.NullString
+	err := s.db.QueryRowContext(ctx, "SELECT calendar_token FROM users WHERE id=?", userID).Scan(&token)
+	if err != nil {
+		return "", err
+	}
+	if token.Valid && token.String != "" {
+		return token.String, nil
+	}
+	return s.resetCalendarFeedToken(ctx, userID)
+}
+
+// resetCalendarFeedToken issues a new token; the old feed URL stops working.
+func (s *server) resetCalendarFeedToken(ctx context.Context, userID int) (string, error) {
+	token, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+	result, err := s.db.ExecContext(ctx, "UPDATE users SET calendar_token=? WHERE id=?", token, userID)
+	if err != nil {
+		return "", err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return "", sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (s *server) userByCalendarToken(ctx context.Context, token string) (user, error) {
+	var item user
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, fio, login, role
+		FROM users
+		WHERE calendar_token=? AND role <> 'no_access'
+	`, token).Scan(&item.ID, &item.Name, &item.Login, &item.Role)
+	return item, err
+}
+
+// calendarEventsFor returns the dated inspections where name is among the
+// inspectors. The feed is built on every request, so edits show up on the
+// next poll of the calendar app.
+func (s *server) calendarEventsFor(ctx context.Context, name string, since time.Time) ([]calendarEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			act.id,
+			COALESCE(insp.inspection_number, ''),
+			insp.inspection_type,
+			COALESCE(NULLIF(org.organization_short_name, ''), org.organization_full_name),
+			insp.addresses,
+			insp.authorized_persons,
+			insp.date_start,
+			COALESCE(insp.date_early_end, insp.date_end, insp.date_start),
+			act.updated_at
+		`+inspectionFromClause+`
+		WHERE insp.date_start IS NOT NULL
+			AND COALESCE(insp.date_early_end, insp.date_end, insp.date_start) >= ?
+		ORDER BY insp.date_start, act.id
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	normalized := normalizeEmployeeName(name)
+	var events []calendarEvent
+	for rows.Next() {
+		var (
+			event          calendarEvent
+			addressesJSON  []byte
+			inspectorsJSON []byte
+		)
+		if err := rows.Scan(
+			&event.ActID,
+			&event.Number,
+			&event.FormType,
+			&event.Organization,
+			&addressesJSON,
+			&inspectorsJSON,
+			&event.Start,
+			&event.End,
+			&event.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if event.inspectors, err = unmarshalStringSlice(inspectorsJSON); err != nil {
+			return nil, err
+		}
+		if !hasInspector(event.inspectors, normalized) {
+			continue
+		}
+		if event.Addresses, err = unmarshalStringSlice(addressesJSON); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// buildICS renders events as an iCalendar document. Inspections are all-day
+// events; DTEND is exclusive, hence the day after the last inspection day.
+// LAST-MODIFIED follows updated_at so that clients replace edited events.
+func buildICS(calendarName string, events []calendarEvent, now time.Time) string {
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//DocGenerationWebApp//Inspections//RU")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:"+escapeICSText(calendarName))
+	writeICSLine(&b, "X-PUBLISHED-TTL:PT1H")
+	for _, event := range events {
+		description := []string{
+			"Форма проверки: " + event.FormType,
+			"Организация: " + event.Organization,
+		}
+		if event.Number != "" {
+			description = append(description, "Номер проверки: "+event.Number)
+		}
+		if len(event.Addresses) > 0 {
+			description = append(description, "Адреса: "+strings.Join(event.Addresses, "; "))
+		}
+		description = append(description, "Инспекторы: "+strings.Join(event.inspectors, ", "))
+
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, fmt.Sprintf("UID:act-%d@docgeneration", event.ActID))
+		writeICSLine(&b, "DTSTAMP:"+now.UTC().Format(icsTimeLayout))
+		writeICSLine(&b, "LAST-MODIFIED:"+event.UpdatedAt.UTC().Format(icsTimeLayout))
+		writeICSLine(&b, "DTSTART;VALUE=DATE:"+event.Start.Format(icsDateLayout))
+		writeICSLine(&b, "DTEND;VALUE=DATE:"+event.End.AddDate(0, 0, 1).Format(icsDateLayout))
+		writeICSLine(&b, "SUMMARY:"+escapeICSText(fmt.Sprintf("Проверка: %s (%s)", event.Organization, event.FormType)))
+		if len(event.Addresses) > 0 {
+			writeICSLine(&b, "LOCATION:"+escapeICSText(strings.Join(event.Addresses, "; ")))
+		}
+		writeICSLine(&b, "DESCRIPTION:"+escapeICSText(strings.Join(description, "\n")))
+		writeICSLine(&b, "TRANSP:OPAQUE")
+		writeICSLine(&b, "END:VEVENT")
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+func escapeICSText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+// writeICSLine writes a content line folded at icsLineLimit octets without
+// splitting UTF-8 sequences; continuation lines start with a space.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isUTF8Start(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsLineLimit - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isUTF8Start(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	// The runtime image has no zoneinfo for REMINDER_TIME_ZONE.
+	_ "time/tzdata"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/dadata"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/mail"
+)
+
+const (
+	dadataCacheMySQL = "mysql"
+	dadataCacheFile  = "file"
+	dadataCacheNone  = "none"
+)
+
+type serverConfig struct {
+	// FullTextSearch switches the registry search between the MySQL FULLTEXT
+	// index and a plain LIKE scan (used where the ngram parser is unavailable).
+	FullTextSearch bool
+	// InspectorOverlap decides what happens when an inspector is assigned to
+	// overlapping on-site inspections: "warn" saves the inspection and lists
+	// the conflicts in the response, "reject" refuses to save it.
+	InspectorOverlap string
+	// SMTP is the outbound mail server; email is disabled when Host is empty.
+	// Mailpit or any other local fake works with TLS "none".
+	SMTP mail.Config
+	// DeadlineRulesFile overrides the built-in deadline rules with a JSON file.
+	DeadlineRulesFile string
+	// ReminderInterval is how often due and overdue deadlines are mailed;
+	// zero turns the scheduler off.
+	ReminderInterval time.Duration
+	// ReminderEmailTo receives every reminder in addition to the inspectors.
+	ReminderEmailTo []string
+	// ReminderTimeZone is the zone whose midnight starts the day deadlines
+	// are counted for; the server zone (TZ) by default.
+	ReminderTimeZone *time.Location
+	// IdempotencyTTL is how long a stored response is replayed for retries
+	// with the same Idempotency-Key.
+	IdempotencyTTL time.Duration
+	// Dadata is the organization lookup API; lookups are disabled without
+	// the key and secret.
+	Dadata dadata.Config
+	// DadataCache stores DaData responses in MySQL, in files under
+	// DadataCacheDir or nowhere; entries live for DadataCacheTTL.
+	DadataCache    string
+	DadataCacheDir string
+	DadataCacheTTL time.Duration
+	// DadataStubFile answers the organization refresh from a JSON file
+	// instead of the API; see dadata.LoadStub.
+	DadataStubFile string
+	// DadataRefreshInterval is how often the registry is compared with
+	// DaData; zero turns the job off.
+	DadataRefreshInterval time.Duration
+	// AddressKeepRaw stores the addresses of an inspection as entered next to
+	// the normalized ones.
+	AddressKeepRaw bool
+}
+
+func loadServerConfig() serverConfig {
+	return serverConfig{
+		FullTextSearch:   getEnvBool("SEARCH_FULLTEXT", true),
+		InspectorOverlap: getEnvChoice("INSPECTOR_OVERLAP", inspectorOverlapWarn, inspectorOverlapWarn, inspectorOverlapReject),
+		SMTP: mail.Config{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnvInt("SMTP_PORT", 25),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "DocGeneration <noreply@localhost>"),
+			TLS:      getEnvChoice("SMTP_TLS", mail.TLSNone, mail.TLSNone, mail.TLSStartTLS, mail.TLSImplicit),
+		},
+		DeadlineRulesFile: getEnv("DEADLINE_RULES_FILE", ""),
+		ReminderInterval:  getEnvDuration("REMINDER_INTERVAL", time.Hour),
+		ReminderEmailTo:   getEnvList("REMINDER_EMAIL_TO"),
+		ReminderTimeZone:  getEnvLocation("REMINDER_TIME_ZONE", time.Local),
+		IdempotencyTTL:    getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		Dadata: dadata.Config{
+			BaseURL:   getEnv("DADATA_BASE_URL", dadata.DefaultBaseURL),
+			APIKey:    getEnv("DADATA_API_KEY", ""),
+			Secret:    getEnv("DADATA_SECRET", ""),
+			RateLimit: float64(getEnvInt("DADATA_RATE_LIMIT", 10)),
+			Retries:   getEnvInt("DADATA_RETRIES", 3),
+		},
+		DadataCache:    getEnvChoice("DADATA_CACHE", dadataCacheMySQL, dadataCacheMySQL, dadataCacheFile, dadataCacheNone),
+		DadataCacheDir: getEnv("DADATA_CACHE_DIR", "/tmp/dadata-cache"),
+		DadataCacheTTL: getEnvDuration("DADATA_CACHE_TTL", 7*24*time.Hour),
+		DadataStubFile: getEnv("DADATA_STUB_FILE", ""),
+		AddressKeepRaw: getEnvBool("ADDRESS_KEEP_RAW", false),
+
+		DadataRefreshInterval: getEnvDuration("DADATA_REFRESH_INTERVAL", 7*24*time.Hour),
+	}
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	switch strings.ToLower(getEnv(key, "")) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	default:
+		return fallback
+	}
+}
+
+// getEnvChoice returns the value of key when it is one of allowed and fallback
+// otherwise.
+func getEnvChoice(key, fallback string, allowed ...string) string {
+	value := strings.ToLower(strings.TrimSpace(getEnv(key, "")))
+	if containsString(allowed, value) {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(getEnv(key, "")))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration parses values such as "30m" or "1h"; "0" is a valid value.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(strings.TrimSpace(getEnv(key, "")))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// getEnvLocation loads an IANA zone such as "Asia/Yekaterinburg".
+func getEnvLocation(key string, fallback *time.Location) *time.Location {
+	name := strings.TrimSpace(getEnv(key, ""))
+	if name == "" {
+		return fallback
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("%s: %v, using %s", key, err, fallback)
+		return fallback
+	}
+	return location
+}
+
+// getEnvList splits a comma-separated value, dropping empty items.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
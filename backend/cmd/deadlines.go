@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
+)
+
+const (
+	deadlineAnchorStart = "start"
+	deadlineAnchorEnd   = "end"
+)
+
+const (
+	reminderStatusUpcoming = "upcoming"
+	reminderStatusDue      = "due"
+	reminderStatusOverdue  = "overdue"
+	reminderStatusDone     = "done"
+)
+
+// deadlineRule places a deadline relative to an inspection date. Anchor is
+// the start or the (early) end of the period, OffsetDays counts from it in
+// calendar or working days and may be negative. The deadline becomes due
+// RemindDays before it comes. An empty FormTypes applies to every form.
+type deadlineRule struct {
+	Code        string   `json:"code"`
+	Title       string   `json:"title"`
+	Anchor      string   `json:"anchor"`
+	OffsetDays  int      `json:"offset_days"`
+	WorkingDays bool     `json:"working_days"`
+	RemindDays  int      `json:"remind_days"`
+	FormTypes   []string `json:"form_types"`
+}
+
+// defaultDeadlineRules follow 294-ФЗ: planned inspections are announced at
+// least three working days ahead, unplanned on-site ones at least a day
+// ahead; the act is drawn up right after the inspection.
+var defaultDeadlineRules = []deadlineRule{
+	{
+		Code:        "notice_planned",
+		Title:       "Уведомление о плановой проверке",
+		Anchor:      deadlineAnchorStart,
+		OffsetDays:  -3,
+		WorkingDays: true,
+		RemindDays:  5,
+		FormTypes:   []string{domain.InspectionPlannedDocumentary, domain.InspectionPlannedOnsite},
+	},
+	{
+		Code:       "notice_unplanned",
+		Title:      "Уведомление о внеплановой выездной проверке",
+		Anchor:     deadlineAnchorStart,
+		OffsetDays: -1,
+		RemindDays: 2,
+		FormTypes:  []string{domain.InspectionUnplannedOnsite},
+	},
+	{
+		Code:        "act",
+		Title:       "Оформление акта проверки",
+		Anchor:      deadlineAnchorEnd,
+		OffsetDays:  3,
+		WorkingDays: true,
+		RemindDays:  2,
+	},
+	{
+		Code:       "prescription",
+		Title:      "Контроль исполнения предписания",
+		Anchor:     deadlineAnchorEnd,
+		OffsetDays: 30,
+		RemindDays: 7,
+	},
+}
+
+// loadDeadlineRules reads rules from a JSON file (an array of deadlineRule)
+// or returns the default rules when path is empty.
+func loadDeadlineRules(path string) ([]deadlineRule, error) {
+	if strings.TrimSpace(path) == "" {
+		return defaultDeadlineRules, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []deadlineRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("deadline rules %s: %w", path, err)
+	}
+	if err := validateDeadlineRules(rules); err != nil {
+		return nil, fmt.Errorf("deadline rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func validateDeadlineRules(rules []deadlineRule) error {
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Code == "" || rule.Title == "" {
+			return errors.New("every rule needs a code and a title")
+		}
+		if seen[rule.Code] {
+			return fmt.Errorf("duplicate rule code %q", rule.Code)
+		}
+		seen[rule.Code] = true
+		if rule.Anchor != deadlineAnchorStart && rule.Anchor != deadlineAnchorEnd {
+			return fmt.Errorf("rule %q: anchor must be %q or %q", rule.Code, deadlineAnchorStart, deadlineAnchorEnd)
+		}
+		if rule.RemindDays < 0 {
+			return fmt.Errorf("rule %q: remind_days must not be negative", rule.Code)
+		}
+		for _, formType := range rule.FormTypes {
+			if !containsString(inspectionFormTypes, formType) {
+				return fmt.Errorf("rule %q: unknown form type %q", rule.Code, formType)
+			}
+		}
+	}
+	return nil
+}
+
+// deadlineFor returns the deadline of rule for an inspection, or false when
+// the rule does not apply or the anchor date is not set yet.
+func deadlineFor(rule deadlineRule, formType string, start, end *time.Time) (time.Time, bool) {
+	if len(rule.FormTypes) > 0 && !containsString(rule.FormTypes, formType) {
+		return time.Time{}, false
+	}
+	anchor := start
+	if rule.Anchor == deadlineAnchorEnd {
+		anchor = end
+	}
+	if anchor == nil {
+		return time.Time{}, false
+	}
+	if rule.WorkingDays {
+		return addWorkingDays(*anchor, rule.OffsetDays), true
+	}
+	return anchor.AddDate(0, 0, rule.OffsetDays), true
+}
+
+// addWorkingDays moves date by days working days, skipping weekends; the
+// result is always a working day.
+func addWorkingDays(date time.Time, days int) time.Time {
+	step := 1
+	if days < 0 {
+		step, days = -1, -days
+	}
+	for days > 0 {
+		date = date.AddDate(0, 0, step)
+		if isWorkingDay(date) {
+			days--
+		}
+	}
+	for !isWorkingDay(date) {
+		date = date.AddDate(0, 0, step)
+	}
+	return date
+}
+
+func isWorkingDay(date time.Time) bool {
+	weekday := date.Weekday()
+	return weekday != time.Saturday && weekday != time.Sunday
+}
+
+// reminderStatus classifies a deadline on today's date.
+func reminderStatus(rule deadlineRule, deadline, today time.Time, completed bool) string {
+	switch {
+	case completed:
+		return reminderStatusDone
+	case today.After(deadline):
+		return reminderStatusOverdue
+	case !today.Before(deadline.AddDate(0, 0, -rule.RemindDays)):
+		return reminderStatusDue
+	default:
+		return reminderStatusUpcoming
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+)
+
+const (
+	documentSendSent   = "sent"
+	documentSendFailed = "failed"
+	// maxDocumentRecipients keeps a mistyped list from mailing half the
+	// address book.
+	maxDocumentRecipients = 20
+)
+
+// documentKind is a document that can be rendered for an inspection.
+type documentKind struct {
+	Title    string
+	Filename string
+	Build    func(inspectionResponse) ([]byte, error)
+}
+
+var documentKinds = map[string]documentKind{
+	"act": {
+		Title:    "Акт проверки",
+		Filename: "inspection-%d.docx",
+		Build:    buildInspectionDocx,
+	},
+}
+
+// Subject and body are text/template sources executed with documentTemplateData.
+const (
+	defaultDocumentSubject = `{{.Title}} {{.Organization}}{{if .Number}}, проверка № {{.Number}}{{end}}`
+	defaultDocumentBody    = `Здравствуйте!
+
+Направляем {{.Title | lower}} по проверке {{.Organization}} (ОГРН {{.OGRN}}).
+Форма проверки: {{.FormType}}.
+{{- if .StartDate}}
+Срок проверки: {{.StartDate}}{{if .EndDate}} — {{.EndDate}}{{end}}.
+{{- end}}
+
+{{.Sender}}
+`
+)
+
+var documentTemplateFuncs = template.FuncMap{"lower": strings.ToLower}
+
+type documentTemplateData struct {
+	Title        string
+	Number       string
+	Organization string
+	OGRN         string
+	FormType     string
+	StartDate    string
+	EndDate      string
+	Sender       string
+}
+
+type documentSendPayload struct {
+	Recipients []string `json:"recipients"`
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body"`
+}
+
+// documentSend is one delivery attempt to one recipient.
+type documentSend struct {
+	ID        int    `json:"id"`
+	Kind      string `json:"kind"`
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject"`
+	Status    string `json:"status"`
+	MessageID string `json:"message_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+	SentBy    string `json:"sent_by"`
+	SentAt    string `json:"sent_at"`
+}
+
+func newDocumentTemplateData(kind documentKind, item inspectionResponse, sender string) documentTemplateData {
+	organization := item.Organization.ShortName
+	if strings.TrimSpace(organization) == "" {
+		organization = item.Organization.Name
+	}
+	return documentTemplateData{
+		Title:        kind.Title,
+		Number:       item.Inspection.Number,
+		Organization: organization,
+		OGRN:         item.Organization.Ogrn,
+		FormType:     item.Inspection.FormType,
+		StartDate:    item.Inspection.Period.StartDate,
+		EndDate:      item.Inspection.Period.EndDate,
+		Sender:       sender,
+	}
+}
+
+// validateDocumentSendPayload checks the recipients and parses the subject
+// and body templates, falling back to the defaults for blank ones.
+func validateDocumentSendPayload(payload *documentSendPayload) (*template.Template, *template.Template, error) {
+	v := validation.New()
+	var (
+		recipients []string
+		invalid    bool
+	)
+	for i, recipient := range payload.Recipients {
+		recipient = strings.TrimSpace(recipient)
+		if recipient == "" {
+			continue
+		}
+		if !validEmail(recipient) {
+			v.Add(fmt.Sprintf("recipients[%d]", i), validation.CodeInvalidFormat, "Некорректный адрес электронной почты")
+			invalid = true
+			continue
+		}
+		if !containsString(recipients, recipient) {
+			recipients = append(recipients, recipient)
+		}
+	}
+	if len(recipients) == 0 && !invalid {
+		v.Add("recipients", validation.CodeRequired, "Укажите получателей")
+	}
+	if len(recipients) > maxDocumentRecipients {
+		v.Add("recipients", validation.CodeOutOfRange, fmt.Sprintf("Не более %d получателей", maxDocumentRecipients))
+	}
+	payload.Recipients = recipients
+
+	subject := parseDocumentTemplate(v, "subject", payload.Subject, defaultDocumentSubject)
+	body := parseDocumentTemplate(v, "body", payload.Body, defaultDocumentBody)
+	if err := v.Err(); err != nil {
+		return nil, nil, err
+	}
+	return subject, body, nil
+}
+
+func parseDocumentTemplate(v *validation.Validator, field, source, fallback string) *template.Template {
+	if strings.TrimSpace(source) == "" {
+		source = fallback
+	}
+	tmpl, err := template.New(field).Funcs(documentTemplateFuncs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		v.Add(field, validation.CodeInvalidFormat, "Ошибка в шаблоне: "+err.Error())
+		return nil
+	}
+	return tmpl
+}
+
+func executeDocumentTemplate(tmpl *template.Template, data documentTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (s *server) insertDocumentSend(ctx context.Context, actID int, item documentSend) (documentSend, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO document_send (act_id, document_kind, recipient, subject, status, message_id, error, sent_by)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)
+	`, actID, item.Kind, item.Recipient, item.Subject, item.Status, item.MessageID, item.Error, item.SentBy)
+	if err != nil {
+		return documentSend{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return documentSend{}, err
+	}
+	item.ID = int(id)
+	item.SentAt = time.Now().UTC().Format(time.RFC3339)
+	return item, nil
+}
+
+func (s *server) listDocumentSends(ctx context.Context, actID int) ([]documentSend, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, document_kind, recipient, subject, status,
+			COALESCE(message_id, ''), COALESCE(error, ''), COALESCE(sent_by, ''), sent_at
+		FROM document_send
+		WHERE act_id=?
+		ORDER BY sent_at DESC, id DESC
+	`, actID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []documentSend{}
+	for rows.Next() {
+		var (
+			item   documentSend
+			sentAt time.Time
+		)
+		if err := rows.Scan(&item.ID, &item.Kind, &item.Recipient, &item.Subject, &item.Status, &item.MessageID, &item.Error, &item.SentBy, &sentAt); err != nil {
+			return nil, err
+		}
+		item.SentAt = sentAt.UTC().Format(time.RFC3339)
+		list = append(list, item)
+	}
+	return list, rows.Err()
+}
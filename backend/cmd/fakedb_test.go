@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database/sql driver answering queries with canned rows. A
+// query gets the rows of the first fakeResult whose match it contains;
+// statements are recorded, affect one row and insert ID 1. Transactions
+// commit everything and roll nothing back.
+type fakeDB struct {
+	mu      sync.Mutex
+	results []fakeResult
+	execs   []fakeStatement
+	queries []fakeStatement
+}
+
+type fakeResult struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+func newFakeDB(t *testing.T, results ...fakeResult) (*sql.DB, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{results: results}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// executed returns the recorded statements containing match.
+func (f *fakeDB) executed(match string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var list []fakeStatement
+	for _, statement := range f.execs {
+		if strings.Contains(statement.query, match) {
+			list = append(list, statement)
+		}
+	}
+	return list
+}
+
+// queried returns the recorded queries containing match.
+func (f *fakeDB) queried(match string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var list []fakeStatement
+	for _, statement := range f.queries {
+		if strings.Contains(statement.query, match) {
+			list = append(list, statement)
+		}
+	}
+	return list
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{db: c.db, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeExecResult struct{}
+
+func (fakeExecResult) LastInsertId() (int64, error) { return 1, nil }
+func (fakeExecResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.execs = append(s.db.execs, fakeStatement{s.query, args})
+	return fakeExecResult{}, nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.queries = append(s.db.queries, fakeStatement{s.query, args})
+	for _, result := range s.db.results {
+		if strings.Contains(s.query, result.match) {
+			return &fakeRows{columns: result.columns, rows: result.rows}, nil
+		}
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
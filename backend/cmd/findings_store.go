@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+)
+
+const (
+	findingKindViolation  = "нарушение"
+	findingKindDeficiency = "недостаток"
+)
+
+var findingKinds = []string{findingKindViolation, findingKindDeficiency}
+
+// finding is a violation or deficiency found during an inspection. Area is
+// the top level of the verification areas, Item the checked point in it.
+type finding struct {
+	ID          int    `json:"id"`
+	Kind        string `json:"kind"`
+	Area        string `json:"area"`
+	Item        string `json:"item"`
+	Description string `json:"description"`
+}
+
+type findingsPayload struct {
+	Items []finding `json:"items"`
+}
+
+func validateFindingsPayload(payload *findingsPayload) error {
+	v := validation.New()
+	for i := range payload.Items {
+		item := &payload.Items[i]
+		item.Kind = strings.TrimSpace(item.Kind)
+		item.Area = strings.TrimSpace(item.Area)
+		item.Item = strings.TrimSpace(item.Item)
+		item.Description = strings.TrimSpace(item.Description)
+
+		prefix := fmt.Sprintf("items[%d].", i)
+		v.OneOf(prefix+"kind", item.Kind, findingKinds, "Укажите нарушение или недостаток")
+		v.Required(prefix+"area", item.Area, "Укажите область проверки")
+	}
+	return v.Err()
+}
+
+func (s *server) listFindings(ctx context.Context, actID int) ([]finding, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, kind, area, item, COALESCE(description, '')
+		FROM inspection_finding
+		WHERE act_id=?
+		ORDER BY id
+	`, actID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []finding{}
+	for rows.Next() {
+		var item finding
+		if err := rows.Scan(&item.ID, &item.Kind, &item.Area, &item.Item, &item.Description); err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, rows.Err()
+}
+
+// replaceFindings stores items as the complete list of findings of the act.
+func (s *server) replaceFindings(ctx context.Context, actID int, items []finding) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM inspection_finding WHERE act_id=?", actID); err != nil {
+		return err
+	}
+	for _, item := range items {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO inspection_finding (act_id, kind, area, item, description)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''))
+		`, actID, item.Kind, item.Area, item.Item, item.Description)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"net/http"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
 )
 
 func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -17,26 +19,26 @@ func (s *server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный формат запроса")
+		writeError(w, apierror.ErrInvalidJSON)
 		return
 	}
 	userItem, err := s.getUserByLogin(r.Context(), payload.Login)
 	if err != nil {
-		writeError(w, http.StatusUnauthorized, "Неверный логин или пароль")
+		writeError(w, apierror.ErrInvalidCredentials)
 		return
 	}
 	if userItem.Password != payload.Password {
-		writeError(w, http.StatusUnauthorized, "Неверный логин или пароль")
+		writeError(w, apierror.ErrInvalidCredentials)
 		return
 	}
 	if userItem.Role == "no_access" {
-		writeError(w, http.StatusForbidden, "Доступ запрещен")
+		writeError(w, apierror.ErrAccessDenied)
 		return
 	}
 
 	sessionID, err := newSessionID()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось создать сессию")
+		writeError(w, apierror.Internal("Не удалось создать сессию"))
 		return
 	}
 
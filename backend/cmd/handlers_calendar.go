@@ -0,0 +1,141 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/gorilla/mux"
+)
+
+// handleInspectorCalendar returns the inspections of every employee between
+// from and to (YYYY-MM-DD, the current month by default). employee_id limits
+// the answer to one employee.
+func (s *server) handleInspectorCalendar(w http.ResponseWriter, r *http.Request, authUser user) {
+	query := r.URL.Query()
+	from, to, err := parseCalendarRange(query.Get("from"), query.Get("to"), time.Now())
+	if err != nil {
+		writeError(w, apierror.BadRequest("Некорректная дата, ожидается ГГГГ-ММ-ДД"))
+		return
+	}
+	if to.Before(from) {
+		writeError(w, apierror.BadRequest("Дата окончания периода раньше даты начала"))
+		return
+	}
+	if to.Sub(from) > maxCalendarDays*24*time.Hour {
+		writeError(w, apierror.BadRequest("Период календаря не может превышать год"))
+		return
+	}
+
+	var employeeID int
+	if raw := strings.TrimSpace(query.Get("employee_id")); raw != "" {
+		if employeeID, err = parseID(raw); err != nil {
+			writeError(w, apierror.ErrInvalidID)
+			return
+		}
+	}
+
+	calendars, err := s.inspectorCalendars(r.Context(), from, to, employeeID)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить календарь инспекторов"))
+		return
+	}
+	if employeeID != 0 && len(calendars) == 0 {
+		writeError(w, apierror.ErrEmployeeNotFound)
+		return
+	}
+	if calendars == nil {
+		calendars = []inspectorCalendar{}
+	}
+	writeJSON(w, http.StatusOK, calendarResponse{
+		From:      from.Format(dateLayout),
+		To:        to.Format(dateLayout),
+		Employees: calendars,
+	})
+}
+
+// checkInspectorConflicts looks for overlapping on-site assignments of the
+// payload inspectors. In reject mode it answers 409 with the conflicts and
+// reports false; in warn mode the conflicts are returned to be attached to
+// the saved inspection.
+func (s *server) checkInspectorConflicts(w http.ResponseWriter, r *http.Request, payload actPayload, excludeID int) ([]inspectorConflict, bool) {
+	conflicts, err := s.findInspectorConflicts(r.Context(), payload, excludeID)
+	if err != nil {
+		log.Println("inspector conflicts error:", err)
+		writeError(w, apierror.Internal("Не удалось проверить занятость инспекторов"))
+		return nil, false
+	}
+	if len(conflicts) > 0 && s.config.InspectorOverlap == inspectorOverlapReject {
+		writeError(w, apierror.ErrInspectorConflict.WithDetails(conflicts))
+		return nil, false
+	}
+	return conflicts, true
+}
+
+type calendarFeedResponse struct {
+	URL string `json:"url"`
+}
+
+// handleCalendarFeedGet returns the secret .ics URL of the current user.
+func (s *server) handleCalendarFeedGet(w http.ResponseWriter, r *http.Request, authUser user) {
+	token, err := s.calendarFeedToken(r.Context(), authUser.ID)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось получить ссылку на календарь"))
+		return
+	}
+	writeJSON(w, http.StatusOK, calendarFeedResponse{URL: calendarFeedURL(r, token)})
+}
+
+// handleCalendarFeedReset replaces the feed token, e.g. after the URL leaked.
+func (s *server) handleCalendarFeedReset(w http.ResponseWriter, r *http.Request, authUser user) {
+	token, err := s.resetCalendarFeedToken(r.Context(), authUser.ID)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось обновить ссылку на календарь"))
+		return
+	}
+	writeJSON(w, http.StatusOK, calendarFeedResponse{URL: calendarFeedURL(r, token)})
+}
+
+// handleCalendarFeed serves the .ics feed. Calendar apps cannot log in, so
+// the secret token in the URL is the only credential.
+func (s *server) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	feedUser, err := s.userByCalendarToken(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, apierror.ErrNotFound)
+			return
+		}
+		writeError(w, apierror.Internal("Не удалось загрузить календарь"))
+		return
+	}
+
+	now := time.Now()
+	events, err := s.calendarEventsFor(r.Context(), feedUser.Name, now.AddDate(-calendarFeedYears, 0, 0))
+	if err != nil {
+		log.Println("calendar feed error:", err)
+		writeError(w, apierror.Internal("Не удалось загрузить календарь"))
+		return
+	}
+
+	w.Header().Set("Content-Type", icsContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", "inspections.ics"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(buildICS("Проверки — "+feedUser.Name, events, now)))
+}
+
+func calendarFeedURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + r.Host + "/api/calendar/feed/" + token + ".ics"
+}
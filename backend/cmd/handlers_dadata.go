@@ -1,117 +1,252 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
-	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/dadata"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+)
+
+// dadataMaxBranches limits the branches returned with an organization.
+const dadataMaxBranches = 50
+
+const (
+	dadataDefaultSuggestions = 10
+	dadataMaxSuggestions     = 20
 )
 
+// dadataOrgRequest takes an INN, OGRN or ОГРНИП in Query; Ogrn is the field
+// older clients send.
 type dadataOrgRequest struct {
-	Ogrn string `json:"ogrn"`
+	Query string `json:"query"`
+	Ogrn  string `json:"ogrn"`
+}
+
+type dadataBranch struct {
+	KPP     string `json:"kpp"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
 }
 
 type dadataOrgResponse struct {
-	Name               string `json:"name"`
-	ShortName          string `json:"shortName"`
-	LegalAddress       string `json:"legalAddress"`
-	BossRole           string `json:"bossRole"`
-	BossNamePatronymic string `json:"bossNamePatronymic"`
-	BossLastName       string `json:"bossLastName"`
+	Name               string         `json:"name"`
+	ShortName          string         `json:"shortName"`
+	LegalAddress       string         `json:"legalAddress"`
+	BossRole           string         `json:"bossRole"`
+	BossNamePatronymic string         `json:"bossNamePatronymic"`
+	BossLastName       string         `json:"bossLastName"`
+	OGRN               string         `json:"ogrn"`
+	INN                string         `json:"inn"`
+	KPP                string         `json:"kpp"`
+	OKPO               string         `json:"okpo"`
+	Type               string         `json:"type"`
+	Branches           []dadataBranch `json:"branches"`
+}
+
+type dadataSuggestRequest struct {
+	Query string `json:"query"`
+	Count int    `json:"count"`
 }
 
-type dadataPartyResponse struct {
-	Suggestions []struct {
-		Data struct {
-			Name struct {
-				FullWithOpf  string `json:"full_with_opf"`
-				ShortWithOpf string `json:"short_with_opf"`
-			} `json:"name"`
-			Address struct {
-				Value string `json:"value"`
-			} `json:"address"`
-			Management *struct {
-				Post string `json:"post"`
-				Name string `json:"name"`
-			} `json:"management"`
-		} `json:"data"`
-	} `json:"suggestions"`
+type dadataPartySuggestion struct {
+	Value        string `json:"value"`
+	OGRN         string `json:"ogrn"`
+	INN          string `json:"inn"`
+	KPP          string `json:"kpp"`
+	LegalAddress string `json:"legalAddress"`
+	Type         string `json:"type"`
 }
 
+type dadataAddressSuggestion struct {
+	Value      string `json:"value"`
+	PostalCode string `json:"postalCode"`
+	FullValue  string `json:"fullValue"`
+}
+
+// validPartyID reports whether id is an INN (10 or 12 digits), an OGRN (13)
+// or an ОГРНИП (15) with correct control digits.
+func validPartyID(id string) bool {
+	switch len(id) {
+	case 10, 12:
+		return validation.INN(id)
+	case 13, 15:
+		return validation.OGRN(id)
+	default:
+		return false
+	}
+}
+
+// handleDadataOrganization looks an organization or an individual
+// entrepreneur up by INN, OGRN or ОГРНИП for the autofill of the act.
 func (s *server) handleDadataOrganization(w http.ResponseWriter, r *http.Request, authUser user) {
 	var payload dadataOrgRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный формат запроса")
+		writeError(w, apierror.ErrInvalidJSON)
 		return
 	}
 
-	ogrn := strings.TrimSpace(payload.Ogrn)
-	if len(ogrn) != 13 || !isDigits(ogrn) {
-		writeError(w, http.StatusBadRequest, "ОГРН должен содержать 13 цифр")
+	id := strings.TrimSpace(payload.Query)
+	if id == "" {
+		id = strings.TrimSpace(payload.Ogrn)
+	}
+	if !validPartyID(id) {
+		writeError(w, apierror.ErrInvalidPartyID)
 		return
 	}
 
-	apiKey := getEnv("DADATA_API_KEY", "")
-	secret := getEnv("DADATA_SECRET", "")
-	if apiKey == "" || secret == "" {
-		writeError(w, http.StatusInternalServerError, "Dadata ключи не настроены")
+	if s.dadata == nil {
+		writeError(w, apierror.ErrDadataNotConfigured)
 		return
 	}
 
-	requestBody, err := json.Marshal(map[string]string{"query": ogrn})
+	parties, err := s.dadata.FindPartyByID(r.Context(), id)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось подготовить запрос")
+		log.Println("dadata error:", err)
+		writeError(w, apierror.ErrDadataUnavailable)
 		return
 	}
 
-	request, err := http.NewRequest(http.MethodPost, "https://suggestions.dadata.ru/suggestions/api/4_1/rs/findById/party", bytes.NewReader(requestBody))
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось создать запрос")
+	if len(parties) == 0 {
+		writeError(w, apierror.ErrOrganizationNotFound)
 		return
 	}
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("Accept", "application/json")
-	request.Header.Set("Authorization", "Token "+apiKey)
-	request.Header.Set("X-Secret", secret)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	response, err := client.Do(request)
-	if err != nil {
-		writeError(w, http.StatusBadGateway, "Не удалось получить данные по ОГРН")
-		return
+	data := mainParty(parties)
+	result := dadataOrgResponse{
+		Name:         data.Name.FullWithOpf,
+		ShortName:    data.Name.ShortWithOpf,
+		LegalAddress: data.Address.Value,
+		OGRN:         data.OGRN,
+		INN:          data.INN,
+		KPP:          data.KPP,
+		OKPO:         data.OKPO,
+		Type:         data.Type,
+		Branches:     []dadataBranch{},
 	}
-	defer response.Body.Close()
 
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		writeError(w, http.StatusBadGateway, "Ошибка ответа Dadata")
-		return
+	switch {
+	case data.Management != nil:
+		result.BossRole = data.Management.Post
+		result.BossLastName, result.BossNamePatronymic = splitManagerName(data.Management.Name)
+	case data.Type == dadata.PartyIndividual && data.FIO != nil:
+		result.BossRole = "Индивидуальный предприниматель"
+		result.BossLastName = data.FIO.Surname
+		result.BossNamePatronymic = strings.TrimSpace(data.FIO.Name + " " + data.FIO.Patronymic)
 	}
 
-	var dadataResponse dadataPartyResponse
-	if err := json.NewDecoder(response.Body).Decode(&dadataResponse); err != nil {
-		writeError(w, http.StatusBadGateway, "Не удалось обработать ответ Dadata")
-		return
+	if data.BranchCount > 0 && data.INN != "" {
+		branches, err := s.dadata.FindBranches(r.Context(), data.INN, dadataMaxBranches)
+		if err != nil {
+			// The organization itself is already known; the branch list is
+			// left empty rather than failing the autofill.
+			log.Println("dadata branches error:", err)
+		}
+		for _, branch := range branches {
+			result.Branches = append(result.Branches, dadataBranch{
+				KPP:     branch.Data.KPP,
+				Name:    branch.Value,
+				Address: branch.Data.Address.Value,
+			})
+		}
 	}
 
-	if len(dadataResponse.Suggestions) == 0 {
-		writeError(w, http.StatusNotFound, "Организация не найдена")
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleDadataOrganizationSuggest returns organizations matching the typed
+// name, INN or OGRN for autocomplete.
+func (s *server) handleDadataOrganizationSuggest(w http.ResponseWriter, r *http.Request, authUser user) {
+	query, count, ok := s.decodeDadataSuggest(w, r)
+	if !ok {
 		return
 	}
+	parties, err := s.dadata.SuggestParty(r.Context(), query, count)
+	if err != nil {
+		log.Println("dadata error:", err)
+		writeError(w, apierror.ErrDadataUnavailable)
+		return
+	}
+	list := make([]dadataPartySuggestion, 0, len(parties))
+	for _, party := range parties {
+		list = append(list, dadataPartySuggestion{
+			Value:        party.Value,
+			OGRN:         party.Data.OGRN,
+			INN:          party.Data.INN,
+			KPP:          party.Data.KPP,
+			LegalAddress: party.Data.Address.Value,
+			Type:         party.Data.Type,
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
 
-	data := dadataResponse.Suggestions[0].Data
-	result := dadataOrgResponse{
-		Name:         data.Name.FullWithOpf,
-		ShortName:    data.Name.ShortWithOpf,
-		LegalAddress: data.Address.Value,
+// handleDadataAddressSuggest returns addresses matching the typed text for
+// the addresses of the inspection, which are written without the postal
+// code.
+func (s *server) handleDadataAddressSuggest(w http.ResponseWriter, r *http.Request, authUser user) {
+	query, count, ok := s.decodeDadataSuggest(w, r)
+	if !ok {
+		return
 	}
+	addresses, err := s.dadata.SuggestAddress(r.Context(), query, count)
+	if err != nil {
+		log.Println("dadata error:", err)
+		writeError(w, apierror.ErrDadataUnavailable)
+		return
+	}
+	list := make([]dadataAddressSuggestion, 0, len(addresses))
+	for _, address := range addresses {
+		list = append(list, dadataAddressSuggestion{
+			Value:      address.Value,
+			PostalCode: address.Data.PostalCode,
+			FullValue:  address.UnrestrictedValue,
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
 
-	if data.Management != nil {
-		result.BossRole = data.Management.Post
-		result.BossLastName, result.BossNamePatronymic = splitManagerName(data.Management.Name)
+// decodeDadataSuggest reads a suggestion request, answering 400/422/500
+// itself when it cannot be served.
+func (s *server) decodeDadataSuggest(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	var payload dadataSuggestRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, apierror.ErrInvalidJSON)
+		return "", 0, false
+	}
+	payload.Query = strings.TrimSpace(payload.Query)
+	v := validation.New()
+	v.Required("query", payload.Query, "Введите текст для поиска")
+	if payload.Count < 0 || payload.Count > dadataMaxSuggestions {
+		v.Add("count", validation.CodeOutOfRange, fmt.Sprintf("Количество подсказок от 1 до %d", dadataMaxSuggestions))
 	}
+	if err := v.Err(); err != nil {
+		writeValidationError(w, err)
+		return "", 0, false
+	}
+	if s.dadata == nil {
+		writeError(w, apierror.ErrDadataNotConfigured)
+		return "", 0, false
+	}
+	if payload.Count == 0 {
+		payload.Count = dadataDefaultSuggestions
+	}
+	return payload.Query, payload.Count, true
+}
 
-	writeJSON(w, http.StatusOK, result)
+// mainParty picks the head organization out of the suggestions: an INN also
+// matches the branches.
+func mainParty(parties []dadata.Party) dadata.PartyData {
+	for _, party := range parties {
+		if party.Data.BranchType != dadata.BranchBranch {
+			return party.Data
+		}
+	}
+	return parties[0].Data
 }
 
 func splitManagerName(fullName string) (string, string) {
@@ -125,12 +260,3 @@ func splitManagerName(fullName string) (string, string) {
 	}
 	return lastName, strings.Join(parts[1:], " ")
 }
-
-func isDigits(value string) bool {
-	for _, ch := range value {
-		if ch < '0' || ch > '9' {
-			return false
-		}
-	}
-	return true
-}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/mail"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// documentSendResponse reports every recipient. NotLogged counts the sends
+// that happened but could not be written to the send log; they have no ID.
+type documentSendResponse struct {
+	Sent      int            `json:"sent"`
+	Failed    int            `json:"failed"`
+	NotLogged int            `json:"not_logged"`
+	Sends     []documentSend `json:"sends"`
+}
+
+// handleDocumentSend renders a document of the inspection and mails it to
+// each recipient separately, so that every delivery gets its own status and
+// Message-ID in the send log.
+func (s *server) handleDocumentSend(w http.ResponseWriter, r *http.Request, authUser user) {
+	vars := mux.Vars(r)
+	id, err := parseID(vars["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	kindCode := vars["kind"]
+	kind, ok := documentKinds[kindCode]
+	if !ok {
+		writeError(w, apierror.ErrDocumentKindNotFound)
+		return
+	}
+	if s.mailer == nil {
+		writeError(w, apierror.ErrMailNotConfigured)
+		return
+	}
+
+	var payload documentSendPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, apierror.ErrInvalidJSON)
+		return
+	}
+	subjectTmpl, bodyTmpl, err := validateDocumentSendPayload(&payload)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	item, err := s.fetchInspectionByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, apierror.ErrInspectionNotFound)
+			return
+		}
+		writeError(w, apierror.Internal("Не удалось загрузить проверку"))
+		return
+	}
+
+	data := newDocumentTemplateData(kind, item, authUser.Name)
+	subject, err := executeDocumentTemplate(subjectTmpl, data)
+	if err != nil {
+		writeValidationError(w, validation.Errors{{Field: "subject", Code: validation.CodeInvalidValue, Message: "Ошибка в шаблоне: " + err.Error()}})
+		return
+	}
+	body, err := executeDocumentTemplate(bodyTmpl, data)
+	if err != nil {
+		writeValidationError(w, validation.Errors{{Field: "body", Code: validation.CodeInvalidValue, Message: "Ошибка в шаблоне: " + err.Error()}})
+		return
+	}
+	subject = strings.TrimSpace(subject)
+
+	document, err := kind.Build(item)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось сформировать документ"))
+		return
+	}
+	attachment := mail.Attachment{
+		Filename:    fmt.Sprintf(kind.Filename, item.ID),
+		ContentType: docxContentType,
+		Data:        document,
+	}
+
+	response := documentSendResponse{Sends: []documentSend{}}
+	for _, recipient := range payload.Recipients {
+		send := documentSend{
+			Kind:      kindCode,
+			Recipient: recipient,
+			Subject:   subject,
+			Status:    documentSendSent,
+			SentBy:    authUser.Name,
+		}
+		messageID, err := s.mailer.Send(r.Context(), mail.Message{
+			To:          []string{recipient},
+			Subject:     subject,
+			Body:        body,
+			Attachments: []mail.Attachment{attachment},
+		})
+		if err != nil {
+			log.Printf("document mail to %s failed: %v", recipient, err)
+			send.Status = documentSendFailed
+			send.Error = err.Error()
+			response.Failed++
+		} else {
+			send.MessageID = messageID
+			response.Sent++
+		}
+		// The mail is already out: a send log failure must not hide it.
+		logged, err := s.insertDocumentSend(r.Context(), id, send)
+		if err != nil {
+			log.Printf("document send log error for %s: %v", recipient, err)
+			send.SentAt = time.Now().UTC().Format(time.RFC3339)
+			response.NotLogged++
+		} else {
+			send = logged
+		}
+		response.Sends = append(response.Sends, send)
+	}
+
+	if response.Sent == 0 {
+		writeError(w, apierror.ErrMailDeliveryFailed.WithDetails(response.Sends))
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleDocumentSends returns the send log of the inspection, newest first.
+func (s *server) handleDocumentSends(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, ok := s.requireInspection(w, r)
+	if !ok {
+		return
+	}
+	list, err := s.listDocumentSends(r.Context(), id)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить журнал отправки"))
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
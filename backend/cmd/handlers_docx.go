@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
 	"github.com/gorilla/mux"
 )
 
@@ -18,22 +19,22 @@ const docxContentType = "application/vnd.openxmlformats-officedocument.wordproce
 func (s *server) handleInspectionDocx(w http.ResponseWriter, r *http.Request, authUser user) {
 	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный идентификатор")
+		writeError(w, apierror.ErrInvalidID)
 		return
 	}
 	item, err := s.fetchInspectionByID(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "Проверка не найдена")
+			writeError(w, apierror.ErrInspectionNotFound)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "Не удалось сформировать документ")
+		writeError(w, apierror.Internal("Не удалось сформировать документ"))
 		return
 	}
 
 	docxBytes, err := buildInspectionDocx(item)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось сформировать документ")
+		writeError(w, apierror.Internal("Не удалось сформировать документ"))
 		return
 	}
 
@@ -50,7 +51,11 @@ func buildInspectionDocx(item inspectionResponse) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return packDocx(documentXML)
+}
 
+// packDocx zips document.xml with the parts every .docx needs.
+func packDocx(documentXML string) ([]byte, error) {
 	var buf bytes.Buffer
 	zipWriter := zip.NewWriter(&buf)
 	if err := addDocxFile(zipWriter, "[Content_Types].xml", docxContentTypesXML); err != nil {
@@ -95,9 +100,10 @@ func inspectionDocxLines(item inspectionResponse) []string {
 		fmt.Sprintf("Почтовый адрес: %s", formatValue(item.Organization.Address.PostalAddress)),
 		"",
 		fmt.Sprintf("Форма проверки: %s", formatValue(item.Inspection.FormType)),
-		fmt.Sprintf("Приказ Минздрава: №%s от %s", formatValue(item.Inspection.MzOrder.Number), formatValue(item.Inspection.MzOrder.Date)),
+		formatOrderLine("Приказ Минздрава", item.Inspection.MzOrder),
+		formatOrderLine("Приказ ЧОМИАЦ", item.Inspection.ChomiazOrder),
 		fmt.Sprintf("Письмо: №%s от %s", formatValue(letterNumber), formatValue(item.Inspection.Letter.Date)),
-		fmt.Sprintf("Срок проверки: %s — %s (%s раб. дней)", formatValue(item.Inspection.Period.StartDate), formatValue(item.Inspection.Period.EndDate), formatValue(item.Inspection.Period.Days)),
+		formatPeriodLine(item.Inspection.Period),
 		"",
 		fmt.Sprintf("Руководитель учреждения: %s %s", formatValue(item.Head.Role), formatValue(headName)),
 		fmt.Sprintf("Представитель учреждения: %s", formatList(item.Inspection.Representative)),
@@ -111,6 +117,23 @@ func inspectionDocxLines(item inspectionResponse) []string {
 	return lines
 }
 
+func formatOrderLine(label string, order orderDTO) string {
+	line := fmt.Sprintf("%s: №%s от %s", label, formatValue(order.Number), formatValue(order.Date))
+	if name := strings.TrimSpace(order.Name); name != "" {
+		line += fmt.Sprintf(" «%s»", name)
+	}
+	return line
+}
+
+// formatPeriodLine shows the planned period and, for inspections finished
+// early, the actual end date the working days were counted to.
+func formatPeriodLine(period periodDTO) string {
+	if strings.TrimSpace(period.EarlyEndDate) == "" {
+		return fmt.Sprintf("Срок проверки: %s — %s (%s раб. дней)", formatValue(period.StartDate), formatValue(period.EndDate), formatValue(period.Days))
+	}
+	return fmt.Sprintf("Срок проверки: %s — %s, окончена досрочно %s (%s раб. дней)", formatValue(period.StartDate), formatValue(period.EndDate), period.EarlyEndDate, formatValue(period.Days))
+}
+
 func formatValue(value string) string {
 	if strings.TrimSpace(value) == "" {
 		return "—"
@@ -135,22 +158,41 @@ func formatList(values []string) string {
 	return strings.Join(filtered, ", ")
 }
 
+// docxBlock is a paragraph of Text or, when Table is set, a table whose first
+// row is the header.
+type docxBlock struct {
+	Text  string
+	Bold  bool
+	Table [][]string
+}
+
+// docxTableWidth is the text width of the page in twentieths of a point.
+const docxTableWidth = 9360
+
 func buildDocxDocumentXML(lines []string) (string, error) {
+	blocks := make([]docxBlock, 0, len(lines))
+	for _, line := range lines {
+		blocks = append(blocks, docxBlock{Text: line})
+	}
+	return buildDocxBlocksXML(blocks)
+}
+
+func buildDocxBlocksXML(blocks []docxBlock) (string, error) {
 	var builder strings.Builder
 	builder.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
 	builder.WriteString(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
 	builder.WriteString(`<w:body>`)
 
-	for _, line := range lines {
-		if line == "" {
-			builder.WriteString(`<w:p/>`)
-			continue
+	for _, block := range blocks {
+		var err error
+		if block.Table != nil {
+			err = writeDocxTable(&builder, block.Table)
+		} else {
+			err = writeDocxParagraph(&builder, block.Text, block.Bold)
 		}
-		builder.WriteString(`<w:p><w:r><w:t xml:space="preserve">`)
-		if err := writeEscapedXML(&builder, line); err != nil {
+		if err != nil {
 			return "", err
 		}
-		builder.WriteString(`</w:t></w:r></w:p>`)
 	}
 
 	builder.WriteString(`<w:sectPr><w:pgSz w:w="12240" w:h="15840"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="720" w:footer="720" w:gutter="0"/></w:sectPr>`)
@@ -158,6 +200,66 @@ func buildDocxDocumentXML(lines []string) (string, error) {
 	return builder.String(), nil
 }
 
+func writeDocxParagraph(builder *strings.Builder, text string, bold bool) error {
+	if text == "" {
+		builder.WriteString(`<w:p/>`)
+		return nil
+	}
+	builder.WriteString(`<w:p><w:r>`)
+	if bold {
+		builder.WriteString(`<w:rPr><w:b/></w:rPr>`)
+	}
+	builder.WriteString(`<w:t xml:space="preserve">`)
+	if err := writeEscapedXML(builder, text); err != nil {
+		return err
+	}
+	builder.WriteString(`</w:t></w:r></w:p>`)
+	return nil
+}
+
+// writeDocxTable writes a bordered table with equal columns and a bold header.
+// A table cell must hold at least one paragraph, even an empty one.
+func writeDocxTable(builder *strings.Builder, rows [][]string) error {
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return nil
+	}
+	width := docxTableWidth / columns
+
+	builder.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="0" w:type="auto"/><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		fmt.Fprintf(builder, `<w:%s w:val="single" w:sz="4" w:space="0" w:color="000000"/>`, side)
+	}
+	builder.WriteString(`</w:tblBorders></w:tblPr><w:tblGrid>`)
+	for i := 0; i < columns; i++ {
+		fmt.Fprintf(builder, `<w:gridCol w:w="%d"/>`, width)
+	}
+	builder.WriteString(`</w:tblGrid>`)
+
+	for rowIndex, row := range rows {
+		builder.WriteString(`<w:tr>`)
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			fmt.Fprintf(builder, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr>`, width)
+			if err := writeDocxParagraph(builder, cell, rowIndex == 0); err != nil {
+				return err
+			}
+			builder.WriteString(`</w:tc>`)
+		}
+		builder.WriteString(`</w:tr>`)
+	}
+	builder.WriteString(`</w:tbl>`)
+	return nil
+}
+
 func writeEscapedXML(builder *strings.Builder, value string) error {
 	var buf bytes.Buffer
 	if err := xml.EscapeText(&buf, []byte(value)); err != nil {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
 	"github.com/gorilla/mux"
 )
 
@@ -15,7 +16,7 @@ func (s *server) handleEmployeesList(w http.ResponseWriter, r *http.Request, aut
 		ORDER BY id
 	`)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось загрузить сотрудников")
+		writeError(w, apierror.Internal("Не удалось загрузить сотрудников"))
 		return
 	}
 	defer rows.Close()
@@ -24,7 +25,7 @@ func (s *server) handleEmployeesList(w http.ResponseWriter, r *http.Request, aut
 	for rows.Next() {
 		var item employee
 		if err := rows.Scan(&item.ID, &item.Name); err != nil {
-			writeError(w, http.StatusInternalServerError, "Не удалось обработать сотрудников")
+			writeError(w, apierror.Internal("Не удалось обработать сотрудников"))
 			return
 		}
 		list = append(list, item)
@@ -38,12 +39,12 @@ func (s *server) handleEmployeeCreate(w http.ResponseWriter, r *http.Request, au
 		Name string `json:"name"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный формат запроса")
+		writeError(w, apierror.ErrInvalidJSON)
 		return
 	}
 	payload.Name = strings.TrimSpace(payload.Name)
 	if payload.Name == "" {
-		writeError(w, http.StatusBadRequest, "Заполните ФИО")
+		writeError(w, apierror.ErrValidation.WithMessage("Заполните ФИО"))
 		return
 	}
 
@@ -52,12 +53,12 @@ func (s *server) handleEmployeeCreate(w http.ResponseWriter, r *http.Request, au
 		VALUES (?)
 	`, payload.Name)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось сохранить сотрудника")
+		writeError(w, apierror.Internal("Не удалось сохранить сотрудника"))
 		return
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось сохранить сотрудника")
+		writeError(w, apierror.Internal("Не удалось сохранить сотрудника"))
 		return
 	}
 
@@ -68,18 +69,18 @@ func (s *server) handleEmployeeCreate(w http.ResponseWriter, r *http.Request, au
 func (s *server) handleEmployeeDelete(w http.ResponseWriter, r *http.Request, authUser user) {
 	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный идентификатор")
+		writeError(w, apierror.ErrInvalidID)
 		return
 	}
 
 	res, err := s.db.ExecContext(r.Context(), "DELETE FROM employees WHERE id=?", id)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось удалить сотрудника")
+		writeError(w, apierror.Internal("Не удалось удалить сотрудника"))
 		return
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		writeError(w, http.StatusNotFound, "Сотрудник не найден")
+		writeError(w, apierror.ErrEmployeeNotFound)
 		return
 	}
 	writeJSON(w, http.StatusNoContent, nil)
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+)
+
+// registryColumn describes one spreadsheet column; a nil Value marks the
+// running row number column.
+type registryColumn struct {
+	Header string
+	Value  func(item inspectionResponse) xlsxCell
+}
+
+var registryColumns = []registryColumn{
+	{"№ п/п", nil},
+	{"Номер проверки", func(item inspectionResponse) xlsxCell { return xlsxString(item.Inspection.Number) }},
+	{"Форма проверки", func(item inspectionResponse) xlsxCell { return xlsxString(item.Inspection.FormType) }},
+	{"Статус", func(item inspectionResponse) xlsxCell { return xlsxString(inspectionStatusTitle(item.Status)) }},
+	{"Организация", func(item inspectionResponse) xlsxCell { return xlsxString(item.Organization.Name) }},
+	{"Краткое наименование", func(item inspectionResponse) xlsxCell { return xlsxString(item.Organization.ShortName) }},
+	{"ОГРН", func(item inspectionResponse) xlsxCell { return xlsxString(item.Organization.Ogrn) }},
+	{"Юридический адрес", func(item inspectionResponse) xlsxCell { return xlsxString(item.Organization.Address.LegalAddress) }},
+	{"Руководитель", func(item inspectionResponse) xlsxCell {
+		return xlsxString(strings.TrimSpace(item.Head.Role + " " + joinHeadName(item.Head)))
+	}},
+	{"Приказ Минздрава №", func(item inspectionResponse) xlsxCell { return xlsxString(item.Inspection.MzOrder.Number) }},
+	{"Дата приказа", func(item inspectionResponse) xlsxCell { return xlsxDate(item.Inspection.MzOrder.Date) }},
+	{"Наименование приказа", func(item inspectionResponse) xlsxCell { return xlsxString(item.Inspection.MzOrder.Name) }},
+	{"Приказ ЧОМИАЦ №", func(item inspectionResponse) xlsxCell { return xlsxString(item.Inspection.ChomiazOrder.Number) }},
+	{"Дата приказа ЧОМИАЦ", func(item inspectionResponse) xlsxCell { return xlsxDate(item.Inspection.ChomiazOrder.Date) }},
+	{"Дата начала", func(item inspectionResponse) xlsxCell { return xlsxDate(item.Inspection.Period.StartDate) }},
+	{"Дата окончания", func(item inspectionResponse) xlsxCell { return xlsxDate(item.Inspection.Period.EndDate) }},
+	{"Досрочное окончание", func(item inspectionResponse) xlsxCell { return xlsxDate(item.Inspection.Period.EarlyEndDate) }},
+	{"Рабочих дней", func(item inspectionResponse) xlsxCell {
+		days, err := strconv.Atoi(item.Inspection.Period.Days)
+		if err != nil {
+			return xlsxString("")
+		}
+		return xlsxNumber(float64(days))
+	}},
+	{"Письмо №", func(item inspectionResponse) xlsxCell { return xlsxString(buildLetterNumber(item.Inspection.Letter)) }},
+	{"Дата письма", func(item inspectionResponse) xlsxCell { return xlsxDate(item.Inspection.Letter.Date) }},
+	{"Адреса проверки", func(item inspectionResponse) xlsxCell {
+		return xlsxString(strings.Join(item.Inspection.AddressNoIndex, "; "))
+	}},
+	{"Инспекторы", func(item inspectionResponse) xlsxCell {
+		return xlsxString(strings.Join(item.Inspection.Inspectors, "; "))
+	}},
+	{"Представители", func(item inspectionResponse) xlsxCell {
+		return xlsxString(strings.Join(item.Inspection.Representative, "; "))
+	}},
+	{"Создал", func(item inspectionResponse) xlsxCell { return xlsxString(item.CreatedBy) }},
+	{"Дата создания", func(item inspectionResponse) xlsxCell { return xlsxDate(firstDatePart(item.CreatedAt)) }},
+}
+
+// handleInspectionsExport streams the whole registry matching the list filters
+// (without pagination) as a spreadsheet. Rows are written as they are read,
+// so errors after the first byte can only be logged.
+func (s *server) handleInspectionsExport(w http.ResponseWriter, r *http.Request, authUser user) {
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if format == "" {
+		format = "xlsx"
+	}
+	if format != "xlsx" && format != "csv" {
+		writeError(w, apierror.ErrUnsupportedFormat)
+		return
+	}
+
+	query, ok := parseInspectionListQuery(w, r)
+	if !ok {
+		return
+	}
+	filename := fmt.Sprintf("inspections-%s.%s", time.Now().Format("2006-01-02"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var err error
+	if format == "csv" {
+		err = s.writeRegistryCSV(w, r, query)
+	} else {
+		err = s.writeRegistryXLSX(w, r, query)
+	}
+	if err != nil {
+		log.Println("inspections export error:", err)
+	}
+}
+
+func (s *server) writeRegistryXLSX(w http.ResponseWriter, r *http.Request, query inspectionListQuery) error {
+	w.Header().Set("Content-Type", xlsxContentType)
+	w.WriteHeader(http.StatusOK)
+
+	sheet, err := newXLSXStreamWriter(w, "Реестр проверок")
+	if err != nil {
+		return err
+	}
+	headers := make([]xlsxCell, 0, len(registryColumns))
+	for _, column := range registryColumns {
+		headers = append(headers, xlsxString(column.Header))
+	}
+	if err := sheet.WriteRow(headers); err != nil {
+		return err
+	}
+
+	rowNumber := 0
+	err = s.eachInspection(r.Context(), query, 0, 0, func(item inspectionResponse) error {
+		rowNumber++
+		return sheet.WriteRow(registryRow(item, rowNumber))
+	})
+	if err != nil {
+		return err
+	}
+	return sheet.Close()
+}
+
+func (s *server) writeRegistryCSV(w http.ResponseWriter, r *http.Request, query inspectionListQuery) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	// BOM and semicolons make Excel with Russian locale open the file as is.
+	if _, err := w.Write([]byte("\ufeff")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+
+	headers := make([]string, 0, len(registryColumns))
+	for _, column := range registryColumns {
+		headers = append(headers, column.Header)
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	rowNumber := 0
+	err := s.eachInspection(r.Context(), query, 0, 0, func(item inspectionResponse) error {
+		rowNumber++
+		cells := registryRow(item, rowNumber)
+		record := make([]string, 0, len(cells))
+		for _, cell := range cells {
+			record = append(record, csvCellValue(cell))
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func registryRow(item inspectionResponse, rowNumber int) []xlsxCell {
+	cells := make([]xlsxCell, 0, len(registryColumns))
+	for _, column := range registryColumns {
+		if column.Value == nil {
+			cells = append(cells, xlsxNumber(float64(rowNumber)))
+			continue
+		}
+		cells = append(cells, column.Value(item))
+	}
+	return cells
+}
+
+func csvCellValue(cell xlsxCell) string {
+	switch cell.Kind {
+	case xlsxCellNumber:
+		return strconv.FormatFloat(cell.Number, 'f', -1, 64)
+	case xlsxCellDate:
+		return cell.Date.Format("02.01.2006")
+	default:
+		return cell.String
+	}
+}
+
+func joinHeadName(head headDTO) string {
+	return strings.Join(strings.Fields(strings.Join([]string{head.LastName, head.NamePatronymic, head.LastNameTo}, " ")), " ")
+}
+
+func firstDatePart(value string) string {
+	if len(value) >= len(dateLayout) {
+		return value[:len(dateLayout)]
+	}
+	return value
+}
+
+func inspectionStatusTitle(status string) string {
+	switch status {
+	case inspectionStatusDraft:
+		return "Черновик"
+	case inspectionStatusPlanned:
+		return "Запланирована"
+	case inspectionStatusInProgress:
+		return "Проводится"
+	case inspectionStatusCompleted:
+		return "Завершена"
+	default:
+		return status
+	}
+}
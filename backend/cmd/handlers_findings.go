@@ -0,0 +1,73 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/gorilla/mux"
+)
+
+func (s *server) handleFindingsGet(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, ok := s.requireInspection(w, r)
+	if !ok {
+		return
+	}
+	list, err := s.listFindings(r.Context(), id)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить нарушения"))
+		return
+	}
+	writeJSON(w, http.StatusOK, findingsPayload{Items: list})
+}
+
+// handleFindingsUpdate replaces the findings of the inspection.
+func (s *server) handleFindingsUpdate(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, ok := s.requireInspection(w, r)
+	if !ok {
+		return
+	}
+	var payload findingsPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, apierror.ErrInvalidJSON)
+		return
+	}
+	if err := validateFindingsPayload(&payload); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if err := s.replaceFindings(r.Context(), id, payload.Items); err != nil {
+		writeError(w, apierror.Internal("Не удалось сохранить нарушения"))
+		return
+	}
+	list, err := s.listFindings(r.Context(), id)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить нарушения"))
+		return
+	}
+	writeJSON(w, http.StatusOK, findingsPayload{Items: list})
+}
+
+// requireInspection reads the {id} of the route and checks that the
+// inspection exists, answering 400/404 otherwise. The findings handlers
+// brought it in; the reminder and document send-log handlers use it too.
+func (s *server) requireInspection(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return 0, false
+	}
+	var exists int
+	err = s.db.QueryRowContext(r.Context(), "SELECT 1 FROM act WHERE id=?", id).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, apierror.ErrInspectionNotFound)
+			return 0, false
+		}
+		writeError(w, apierror.Internal("Не удалось загрузить проверку"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/gorilla/mux"
+)
+
+// handleInspectionsImport loads inspections from an uploaded XLSX/CSV file
+// (multipart field "file"). With dry_run=true rows are only validated.
+func (s *server) handleInspectionsImport(w http.ResponseWriter, r *http.Request, authUser user) {
+	table, ok := readImportUpload(w, r)
+	if !ok {
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	rows, rowErrors, unknown := mapImportRows(table)
+	valid, rowErrors := validateImportRows(rows, rowErrors)
+
+	result := importResult{
+		DryRun:         dryRun,
+		TotalRows:      len(rows),
+		ValidRows:      len(valid),
+		IDs:            []int64{},
+		UnknownColumns: unknown,
+	}
+	if !dryRun {
+		ids, insertErrors := s.importInspections(r.Context(), valid, authUser.Name)
+		result.IDs = ids
+		result.Imported = len(ids)
+		rowErrors = append(rowErrors, insertErrors...)
+	}
+	s.writeImportResult(w, result, rowErrors)
+}
+
+// readImportUpload reads the table of an uploaded XLSX/CSV file (multipart
+// field "file", optional format=xlsx|csv). On failure the error is already
+// written and ok is false.
+func readImportUpload(w http.ResponseWriter, r *http.Request) ([][]string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		writeError(w, apierror.ErrFileUnreadable)
+		return nil, false
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, apierror.ErrFileMissing)
+		return nil, false
+	}
+	defer file.Close()
+
+	format, err := importFormat(header.Filename, r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, apierror.ErrUnsupportedFormat)
+		return nil, false
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, apierror.ErrFileUnreadable)
+		return nil, false
+	}
+	table, err := readImportTable(data, format)
+	if err != nil {
+		writeError(w, apierror.ErrFileInvalid)
+		return nil, false
+	}
+	return table, true
+}
+
+// writeImportResult completes result with the row errors and a link to their
+// CSV report and writes it: 201 when something was imported, 200 otherwise.
+func (s *server) writeImportResult(w http.ResponseWriter, result importResult, rowErrors []importRowError) {
+	if result.UnknownColumns == nil {
+		result.UnknownColumns = []string{}
+	}
+	result.Errors = rowErrors
+	if result.Errors == nil {
+		result.Errors = []importRowError{}
+	}
+
+	if len(rowErrors) > 0 {
+		reportURL, err := s.storeImportReport(rowErrors)
+		if err != nil {
+			log.Println("import report error:", err)
+		}
+		result.ReportURL = reportURL
+	}
+
+	status := http.StatusOK
+	if result.Imported > 0 {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, result)
+}
+
+func (s *server) storeImportReport(rowErrors []importRowError) (string, error) {
+	report, err := buildImportReportCSV(rowErrors)
+	if err != nil {
+		return "", err
+	}
+	id, err := s.importReports.add(report)
+	if err != nil {
+		return "", err
+	}
+	return "/api/inspections/import/reports/" + id, nil
+}
+
+func (s *server) handleImportReport(w http.ResponseWriter, r *http.Request, authUser user) {
+	report, ok := s.importReports.get(mux.Vars(r)["id"])
+	if !ok {
+		writeError(w, apierror.ErrReportNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "import-errors.csv"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(report)
+}
+
+// importInspections commits rows in batches of importBatchSize, one
+// transaction per batch. When a batch fails it is retried row by row so that
+// a single bad row does not discard its neighbours.
+func (s *server) importInspections(ctx context.Context, rows []importRow, createdBy string) ([]int64, []importRowError) {
+	ids := []int64{}
+	var rowErrors []importRowError
+	for start := 0; start < len(rows); start += importBatchSize {
+		batch := rows[start:min(start+importBatchSize, len(rows))]
+		batchIDs, err := s.insertInspectionBatch(ctx, batch, createdBy)
+		if err == nil {
+			ids = append(ids, batchIDs...)
+			continue
+		}
+		if ctx.Err() != nil {
+			for _, row := range batch {
+				rowErrors = append(rowErrors, importRowError{Row: row.Line, Message: "Импорт прерван"})
+			}
+			continue
+		}
+		for _, row := range batch {
+			rowIDs, err := s.insertInspectionBatch(ctx, []importRow{row}, createdBy)
+			if err != nil {
+				log.Println("import row error:", err)
+				rowErrors = append(rowErrors, importRowError{Row: row.Line, Message: "Не удалось сохранить проверку"})
+				continue
+			}
+			ids = append(ids, rowIDs...)
+		}
+	}
+	return ids, rowErrors
+}
+
+func (s *server) insertInspectionBatch(ctx context.Context, rows []importRow, createdBy string) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	ids := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		var id int64
+		id, err = s.insertInspectionTx(ctx, tx, row.Payload, createdBy)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -2,19 +2,26 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
 	"github.com/gorilla/mux"
 )
 
 func (s *server) handleInspectionsList(w http.ResponseWriter, r *http.Request, authUser user) {
-	query := parseInspectionListQuery(r)
+	query, ok := parseInspectionListQuery(w, r)
+	if !ok {
+		return
+	}
 	items, total, err := s.fetchInspections(r.Context(), query)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось загрузить проверки")
+		writeError(w, apierror.Internal("Не удалось загрузить проверки"))
 		return
 	}
 	writeJSON(w, http.StatusOK, inspectionListResponse{
@@ -28,16 +35,16 @@ func (s *server) handleInspectionsList(w http.ResponseWriter, r *http.Request, a
 func (s *server) handleInspectionGet(w http.ResponseWriter, r *http.Request, authUser user) {
 	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный идентификатор")
+		writeError(w, apierror.ErrInvalidID)
 		return
 	}
 	item, err := s.fetchInspectionByID(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "Проверка не найдена")
+			writeError(w, apierror.ErrInspectionNotFound)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "Не удалось загрузить проверку")
+		writeError(w, apierror.Internal("Не удалось загрузить проверку"))
 		return
 	}
 	writeJSON(w, http.StatusOK, item)
@@ -46,14 +53,56 @@ func (s *server) handleInspectionGet(w http.ResponseWriter, r *http.Request, aut
 func (s *server) handleInspectionCreate(w http.ResponseWriter, r *http.Request, authUser user) {
 	payload, err := decodeInspectionPayload(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, apierror.ErrInvalidJSON)
+		return
+	}
+	if err := validateInspectionPayload(payload); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 	createdBy := authUser.Name
+	conflicts, ok := s.checkInspectorConflicts(w, r, payload, 0)
+	if !ok {
+		return
+	}
 
 	item, err := s.insertInspection(r.Context(), payload, createdBy)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось сохранить проверку")
+		writeError(w, apierror.Internal("Не удалось сохранить проверку"))
+		return
+	}
+	item.Conflicts = conflicts
+	writeJSON(w, http.StatusCreated, item)
+}
+
+func (s *server) handleInspectionClone(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	var payload struct {
+		FormType string `json:"formType"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, apierror.ErrInvalidJSON)
+			return
+		}
+	}
+	formType := strings.TrimSpace(payload.FormType)
+	if formType != "" && !containsString(inspectionFormTypes, formType) {
+		writeError(w, apierror.ErrUnknownFormType)
+		return
+	}
+
+	item, err := s.cloneInspection(r.Context(), id, formType, authUser.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, apierror.ErrInspectionNotFound)
+			return
+		}
+		writeError(w, apierror.Internal("Не удалось скопировать проверку"))
 		return
 	}
 	writeJSON(w, http.StatusCreated, item)
@@ -62,54 +111,79 @@ func (s *server) handleInspectionCreate(w http.ResponseWriter, r *http.Request,
 func (s *server) handleInspectionUpdate(w http.ResponseWriter, r *http.Request, authUser user) {
 	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный идентификатор")
+		writeError(w, apierror.ErrInvalidID)
 		return
 	}
 	payload, err := decodeInspectionPayload(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, apierror.ErrInvalidJSON)
+		return
+	}
+	if err := validateInspectionPayload(payload); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	conflicts, ok := s.checkInspectorConflicts(w, r, payload, id)
+	if !ok {
 		return
 	}
 
 	item, err := s.updateInspection(r.Context(), id, payload, authUser.Name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "Проверка не найдена")
+			writeError(w, apierror.ErrInspectionNotFound)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "Не удалось обновить проверку")
+		writeError(w, apierror.Internal("Не удалось обновить проверку"))
 		return
 	}
+	item.Conflicts = conflicts
 	writeJSON(w, http.StatusOK, item)
 }
 
 func (s *server) handleInspectionDelete(w http.ResponseWriter, r *http.Request, authUser user) {
 	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный идентификатор")
+		writeError(w, apierror.ErrInvalidID)
 		return
 	}
 	res, err := s.db.ExecContext(r.Context(), "DELETE FROM act WHERE id=?", id)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось удалить проверку")
+		writeError(w, apierror.Internal("Не удалось удалить проверку"))
 		return
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		writeError(w, http.StatusNotFound, "Проверка не найдена")
+		writeError(w, apierror.ErrInspectionNotFound)
 		return
 	}
 	writeJSON(w, http.StatusNoContent, nil)
 }
 
 type inspectionListQuery struct {
-	Page     int
-	PageSize int
-	Year     int
-	Ogrn     string
+	Page         int
+	PageSize     int
+	Year         int
+	Ogrn         string
+	SearchTerms  []string
+	FormTypes    []string
+	Statuses     []string
+	Organization string
+	Inspector    string
+	Author       string
+	OrderNumber  string
+	LetterNumber string
+	StartFrom    *time.Time
+	StartTo      *time.Time
+	EndFrom      *time.Time
+	EndTo        *time.Time
+	Sort         []inspectionSortKey
 }
 
-func parseInspectionListQuery(r *http.Request) inspectionListQuery {
+// parseInspectionListQuery reads the list filters. Unknown form types,
+// statuses and sort keys are ignored, but a malformed date filter is a 400:
+// dropping it would silently widen the list.
+func parseInspectionListQuery(w http.ResponseWriter, r *http.Request) (inspectionListQuery, bool) {
 	const (
 		defaultPageSize = 10
 		maxPageSize     = 500
@@ -122,12 +196,94 @@ func parseInspectionListQuery(r *http.Request) inspectionListQuery {
 	}
 	year := parsePositiveInt(values.Get("year"), 0)
 	ogrn := strings.TrimSpace(values.Get("ogrn"))
-	return inspectionListQuery{
-		Page:     page,
-		PageSize: pageSize,
-		Year:     year,
-		Ogrn:     ogrn,
+	terms := parseSearchTerms(values.Get("q"))
+	query := inspectionListQuery{
+		Page:         page,
+		PageSize:     pageSize,
+		Year:         year,
+		Ogrn:         ogrn,
+		SearchTerms:  terms,
+		FormTypes:    parseAllowedValues(values["form_type"], inspectionFormTypes),
+		Statuses:     parseAllowedValues(values["status"], inspectionStatuses),
+		Organization: strings.TrimSpace(values.Get("organization")),
+		Inspector:    strings.TrimSpace(values.Get("inspector")),
+		Author:       strings.TrimSpace(values.Get("author")),
+		OrderNumber:  strings.TrimSpace(values.Get("order_number")),
+		LetterNumber: strings.TrimSpace(values.Get("letter_number")),
+		Sort:         parseInspectionSort(values.Get("sort"), len(terms) > 0),
+	}
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{
+		{"date_start_from", &query.StartFrom},
+		{"date_start_to", &query.StartTo},
+		{"date_end_from", &query.EndFrom},
+		{"date_end_to", &query.EndTo},
+	} {
+		raw := strings.TrimSpace(values.Get(param.name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			writeError(w, apierror.BadRequest("Некорректная дата в фильтре "+param.name+", ожидается ГГГГ-ММ-ДД"))
+			return inspectionListQuery{}, false
+		}
+		*param.target = &parsed
+	}
+	return query, true
+}
+
+// parseAllowedValues keeps only the values present in allowed. Both repeated
+// parameters (?status=a&status=b) and comma separated lists are accepted.
+func parseAllowedValues(raw []string, allowed []string) []string {
+	var result []string
+	for _, item := range raw {
+		for _, value := range strings.Split(item, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" || !containsString(allowed, value) || containsString(result, value) {
+				continue
+			}
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// parseInspectionSort parses "sort=date_start,-created_at": keys are
+// separated by commas and a leading "-" means descending order. Unknown keys
+// are ignored; without any valid key the list is sorted by relevance when
+// searching and by creation time otherwise.
+func parseInspectionSort(raw string, searching bool) []inspectionSortKey {
+	var keys []inspectionSortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		desc := false
+		switch {
+		case strings.HasPrefix(part, "-"):
+			desc = true
+			part = part[1:]
+		case strings.HasPrefix(part, "+"):
+			part = part[1:]
+		}
+		if _, ok := inspectionSortColumns[part]; !ok || seen[part] {
+			continue
+		}
+		if part == "relevance" && !searching {
+			continue
+		}
+		seen[part] = true
+		keys = append(keys, inspectionSortKey{Field: part, Desc: desc})
+	}
+	if len(keys) == 0 && searching {
+		keys = []inspectionSortKey{{Field: "relevance", Desc: true}, {Field: "created_at", Desc: true}}
+	}
+	if len(keys) == 0 {
+		keys = []inspectionSortKey{{Field: "created_at", Desc: true}}
 	}
+	return keys
 }
 
 func parsePositiveInt(value string, fallback int) int {
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// handleOrganizationHistory returns the previous inspections of an OGRN with
+// the findings of each, for planning repeat visits.
+func (s *server) handleOrganizationHistory(w http.ResponseWriter, r *http.Request, authUser user) {
+	ogrn := mux.Vars(r)["ogrn"]
+	if !validation.OGRN(ogrn) {
+		writeError(w, apierror.ErrInvalidOGRN)
+		return
+	}
+	history, err := s.organizationHistory(r.Context(), ogrn)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить историю проверок"))
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// handleOrganizationDuplicates lists groups of registry records that are
+// likely one organization typed differently.
+func (s *server) handleOrganizationDuplicates(w http.ResponseWriter, r *http.Request, authUser user) {
+	groups, err := s.findDuplicateOrganizations(r.Context())
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось найти дубликаты организаций"))
+		return
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+func (s *server) handleOrganizationMerge(w http.ResponseWriter, r *http.Request, authUser user) {
+	var payload organizationMergePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, apierror.ErrInvalidJSON)
+		return
+	}
+	if err := validateOrganizationMergePayload(payload); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	merge, err := s.mergeOrganizations(r.Context(), payload, authUser.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			writeError(w, apierror.ErrOrganizationNotFound)
+		case errors.Is(err, errMergeLeaderTerms):
+			writeError(w, apierror.ErrLeaderPeriodOverlap.WithMessage("Сроки полномочий руководителей объединяемых организаций пересекаются: завершите лишние сроки перед объединением"))
+		default:
+			log.Println("organization merge error:", err)
+			writeError(w, apierror.Internal("Не удалось объединить организации"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, merge)
+}
+
+func (s *server) handleOrganizationMerges(w http.ResponseWriter, r *http.Request, authUser user) {
+	list, err := s.listOrganizationMerges(r.Context())
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить журнал объединений"))
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *server) handleOrganizationMergeUndo(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	merge, err := s.undoOrganizationMerge(r.Context(), id, authUser.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			writeError(w, apierror.ErrMergeNotFound)
+		case errors.Is(err, errMergeUndone):
+			writeError(w, apierror.ErrMergeUndone)
+		case errors.Is(err, errMergeTargetGone):
+			writeError(w, apierror.ErrMergeUndoConflict.WithMessage("Организация, в которую выполнено объединение, уже удалена или объединена с другой"))
+		case errors.Is(err, errMergeSourceExists):
+			writeError(w, apierror.ErrMergeUndoConflict)
+		default:
+			log.Println("organization merge undo error:", err)
+			writeError(w, apierror.Internal("Не удалось отменить объединение"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, merge)
+}
+
+// handleOrganizationRefresh starts comparing the registry with DaData in the
+// background; the differences appear in the review queue.
+func (s *server) handleOrganizationRefresh(w http.ResponseWriter, r *http.Request, authUser user) {
+	if s.parties == nil {
+		writeError(w, apierror.ErrDadataNotConfigured)
+		return
+	}
+	if !s.refreshing.TryLock() {
+		writeError(w, apierror.ErrRefreshInProgress)
+		return
+	}
+	go func() {
+		defer s.refreshing.Unlock()
+		result, err := s.refreshOrganizations(context.Background(), s.parties)
+		if err != nil {
+			log.Println("organization refresh error:", err)
+			return
+		}
+		log.Printf("organization refresh by %s: %+v", authUser.Login, result)
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// handleOrganizationChanges returns the review queue. status is a
+// comma-separated filter ("pending" by default, "all" for everything).
+func (s *server) handleOrganizationChanges(w http.ResponseWriter, r *http.Request, authUser user) {
+	statuses := []string{organizationChangePending}
+	if raw := strings.TrimSpace(r.URL.Query().Get("status")); raw == "all" {
+		statuses = organizationChangeStatuses
+	} else if raw != "" {
+		statuses = nil
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			if !containsString(organizationChangeStatuses, status) {
+				writeError(w, apierror.BadRequest("Неизвестный статус изменения: "+status))
+				return
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	list, err := s.listOrganizationChanges(r.Context(), statuses)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить изменения организаций"))
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleOrganizationChangeReview accepts or rejects a change from the queue.
+func (s *server) handleOrganizationChangeReview(w http.ResponseWriter, r *http.Request, authUser user) {
+	vars := mux.Vars(r)
+	id, err := parseID(vars["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	change, err := s.reviewOrganizationChange(r.Context(), id, vars["decision"] == "accept", authUser.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			writeError(w, apierror.ErrChangeNotFound)
+		case errors.Is(err, errOrganizationChangeReviewed):
+			writeError(w, apierror.ErrChangeReviewed)
+		case errors.Is(err, errLeaderStartsToday):
+			writeError(w, apierror.ErrLeaderPeriodOverlap.WithMessage("Срок полномочий другого руководителя уже начинается сегодня"))
+		default:
+			log.Println("organization change review error:", err)
+			writeError(w, apierror.Internal("Не удалось применить изменение"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, change)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/gorilla/mux"
+)
+
+func (s *server) handlePlansList(w http.ResponseWriter, r *http.Request, authUser user) {
+	plans, err := s.listPlans(r.Context())
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить планы проверок"))
+		return
+	}
+	writeJSON(w, http.StatusOK, plans)
+}
+
+func (s *server) handlePlanGet(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	plan, err := s.fetchPlan(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, apierror.ErrPlanNotFound)
+			return
+		}
+		writeError(w, apierror.Internal("Не удалось загрузить план проверок"))
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+func (s *server) handlePlanCreate(w http.ResponseWriter, r *http.Request, authUser user) {
+	payload, ok := decodePlanPayload(w, r)
+	if !ok {
+		return
+	}
+	id, err := s.insertPlan(r.Context(), payload, authUser.Name)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			writeError(w, apierror.ErrPlanYearTaken)
+			return
+		}
+		writeError(w, apierror.Internal("Не удалось сохранить план проверок"))
+		return
+	}
+	plan, err := s.fetchPlan(r.Context(), id)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить план проверок"))
+		return
+	}
+	writeJSON(w, http.StatusCreated, plan)
+}
+
+func (s *server) handlePlanUpdate(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	payload, ok := decodePlanPayload(w, r)
+	if !ok {
+		return
+	}
+	found, err := s.updatePlan(r.Context(), id, payload)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			writeError(w, apierror.ErrPlanYearTaken)
+			return
+		}
+		writeError(w, apierror.Internal("Не удалось сохранить план проверок"))
+		return
+	}
+	if !found {
+		writeError(w, apierror.ErrPlanNotFound)
+		return
+	}
+	plan, err := s.fetchPlan(r.Context(), id)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить план проверок"))
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+func (s *server) handlePlanDelete(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	found, err := s.deletePlan(r.Context(), id)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось удалить план проверок"))
+		return
+	}
+	if !found {
+		writeError(w, apierror.ErrPlanNotFound)
+		return
+	}
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+func (s *server) handlePlanItemCreate(w http.ResponseWriter, r *http.Request, authUser user) {
+	planID, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	if !s.requirePlan(r.Context(), w, planID) {
+		return
+	}
+	payload, ok := s.decodePlanItemPayload(w, r)
+	if !ok {
+		return
+	}
+	ids, err := s.insertPlanItems(r.Context(), planID, []planItemPayload{payload})
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось сохранить позицию плана"))
+		return
+	}
+	item, err := s.fetchPlanItem(r.Context(), planID, int(ids[0]))
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить позицию плана"))
+		return
+	}
+	writeJSON(w, http.StatusCreated, item)
+}
+
+func (s *server) handlePlanItemUpdate(w http.ResponseWriter, r *http.Request, authUser user) {
+	planID, itemID, ok := parsePlanItemIDs(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := s.requirePlanItem(r.Context(), w, planID, itemID); !ok {
+		return
+	}
+	payload, ok := s.decodePlanItemPayload(w, r)
+	if !ok {
+		return
+	}
+	if err := s.updatePlanItem(r.Context(), planID, itemID, payload); err != nil {
+		writeError(w, apierror.Internal("Не удалось сохранить позицию плана"))
+		return
+	}
+	item, err := s.fetchPlanItem(r.Context(), planID, itemID)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить позицию плана"))
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *server) handlePlanItemDelete(w http.ResponseWriter, r *http.Request, authUser user) {
+	planID, itemID, ok := parsePlanItemIDs(w, r)
+	if !ok {
+		return
+	}
+	found, err := s.deletePlanItem(r.Context(), planID, itemID)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось удалить позицию плана"))
+		return
+	}
+	if !found {
+		writeError(w, apierror.ErrPlanItemNotFound)
+		return
+	}
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+// handlePlanItemInspection creates a draft inspection prefilled from a plan
+// line and links it to the line.
+func (s *server) handlePlanItemInspection(w http.ResponseWriter, r *http.Request, authUser user) {
+	planID, itemID, ok := parsePlanItemIDs(w, r)
+	if !ok {
+		return
+	}
+	item, ok := s.requirePlanItem(r.Context(), w, planID, itemID)
+	if !ok {
+		return
+	}
+
+	inspection, err := s.createInspectionFromPlanItem(r.Context(), item, authUser.Name)
+	if err != nil {
+		if errors.Is(err, errPlanItemHasInspection) {
+			writeError(w, apierror.ErrPlanItemHasInspection)
+			return
+		}
+		log.Println("plan inspection error:", err)
+		writeError(w, apierror.Internal("Не удалось создать проверку по плану"))
+		return
+	}
+	writeJSON(w, http.StatusCreated, inspection)
+}
+
+// handlePlanImport appends plan lines from an uploaded XLSX/CSV file
+// (multipart field "file"). Like the inspection import it supports
+// dry_run=true and rejects only the invalid rows.
+func (s *server) handlePlanImport(w http.ResponseWriter, r *http.Request, authUser user) {
+	planID, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	if !s.requirePlan(r.Context(), w, planID) {
+		return
+	}
+
+	table, ok := readImportUpload(w, r)
+	if !ok {
+		return
+	}
+	employees, err := s.loadEmployeeNames(r.Context())
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить сотрудников"))
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	rows, rowErrors, unknown := mapPlanImportRows(table, employees)
+	valid, rowErrors := validatePlanImportRows(rows, rowErrors, employees)
+
+	result := importResult{
+		DryRun:         dryRun,
+		TotalRows:      len(rows),
+		ValidRows:      len(valid),
+		IDs:            []int64{},
+		UnknownColumns: unknown,
+	}
+	if !dryRun && len(valid) > 0 {
+		ids, err := s.insertPlanItems(r.Context(), planID, valid)
+		if err != nil {
+			log.Println("plan import error:", err)
+			writeError(w, apierror.Internal("Не удалось сохранить позиции плана"))
+			return
+		}
+		result.IDs = ids
+		result.Imported = len(ids)
+	}
+	s.writeImportResult(w, result, rowErrors)
+}
+
+func decodePlanPayload(w http.ResponseWriter, r *http.Request) (planPayload, bool) {
+	var payload planPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, apierror.ErrInvalidJSON)
+		return payload, false
+	}
+	payload.Title = strings.TrimSpace(payload.Title)
+	if err := validatePlanPayload(payload); err != nil {
+		writeValidationError(w, err)
+		return payload, false
+	}
+	return payload, true
+}
+
+func (s *server) decodePlanItemPayload(w http.ResponseWriter, r *http.Request) (planItemPayload, bool) {
+	var payload planItemPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, apierror.ErrInvalidJSON)
+		return payload, false
+	}
+	payload.Ogrn = strings.TrimSpace(payload.Ogrn)
+	payload.OrganizationName = strings.TrimSpace(payload.OrganizationName)
+	payload.FormType = strings.TrimSpace(payload.FormType)
+
+	employees, err := s.loadEmployeeNames(r.Context())
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось загрузить сотрудников"))
+		return payload, false
+	}
+	if err := validatePlanItemPayload(payload, employees); err != nil {
+		writeValidationError(w, err)
+		return payload, false
+	}
+	return payload, true
+}
+
+func parsePlanItemIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	planID, err := parseID(vars["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return 0, 0, false
+	}
+	itemID, err := parseID(vars["itemId"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return 0, 0, false
+	}
+	return planID, itemID, true
+}
+
+func (s *server) requirePlan(ctx context.Context, w http.ResponseWriter, id int) bool {
+	var found int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM inspection_plan WHERE id=?", id).Scan(&found)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, apierror.ErrPlanNotFound)
+			return false
+		}
+		writeError(w, apierror.Internal("Не удалось загрузить план проверок"))
+		return false
+	}
+	return true
+}
+
+func (s *server) requirePlanItem(ctx context.Context, w http.ResponseWriter, planID, itemID int) (planItem, bool) {
+	item, err := s.fetchPlanItem(ctx, planID, itemID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, apierror.ErrPlanItemNotFound)
+			return planItem{}, false
+		}
+		writeError(w, apierror.Internal("Не удалось загрузить позицию плана"))
+		return planItem{}, false
+	}
+	return item, true
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/gorilla/mux"
+)
+
+var reminderStatuses = []string{
+	reminderStatusUpcoming,
+	reminderStatusDue,
+	reminderStatusOverdue,
+	reminderStatusDone,
+}
+
+type reminderListResponse struct {
+	Items []reminder     `json:"items"`
+	Rules []deadlineRule `json:"rules"`
+	Total int            `json:"total"`
+	Count map[string]int `json:"count"`
+}
+
+// handleRemindersList returns the deadlines of recent inspections. status is
+// a comma-separated filter ("due,overdue" by default, "all" for everything);
+// mine=true keeps the inspections of the current user.
+func (s *server) handleRemindersList(w http.ResponseWriter, r *http.Request, authUser user) {
+	query := r.URL.Query()
+	statuses := []string{reminderStatusDue, reminderStatusOverdue}
+	if raw := strings.TrimSpace(query.Get("status")); raw == "all" {
+		statuses = reminderStatuses
+	} else if raw != "" {
+		statuses = nil
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			if !containsString(reminderStatuses, status) {
+				writeError(w, apierror.BadRequest("Неизвестный статус срока: "+status))
+				return
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	mine, _ := strconv.ParseBool(query.Get("mine"))
+
+	all, err := s.computeReminders(r.Context(), reminderDay(time.Now(), s.config.ReminderTimeZone))
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось рассчитать сроки"))
+		return
+	}
+
+	response := reminderListResponse{
+		Items: []reminder{},
+		Rules: s.deadlineRules,
+		Count: make(map[string]int),
+	}
+	name := normalizeEmployeeName(authUser.Name)
+	for _, item := range all {
+		if mine && !hasInspector(item.Inspectors, name) {
+			continue
+		}
+		response.Count[item.Status]++
+		if containsString(statuses, item.Status) {
+			response.Items = append(response.Items, item)
+		}
+	}
+	response.Total = len(response.Items)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleReminderComplete marks a deadline done (POST) or open again (DELETE).
+func (s *server) handleReminderComplete(w http.ResponseWriter, r *http.Request, authUser user) {
+	rule, ok := s.findDeadlineRule(mux.Vars(r)["rule"])
+	if !ok {
+		writeError(w, apierror.ErrDeadlineRuleNotFound)
+		return
+	}
+	actID, ok := s.requireInspection(w, r)
+	if !ok {
+		return
+	}
+
+	done := r.Method == http.MethodPost
+	if err := s.setDeadlineCompleted(r.Context(), actID, rule.Code, done, authUser.Name); err != nil {
+		writeError(w, apierror.Internal("Не удалось сохранить отметку о сроке"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"act_id": actID, "rule": rule.Code, "completed": done})
+}
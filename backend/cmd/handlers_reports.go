@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+)
+
+// handleAnnualReport builds the annual summary DOCX. year defaults to the
+// current one; ogrn (comma-separated or repeated) limits the report to the
+// listed organizations.
+func (s *server) handleAnnualReport(w http.ResponseWriter, r *http.Request, authUser user) {
+	values := r.URL.Query()
+	now := time.Now()
+	query := annualReportQuery{Year: now.Year()}
+	if raw := strings.TrimSpace(values.Get("year")); raw != "" {
+		year, err := strconv.Atoi(raw)
+		if err != nil || year < 2000 || year > 2100 {
+			writeError(w, apierror.BadRequest("Некорректный год отчета"))
+			return
+		}
+		query.Year = year
+	}
+	for _, raw := range values["ogrn"] {
+		for _, ogrn := range strings.Split(raw, ",") {
+			ogrn = strings.TrimSpace(ogrn)
+			if ogrn == "" || containsString(query.OGRNs, ogrn) {
+				continue
+			}
+			if !validation.OGRN(ogrn) {
+				writeError(w, apierror.ErrInvalidOGRN.WithDetails([]string{ogrn}))
+				return
+			}
+			query.OGRNs = append(query.OGRNs, ogrn)
+		}
+	}
+
+	report, err := s.buildAnnualReport(r.Context(), query)
+	if err != nil {
+		log.Println("annual report error:", err)
+		writeError(w, apierror.Internal("Не удалось сформировать годовой отчет"))
+		return
+	}
+	docxBytes, err := buildAnnualReportDocx(report, now)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось сформировать годовой отчет"))
+		return
+	}
+
+	filename := fmt.Sprintf("annual-report-%d.docx", query.Year)
+	w.Header().Set("Content-Type", docxContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(docxBytes)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/gorilla/mux"
+)
+
+// handleStats returns the totals and every breakdown for the range.
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request, authUser user) {
+	query, ok := parseStatsQuery(w, r)
+	if !ok {
+		return
+	}
+	totals, err := s.statsTotals(r.Context(), query)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось рассчитать статистику"))
+		return
+	}
+	response := statsResponse{
+		From:   query.From.Format(dateLayout),
+		To:     query.To.Format(dateLayout),
+		Totals: totals,
+		Groups: make(map[string][]statsBucket, len(statsGroupOrder)),
+	}
+	for _, name := range statsGroupOrder {
+		buckets, err := s.statsBuckets(r.Context(), statsGroups[name], query)
+		if err != nil {
+			writeError(w, apierror.Internal("Не удалось рассчитать статистику"))
+			return
+		}
+		response.Groups[name] = buckets
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleStatsGroup returns one breakdown: form_type, month, status,
+// inspector or organization.
+func (s *server) handleStatsGroup(w http.ResponseWriter, r *http.Request, authUser user) {
+	name := mux.Vars(r)["group"]
+	group, ok := statsGroups[name]
+	if !ok {
+		writeError(w, apierror.ErrNotFound.WithMessage("Неизвестный разрез статистики"))
+		return
+	}
+	query, ok := parseStatsQuery(w, r)
+	if !ok {
+		return
+	}
+	buckets, err := s.statsBuckets(r.Context(), group, query)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось рассчитать статистику"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"from":  query.From.Format(dateLayout),
+		"to":    query.To.Format(dateLayout),
+		"group": name,
+		"items": buckets,
+	})
+}
+
+// parseStatsQuery reads from/to (YYYY-MM-DD, the current year by default),
+// form_type and limit.
+func parseStatsQuery(w http.ResponseWriter, r *http.Request) (statsQuery, bool) {
+	values := r.URL.Query()
+	now := time.Now()
+	query := statsQuery{
+		From: time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(now.Year(), time.December, 31, 0, 0, 0, 0, time.UTC),
+	}
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		raw := strings.TrimSpace(values.Get(param.name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			writeError(w, apierror.BadRequest("Некорректная дата, ожидается ГГГГ-ММ-ДД"))
+			return statsQuery{}, false
+		}
+		*param.target = parsed
+	}
+	if query.To.Before(query.From) {
+		writeError(w, apierror.BadRequest("Дата окончания периода раньше даты начала"))
+		return statsQuery{}, false
+	}
+
+	if formType := strings.TrimSpace(values.Get("form_type")); formType != "" {
+		if !containsString(inspectionFormTypes, formType) {
+			writeError(w, apierror.ErrUnknownFormType)
+			return statsQuery{}, false
+		}
+		query.FormType = formType
+	}
+	if raw := strings.TrimSpace(values.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			writeError(w, apierror.BadRequest("Некорректный limit"))
+			return statsQuery{}, false
+		}
+		query.Limit = limit
+	}
+	return query, true
+}
@@ -3,19 +3,21 @@ package main
 import (
 	"encoding/json"
 	"net/http"
+	netmail "net/mail"
 	"strings"
 
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
 	"github.com/gorilla/mux"
 )
 
 func (s *server) handleUsersList(w http.ResponseWriter, r *http.Request, authUser user) {
 	rows, err := s.db.QueryContext(r.Context(), `
-		SELECT id, fio, login, password, role
+		SELECT id, fio, login, password, role, COALESCE(email, '')
 		FROM users
 		ORDER BY id
 	`)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось загрузить пользователей")
+		writeError(w, apierror.Internal("Не удалось загрузить пользователей"))
 		return
 	}
 	defer rows.Close()
@@ -23,8 +25,8 @@ func (s *server) handleUsersList(w http.ResponseWriter, r *http.Request, authUse
 	var list []user
 	for rows.Next() {
 		var item user
-		if err := rows.Scan(&item.ID, &item.Name, &item.Login, &item.Password, &item.Role); err != nil {
-			writeError(w, http.StatusInternalServerError, "Не удалось обработать пользователей")
+		if err := rows.Scan(&item.ID, &item.Name, &item.Login, &item.Password, &item.Role, &item.Email); err != nil {
+			writeError(w, apierror.Internal("Не удалось обработать пользователей"))
 			return
 		}
 		list = append(list, item)
@@ -39,9 +41,15 @@ func (s *server) handleUserCreate(w http.ResponseWriter, r *http.Request, authUs
 		Login    string `json:"login"`
 		Password string `json:"password"`
 		Role     string `json:"role"`
+		Email    string `json:"email"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный формат запроса")
+		writeError(w, apierror.ErrInvalidJSON)
+		return
+	}
+	payload.Email = strings.TrimSpace(payload.Email)
+	if !validEmail(payload.Email) {
+		writeError(w, apierror.ErrValidation.WithMessage("Некорректный адрес электронной почты"))
 		return
 	}
 	payload.Name = strings.TrimSpace(payload.Name)
@@ -50,51 +58,51 @@ func (s *server) handleUserCreate(w http.ResponseWriter, r *http.Request, authUs
 	payload.Role = normalizeRole(payload.Role)
 
 	if payload.Name == "" || payload.Login == "" || payload.Password == "" {
-		writeError(w, http.StatusBadRequest, "Заполните имя, логин и пароль")
+		writeError(w, apierror.ErrValidation.WithMessage("Заполните имя, логин и пароль"))
 		return
 	}
 
 	result, err := s.db.ExecContext(r.Context(), `
-		INSERT INTO users (fio, login, password, role)
-		VALUES (?, ?, ?, ?)
-	`, payload.Name, payload.Login, payload.Password, payload.Role)
+		INSERT INTO users (fio, login, password, role, email)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''))
+	`, payload.Name, payload.Login, payload.Password, payload.Role, payload.Email)
 	if err != nil {
 		if isDuplicateEntry(err) {
-			writeError(w, http.StatusConflict, "Логин уже занят")
+			writeError(w, apierror.ErrLoginTaken)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "Не удалось сохранить пользователя")
+		writeError(w, apierror.Internal("Не удалось сохранить пользователя"))
 		return
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось сохранить пользователя")
+		writeError(w, apierror.Internal("Не удалось сохранить пользователя"))
 		return
 	}
 
-	created := user{ID: int(id), Name: payload.Name, Login: payload.Login, Password: payload.Password, Role: payload.Role}
+	created := user{ID: int(id), Name: payload.Name, Login: payload.Login, Password: payload.Password, Role: payload.Role, Email: payload.Email}
 	writeJSON(w, http.StatusCreated, created)
 }
 
 func (s *server) handleUserDelete(w http.ResponseWriter, r *http.Request, authUser user) {
 	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный идентификатор")
+		writeError(w, apierror.ErrInvalidID)
 		return
 	}
 	if id == authUser.ID {
-		writeError(w, http.StatusConflict, "Нельзя удалить текущего пользователя")
+		writeError(w, apierror.ErrCannotDeleteSelf)
 		return
 	}
 
 	res, err := s.db.ExecContext(r.Context(), "DELETE FROM users WHERE id=?", id)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось удалить пользователя")
+		writeError(w, apierror.Internal("Не удалось удалить пользователя"))
 		return
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		writeError(w, http.StatusNotFound, "Пользователь не найден")
+		writeError(w, apierror.ErrUserNotFound)
 		return
 	}
 	writeJSON(w, http.StatusNoContent, nil)
@@ -103,28 +111,75 @@ func (s *server) handleUserDelete(w http.ResponseWriter, r *http.Request, authUs
 func (s *server) handleUserRoleUpdate(w http.ResponseWriter, r *http.Request, authUser user) {
 	id, err := parseID(mux.Vars(r)["id"])
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный идентификатор")
+		writeError(w, apierror.ErrInvalidID)
 		return
 	}
 	var payload struct {
 		Role string `json:"role"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный формат запроса")
+		writeError(w, apierror.ErrInvalidJSON)
 		return
 	}
 	role := normalizeRole(payload.Role)
 
 	res, err := s.db.ExecContext(r.Context(), "UPDATE users SET role=? WHERE id=?", role, id)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось обновить роль")
+		writeError(w, apierror.Internal("Не удалось обновить роль"))
 		return
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		writeError(w, http.StatusNotFound, "Пользователь не найден")
+		writeError(w, apierror.ErrUserNotFound)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{"id": id, "role": role})
 }
+
+// handleUserEmailUpdate sets the address for deadline reminders; an empty
+// email turns them off for the user.
+func (s *server) handleUserEmailUpdate(w http.ResponseWriter, r *http.Request, authUser user) {
+	id, err := parseID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, apierror.ErrInvalidID)
+		return
+	}
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, apierror.ErrInvalidJSON)
+		return
+	}
+	email := strings.TrimSpace(payload.Email)
+	if !validEmail(email) {
+		writeError(w, apierror.ErrValidation.WithMessage("Некорректный адрес электронной почты"))
+		return
+	}
+
+	res, err := s.db.ExecContext(r.Context(), "UPDATE users SET email=NULLIF(?, '') WHERE id=?", email, id)
+	if err != nil {
+		writeError(w, apierror.Internal("Не удалось обновить адрес почты"))
+		return
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		var exists int
+		if err := s.db.QueryRowContext(r.Context(), "SELECT 1 FROM users WHERE id=?", id).Scan(&exists); err != nil {
+			writeError(w, apierror.ErrUserNotFound)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "email": email})
+}
+
+// validEmail accepts an empty value or a single bare address.
+func validEmail(value string) bool {
+	if value == "" {
+		return true
+	}
+	address, err := netmail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
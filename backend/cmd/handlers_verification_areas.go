@@ -3,12 +3,14 @@ package main
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
 )
 
 func (s *server) handleVerificationAreasGet(w http.ResponseWriter, r *http.Request, authUser user) {
 	areas, err := s.fetchVerificationAreas(r.Context())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось загрузить области проверки")
+		writeError(w, apierror.Internal("Не удалось загрузить области проверки"))
 		return
 	}
 
@@ -20,13 +22,13 @@ func (s *server) handleVerificationAreasUpsert(w http.ResponseWriter, r *http.Re
 
 	var payload verificationAreasUpsertRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "Некорректный JSON")
+		writeError(w, apierror.ErrInvalidJSON)
 		return
 	}
 
 	areas := normalizeVerificationAreas(payload.Items)
 	if err := s.replaceVerificationAreas(r.Context(), areas); err != nil {
-		writeError(w, http.StatusInternalServerError, "Не удалось сохранить области проверки")
+		writeError(w, apierror.Internal("Не удалось сохранить области проверки"))
 		return
 	}
 
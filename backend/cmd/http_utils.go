@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
 	"github.com/go-sql-driver/mysql"
 )
 
@@ -29,8 +31,19 @@ func writeJSON(w http.ResponseWriter, status int, data any) {
 	_ = json.NewEncoder(w).Encode(data)
 }
 
-func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, map[string]string{"message": message})
+func writeError(w http.ResponseWriter, err *apierror.Error) {
+	apierror.Write(w, err)
+}
+
+// writeValidationError responds with 422 validation_failed and the per-field
+// messages in details when err is a validation.Errors, and with 400 otherwise.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var fieldErrors validation.Errors
+	if !errors.As(err, &fieldErrors) {
+		writeError(w, apierror.BadRequest(err.Error()))
+		return
+	}
+	writeError(w, apierror.ErrValidation.WithDetails(fieldErrors))
 }
 
 func parseID(raw string) (int, error) {
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+)
+
+const (
+	idempotencyHeader         = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyMaxLength   = 255
+	// idempotencyBodyMaxBytes bounds the request body the middleware reads
+	// into memory to hash it.
+	idempotencyBodyMaxBytes = 1 << 20
+	// idempotencyCleanupInterval is how often expired keys are deleted.
+	idempotencyCleanupInterval = time.Hour
+)
+
+// idempotentResponse is a response stored for replay.
+type idempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// idempotencySkippedHeaders are not stored with a response: net/http sets
+// them again for the replay.
+var idempotencySkippedHeaders = []string{"Content-Length", "Date", "Connection", "Transfer-Encoding"}
+
+// idempotent makes a POST route safe to retry with an Idempotency-Key: the
+// first response is stored under the key and every retry with the same key
+// and body gets it back, headers such as Location included, instead of
+// running the handler again. Keys are scoped to the session user and the
+// route and live for config.IdempotencyTTL; requests without a session pass
+// through, the handler answers them. Server errors and responses setting
+// cookies are not stored, so such requests run again.
+func (s *server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(idempotencyHeader))
+		if r.Method != http.MethodPost || key == "" {
+			next(w, r)
+			return
+		}
+		authUser, err := s.userFromRequest(r)
+		if err != nil {
+			next(w, r)
+			return
+		}
+		if len(key) > idempotencyKeyMaxLength {
+			writeError(w, apierror.ErrIdempotencyKeyInvalid)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotencyBodyMaxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, apierror.ErrIdempotencyBodyTooLarge)
+				return
+			}
+			writeError(w, apierror.BadRequest("Не удалось прочитать запрос"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := sha256.Sum256(append([]byte(r.Header.Get("Content-Type")+"\n"), body...))
+
+		entry := idempotencyEntry{
+			Scope:       authUser.Login,
+			Key:         key,
+			Route:       r.Method + " " + r.URL.Path,
+			RequestHash: hex.EncodeToString(requestHash[:]),
+		}
+
+		stored, err := s.idempotency.claim(r.Context(), entry)
+		if err != nil {
+			switch {
+			case errors.Is(err, errIdempotencyMismatch):
+				writeError(w, apierror.ErrIdempotencyKeyReused)
+			case errors.Is(err, errIdempotencyInProgress):
+				writeError(w, apierror.ErrIdempotencyInProgress)
+			default:
+				log.Println("idempotency key error:", err)
+				writeError(w, apierror.Internal("Не удалось обработать ключ идемпотентности"))
+			}
+			return
+		}
+		if stored != nil {
+			for name, values := range stored.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(idempotencyReplayedHeader, "true")
+			w.WriteHeader(stored.Status)
+			_, _ = w.Write(stored.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				// A panicking handler must not leave the key claimed until
+				// it expires; net/http still handles the panic.
+				if err := s.idempotency.release(context.WithoutCancel(r.Context()), entry); err != nil {
+					log.Println("idempotency key error:", err)
+				}
+				panic(recovered)
+			}
+		}()
+		next(recorder, r)
+
+		// The request is finished; a cancelled client must not leave the key
+		// claimed forever.
+		ctx := context.WithoutCancel(r.Context())
+		if recorder.status >= http.StatusInternalServerError || recorder.Header().Get("Set-Cookie") != "" {
+			err = s.idempotency.release(ctx, entry)
+		} else {
+			header := recorder.Header().Clone()
+			for _, name := range idempotencySkippedHeaders {
+				header.Del(name)
+			}
+			err = s.idempotency.store(ctx, entry, idempotentResponse{
+				Status: recorder.status,
+				Header: header,
+				Body:   recorder.body.Bytes(),
+			})
+		}
+		if err != nil {
+			log.Println("idempotency key error:", err)
+		}
+	}
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+var (
+	errIdempotencyMismatch   = errors.New("idempotency key reused with another request")
+	errIdempotencyInProgress = errors.New("idempotency key request in progress")
+)
+
+type idempotencyEntry struct {
+	Scope       string
+	Key         string
+	Route       string
+	RequestHash string
+}
+
+// idempotencyStore keeps the claimed keys and their responses.
+type idempotencyStore interface {
+	// claim registers the key for a new request and returns nil. For a key
+	// seen before it returns the stored response, or an error when the
+	// first request is still running or had another body.
+	claim(ctx context.Context, entry idempotencyEntry) (*idempotentResponse, error)
+	store(ctx context.Context, entry idempotencyEntry, response idempotentResponse) error
+	release(ctx context.Context, entry idempotencyEntry) error
+}
+
+// sqlIdempotencyStore keeps the keys in the idempotency_key table.
+type sqlIdempotencyStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+func (s sqlIdempotencyStore) claim(ctx context.Context, entry idempotencyEntry) (*idempotentResponse, error) {
+	expired := time.Now().Add(-s.ttl)
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_key
+		WHERE scope=? AND idem_key=? AND route=? AND created_at < ?
+	`, entry.Scope, entry.Key, entry.Route, expired); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency_key (scope, idem_key, route, request_hash, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, entry.Scope, entry.Key, entry.Route, entry.RequestHash, time.Now())
+	if err == nil {
+		return nil, nil
+	}
+	if !isDuplicateEntry(err) {
+		return nil, err
+	}
+
+	var (
+		requestHash string
+		status      sql.NullInt64
+		header      []byte
+		stored      idempotentResponse
+	)
+	err = s.db.QueryRowContext(ctx, `
+		SELECT request_hash, response_status, COALESCE(response_headers, '{}'), COALESCE(response_body, '')
+		FROM idempotency_key
+		WHERE scope=? AND idem_key=? AND route=?
+	`, entry.Scope, entry.Key, entry.Route).Scan(&requestHash, &status, &header, &stored.Body)
+	if err != nil {
+		return nil, err
+	}
+	if requestHash != entry.RequestHash {
+		return nil, errIdempotencyMismatch
+	}
+	if !status.Valid {
+		return nil, errIdempotencyInProgress
+	}
+	if err := json.Unmarshal(header, &stored.Header); err != nil {
+		return nil, err
+	}
+	stored.Status = int(status.Int64)
+	return &stored, nil
+}
+
+func (s sqlIdempotencyStore) store(ctx context.Context, entry idempotencyEntry, response idempotentResponse) error {
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE idempotency_key
+		SET response_status=?, response_headers=?, response_body=?
+		WHERE scope=? AND idem_key=? AND route=?
+	`, response.Status, header, response.Body, entry.Scope, entry.Key, entry.Route)
+	return err
+}
+
+func (s sqlIdempotencyStore) release(ctx context.Context, entry idempotencyEntry) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_key
+		WHERE scope=? AND idem_key=? AND route=?
+	`, entry.Scope, entry.Key, entry.Route)
+	return err
+}
+
+// runIdempotencyCleanup deletes the keys older than config.IdempotencyTTL
+// every idempotencyCleanupInterval until ctx is done; a claim only drops an
+// expired key when it comes back.
+func (s *server) runIdempotencyCleanup(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyCleanupInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.deleteExpiredIdempotencyKeys(ctx, time.Now()); err != nil {
+			log.Println("idempotency cleanup error:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *server) deleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE created_at < ?", now.Add(-s.config.IdempotencyTTL))
+	return err
+}
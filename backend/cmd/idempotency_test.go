@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryIdempotencyStore is an idempotencyStore without a database.
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryIdempotencyEntry
+}
+
+type memoryIdempotencyEntry struct {
+	requestHash string
+	response    *idempotentResponse
+}
+
+func (m *memoryIdempotencyStore) claim(_ context.Context, entry idempotencyEntry) (*idempotentResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := entry.Scope + "\x00" + entry.Key + "\x00" + entry.Route
+	stored, ok := m.entries[id]
+	if !ok {
+		m.entries[id] = memoryIdempotencyEntry{requestHash: entry.RequestHash}
+		return nil, nil
+	}
+	if stored.requestHash != entry.RequestHash {
+		return nil, errIdempotencyMismatch
+	}
+	if stored.response == nil {
+		return nil, errIdempotencyInProgress
+	}
+	return stored.response, nil
+}
+
+func (m *memoryIdempotencyStore) store(_ context.Context, entry idempotencyEntry, response idempotentResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := entry.Scope + "\x00" + entry.Key + "\x00" + entry.Route
+	stored := m.entries[id]
+	stored.response = &response
+	m.entries[id] = stored
+	return nil
+}
+
+func (m *memoryIdempotencyStore) release(_ context.Context, entry idempotencyEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, entry.Scope+"\x00"+entry.Key+"\x00"+entry.Route)
+	return nil
+}
+
+func newIdempotencyTestServer() *server {
+	srv := &server{
+		sessions:    newSessionStore(),
+		idempotency: &memoryIdempotencyStore{entries: make(map[string]memoryIdempotencyEntry)},
+	}
+	srv.sessions.set("session", user{Login: "petrova"})
+	return srv
+}
+
+func idempotentRequest(key, body string, session bool) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/inspections", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		r.Header.Set(idempotencyHeader, key)
+	}
+	if session {
+		r.AddCookie(&http.Cookie{Name: "session_id", Value: "session"})
+	}
+	return r
+}
+
+func TestIdempotentReplaysFirstResponse(t *testing.T) {
+	srv := newIdempotencyTestServer()
+	calls := 0
+	handler := srv.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Location", "/api/inspections/7")
+		writeJSON(w, http.StatusCreated, map[string]int{"id": 7})
+	})
+
+	first := httptest.NewRecorder()
+	handler(first, idempotentRequest("key-1", `{"a":1}`, true))
+	retry := httptest.NewRecorder()
+	handler(retry, idempotentRequest("key-1", `{"a":1}`, true))
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %d %q", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+	}
+	if got := retry.Header().Get("Location"); got != "/api/inspections/7" {
+		t.Errorf("replayed Location = %q", got)
+	}
+	if got := retry.Header().Get("Content-Type"); got != first.Header().Get("Content-Type") {
+		t.Errorf("replayed Content-Type = %q, want %q", got, first.Header().Get("Content-Type"))
+	}
+	if retry.Header().Get(idempotencyReplayedHeader) != "true" || first.Header().Get(idempotencyReplayedHeader) != "" {
+		t.Errorf("%s header: first %q, retry %q", idempotencyReplayedHeader,
+			first.Header().Get(idempotencyReplayedHeader), retry.Header().Get(idempotencyReplayedHeader))
+	}
+}
+
+func TestIdempotentRejectsAnotherBody(t *testing.T) {
+	srv := newIdempotencyTestServer()
+	calls := 0
+	handler := srv.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeJSON(w, http.StatusCreated, map[string]int{"id": 7})
+	})
+
+	handler(httptest.NewRecorder(), idempotentRequest("key-1", `{"a":1}`, true))
+	conflict := httptest.NewRecorder()
+	handler(conflict, idempotentRequest("key-1", `{"a":2}`, true))
+
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+	if conflict.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", conflict.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotentReleasesKeyAfterServerError(t *testing.T) {
+	srv := newIdempotencyTestServer()
+	status := http.StatusInternalServerError
+	calls := 0
+	handler := srv.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	})
+
+	handler(httptest.NewRecorder(), idempotentRequest("key-1", `{}`, true))
+	status = http.StatusCreated
+	retry := httptest.NewRecorder()
+	handler(retry, idempotentRequest("key-1", `{}`, true))
+
+	if calls != 2 || retry.Code != http.StatusCreated {
+		t.Errorf("retry after 5xx: handler ran %d times, status %d; want 2 and %d", calls, retry.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotentReleasesKeyAfterPanic(t *testing.T) {
+	srv := newIdempotencyTestServer()
+	panicking := true
+	handler := srv.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		if panicking {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was not passed on")
+			}
+		}()
+		handler(httptest.NewRecorder(), idempotentRequest("key-1", `{}`, true))
+	}()
+	panicking = false
+	retry := httptest.NewRecorder()
+	handler(retry, idempotentRequest("key-1", `{}`, true))
+
+	if retry.Code != http.StatusCreated {
+		t.Errorf("retry after panic: status %d, want %d", retry.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotentPassesThrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		session bool
+	}{
+		{"without key", "", true},
+		{"without session", "key-1", false},
+	}
+	for _, tt := range tests {
+		srv := newIdempotencyTestServer()
+		calls := 0
+		handler := srv.idempotent(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusCreated)
+		})
+		handler(httptest.NewRecorder(), idempotentRequest(tt.key, `{}`, tt.session))
+		handler(httptest.NewRecorder(), idempotentRequest(tt.key, `{}`, tt.session))
+		if calls != 2 {
+			t.Errorf("%s: handler ran %d times, want 2", tt.name, calls)
+		}
+	}
+}
+
+func TestIdempotentLimitsBody(t *testing.T) {
+	srv := newIdempotencyTestServer()
+	calls := 0
+	handler := srv.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, idempotentRequest("key-1", strings.Repeat("a", idempotencyBodyMaxBytes+1), true))
+	if calls != 0 || rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: handler ran %d times, status %d; want 0 and %d", calls, rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
@@ -3,21 +3,54 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/address"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
 )
 
 func decodeInspectionPayload(r *http.Request) (actPayload, error) {
 	var payload actPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		return payload, errors.New("Некорректный формат запроса")
+		return payload, err
 	}
 	return payload, nil
 }
 
+// clonePayload keeps who and where was inspected and drops everything tied to
+// the particular inspection: dates, order, numbers, letter and signatures.
+// Without an explicit formType the unplanned variant of the source form is
+// used, since repeat inspections are unplanned.
+func clonePayload(source inspectionResponse, formType string) actPayload {
+	if formType == "" {
+		formType = unplannedFormType(source.Inspection.FormType)
+	}
+	return actPayload{
+		Organization: source.Organization,
+		Head:         source.Head,
+		Inspection: inspectionDTO{
+			FormType:       formType,
+			AddressNoIndex: append(stringList{}, source.Inspection.AddressNoIndex...),
+			Representative: append(stringList{}, source.Inspection.Representative...),
+			Inspectors:     append([]string{}, source.Inspection.Inspectors...),
+		},
+	}
+}
+
+func unplannedFormType(formType string) string {
+	switch formType {
+	case domain.InspectionPlannedDocumentary:
+		return domain.InspectionUnplannedDocumentary
+	case domain.InspectionPlannedOnsite:
+		return domain.InspectionUnplannedOnsite
+	default:
+		return formType
+	}
+}
+
 func buildLetterNumber(letter letterDTO) string {
 	left := strings.TrimSpace(letter.NumberLeft)
 	right := strings.TrimSpace(letter.NumberRight)
@@ -38,8 +71,19 @@ func splitLetterNumber(value string) (string, string) {
 	return strings.TrimSpace(value), ""
 }
 
+// buildAddresses returns the addresses of the inspection without postal
+// codes, with uniform abbreviations and without repeats.
 func buildAddresses(payload actPayload) []string {
-	return normalizeStringList(payload.Inspection.AddressNoIndex)
+	return address.NormalizeList(payload.Inspection.AddressNoIndex)
+}
+
+// buildRawAddresses returns the addresses as entered when keepRaw is set and
+// nil otherwise, which is stored as NULL.
+func buildRawAddresses(payload actPayload, keepRaw bool) ([]byte, error) {
+	if !keepRaw {
+		return nil, nil
+	}
+	return marshalStringSlice(normalizeStringList(payload.Inspection.AddressNoIndex))
 }
 
 func buildRepresentatives(payload actPayload) []string {
@@ -102,9 +146,63 @@ func formatDays(value sql.NullInt64) string {
 	return strconv.FormatInt(value.Int64, 10)
 }
 
+// inspectionDuration returns the working days to store for period. The
+// submitted value is kept, so a count without public holidays survives;
+// without one the days are counted from the start to the early end or, when
+// there is none, to the planned end.
+func inspectionDuration(period periodDTO) *int {
+	if days, err := strconv.Atoi(strings.TrimSpace(period.Days)); err == nil {
+		return &days
+	}
+	start := parseDate(period.StartDate)
+	end := parseDate(period.EarlyEndDate)
+	if end == nil {
+		end = parseDate(period.EndDate)
+	}
+	if start != nil && end != nil && !end.Before(*start) {
+		days := workingDays(*start, *end)
+		return &days
+	}
+	return nil
+}
+
+// workingDays counts Monday to Friday dates from start to end inclusive.
+func workingDays(start, end time.Time) int {
+	days := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if isWorkingDay(day) {
+			days++
+		}
+	}
+	return days
+}
+
 func firstOrEmpty(values []string) string {
 	if len(values) == 0 {
 		return ""
 	}
 	return values[0]
 }
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+func placeholders(count int) string {
+	if count <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+// likePattern wraps value in % wildcards, escaping LIKE metacharacters so that
+// user input is matched literally.
+func likePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(value) + "%"
+}
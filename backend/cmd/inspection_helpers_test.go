@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestInspectionDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		period periodDTO
+		want   string
+	}{
+		{"counted to the planned end", periodDTO{StartDate: "2024-03-04", EndDate: "2024-03-15"}, "10"},
+		{"counted to the early end", periodDTO{StartDate: "2024-03-04", EndDate: "2024-03-15", EarlyEndDate: "2024-03-07"}, "4"},
+		{"submitted value without holidays kept", periodDTO{StartDate: "2024-03-04", EndDate: "2024-03-15", Days: "9"}, "9"},
+		{"unparsable value counted", periodDTO{StartDate: "2024-03-04", EndDate: "2024-03-15", Days: "десять"}, "10"},
+		{"end before start", periodDTO{StartDate: "2024-03-15", EndDate: "2024-03-04"}, ""},
+		{"no dates", periodDTO{}, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if days := inspectionDuration(tt.period); days != nil {
+			got = strconv.Itoa(*days)
+		}
+		if got != tt.want {
+			t.Errorf("%s: inspectionDuration = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
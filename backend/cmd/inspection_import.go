@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+)
+
+const (
+	importBatchSize   = 100
+	importReportTTL   = time.Hour
+	maxImportFileSize = 20 << 20
+)
+
+type importValueKind int
+
+const (
+	importText importValueKind = iota
+	importDate
+	importList
+	importNumber
+)
+
+// importField maps spreadsheet headers onto an actPayload field. Headers are
+// compared case-insensitively; the export column titles are accepted too, so
+// an exported registry can be imported back.
+type importField struct {
+	Field   string
+	Headers []string
+	Kind    importValueKind
+	Apply   func(payload *actPayload, value string)
+}
+
+var importFields = []importField{
+	{"organization.ogrn", []string{"ОГРН", "ОГРНИП", "ОГРН/ОГРНИП"}, importText, func(p *actPayload, v string) { p.Organization.Ogrn = v }},
+	{"organization.name", []string{"Организация", "Наименование", "Полное наименование"}, importText, func(p *actPayload, v string) { p.Organization.Name = v }},
+	{"organization.shortName", []string{"Краткое наименование"}, importText, func(p *actPayload, v string) { p.Organization.ShortName = v }},
+	{"organization.address.legalAddress", []string{"Юридический адрес"}, importText, func(p *actPayload, v string) { p.Organization.Address.LegalAddress = v }},
+	{"organization.address.postalAddress", []string{"Почтовый адрес"}, importText, func(p *actPayload, v string) { p.Organization.Address.PostalAddress = v }},
+	{"head.role", []string{"Должность руководителя"}, importText, func(p *actPayload, v string) { p.Head.Role = v }},
+	{"head.lastName", []string{"Фамилия руководителя"}, importText, func(p *actPayload, v string) { p.Head.LastName = v }},
+	{"head.namePatronymic", []string{"Имя отчество руководителя", "Имя и отчество руководителя"}, importText, func(p *actPayload, v string) { p.Head.NamePatronymic = v }},
+	{"head", []string{"ФИО руководителя"}, importText, func(p *actPayload, v string) {
+		p.Head.LastName, p.Head.NamePatronymic = splitManagerName(v)
+	}},
+	{"inspection.formType", []string{"Форма проверки", "Вид проверки"}, importText, func(p *actPayload, v string) { p.Inspection.FormType = strings.ToLower(v) }},
+	{"inspection.number", []string{"Номер проверки"}, importText, func(p *actPayload, v string) { p.Inspection.Number = v }},
+	{"inspection.mzOrder.number", []string{"Приказ Минздрава №", "Номер приказа"}, importText, func(p *actPayload, v string) { p.Inspection.MzOrder.Number = v }},
+	{"inspection.mzOrder.date", []string{"Дата приказа"}, importDate, func(p *actPayload, v string) { p.Inspection.MzOrder.Date = v }},
+	{"inspection.mzOrder.name", []string{"Наименование приказа"}, importText, func(p *actPayload, v string) { p.Inspection.MzOrder.Name = v }},
+	{"inspection.chomiazOrder.number", []string{"Приказ ЧОМИАЦ №", "Номер приказа ЧОМИАЦ"}, importText, func(p *actPayload, v string) { p.Inspection.ChomiazOrder.Number = v }},
+	{"inspection.chomiazOrder.date", []string{"Дата приказа ЧОМИАЦ"}, importDate, func(p *actPayload, v string) { p.Inspection.ChomiazOrder.Date = v }},
+	{"inspection.period.startDate", []string{"Дата начала"}, importDate, func(p *actPayload, v string) { p.Inspection.Period.StartDate = v }},
+	{"inspection.period.endDate", []string{"Дата окончания"}, importDate, func(p *actPayload, v string) { p.Inspection.Period.EndDate = v }},
+	{"inspection.period.earlyEndDate", []string{"Досрочное окончание", "Дата досрочного окончания"}, importDate, func(p *actPayload, v string) { p.Inspection.Period.EarlyEndDate = v }},
+	{"inspection.period.days", []string{"Рабочих дней"}, importNumber, func(p *actPayload, v string) { p.Inspection.Period.Days = v }},
+	{"inspection.letter.number", []string{"Письмо №", "Номер письма"}, importText, func(p *actPayload, v string) {
+		p.Inspection.Letter.NumberLeft, p.Inspection.Letter.NumberRight = splitLetterNumber(v)
+	}},
+	{"inspection.letter.date", []string{"Дата письма"}, importDate, func(p *actPayload, v string) { p.Inspection.Letter.Date = v }},
+	{"inspection.addressNoIndex", []string{"Адреса проверки", "Адрес проверки"}, importList, func(p *actPayload, v string) {
+		p.Inspection.AddressNoIndex = splitImportList(v)
+	}},
+	{"inspection.inspectors", []string{"Инспекторы"}, importList, func(p *actPayload, v string) { p.Inspection.Inspectors = splitImportList(v) }},
+	{"inspection.representative", []string{"Представители", "Представитель"}, importList, func(p *actPayload, v string) {
+		p.Inspection.Representative = splitImportList(v)
+	}},
+	{"inspection.signatures", []string{"Подписи"}, importList, func(p *actPayload, v string) { p.Inspection.Signatures = splitImportList(v) }},
+}
+
+type importRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+type importRow struct {
+	Line    int
+	Payload actPayload
+}
+
+type importResult struct {
+	DryRun         bool             `json:"dry_run"`
+	TotalRows      int              `json:"total_rows"`
+	ValidRows      int              `json:"valid_rows"`
+	Imported       int              `json:"imported"`
+	IDs            []int64          `json:"ids"`
+	Errors         []importRowError `json:"errors"`
+	UnknownColumns []string         `json:"unknown_columns"`
+	ReportURL      string           `json:"report_url,omitempty"`
+}
+
+// readImportTable returns the rows of an uploaded XLSX or CSV file.
+func readImportTable(data []byte, format string) ([][]string, error) {
+	if format == "xlsx" {
+		return readXLSXRows(data)
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	firstLine := data
+	if index := bytes.IndexByte(data, '\n'); index >= 0 {
+		firstLine = data[:index]
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	return reader.ReadAll()
+}
+
+// mapImportRows turns table rows into payloads. The first non-empty row is
+// the header; line numbers in the result match the spreadsheet rows.
+func mapImportRows(table [][]string) ([]importRow, []importRowError, []string) {
+	headerLine := importHeaderLine(table)
+	if headerLine < 0 {
+		return nil, nil, nil
+	}
+
+	columns := make(map[int]importField)
+	var unknown []string
+	for index, header := range table[headerLine] {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		field, ok := findImportField(header)
+		if !ok {
+			unknown = append(unknown, header)
+			continue
+		}
+		columns[index] = field
+	}
+
+	var rows []importRow
+	var rowErrors []importRowError
+	for index := headerLine + 1; index < len(table); index++ {
+		raw := table[index]
+		if isBlankRow(raw) {
+			continue
+		}
+		line := index + 1
+		row := importRow{Line: line}
+		for column, value := range raw {
+			field, ok := columns[column]
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			converted, err := convertImportValue(field.Kind, value)
+			if err != nil {
+				rowErrors = append(rowErrors, importRowError{Row: line, Field: field.Field, Value: value, Message: err.Error()})
+				continue
+			}
+			field.Apply(&row.Payload, converted)
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, unknown
+}
+
+// importHeaderLine returns the index of the first non-empty row, which holds
+// the column headers, or -1 for an empty table.
+func importHeaderLine(table [][]string) int {
+	for index, row := range table {
+		if !isBlankRow(row) {
+			return index
+		}
+	}
+	return -1
+}
+
+func findImportField(header string) (importField, bool) {
+	normalized := normalizeImportHeader(header)
+	for _, field := range importFields {
+		for _, candidate := range field.Headers {
+			if normalizeImportHeader(candidate) == normalized {
+				return field, true
+			}
+		}
+	}
+	return importField{}, false
+}
+
+func normalizeImportHeader(value string) string {
+	value = strings.ToLower(strings.Join(strings.Fields(value), " "))
+	return strings.TrimRight(value, ".:")
+}
+
+func convertImportValue(kind importValueKind, value string) (string, error) {
+	switch kind {
+	case importDate:
+		parsed, ok := parseImportDate(value)
+		if !ok {
+			return "", errors.New("Некорректная дата")
+		}
+		return parsed.Format(dateLayout), nil
+	case importNumber:
+		number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+		if err != nil || number < 0 || number != float64(int(number)) {
+			return "", errors.New("Ожидается целое число")
+		}
+		return strconv.Itoa(int(number)), nil
+	default:
+		return value, nil
+	}
+}
+
+// parseImportDate accepts ISO dates, dd.mm.yyyy and spreadsheet date serials.
+func parseImportDate(value string) (time.Time, bool) {
+	for _, layout := range []string{dateLayout, "02.01.2006", "2.1.2006", "02.01.06"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+	if len(value) > len(dateLayout) {
+		if parsed, err := time.Parse(dateLayout, value[:len(dateLayout)]); err == nil {
+			return parsed, true
+		}
+	}
+	return xlsxSerialToDate(value)
+}
+
+func splitImportList(value string) []string {
+	separator := ";"
+	if !strings.Contains(value, ";") && strings.Contains(value, "\n") {
+		separator = "\n"
+	}
+	return normalizeStringList(strings.Split(value, separator))
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// validateImportRows checks the mapped rows and returns those without
+// mapping or validation errors together with all the row errors.
+func validateImportRows(rows []importRow, rowErrors []importRowError) ([]importRow, []importRowError) {
+	invalid := make(map[int]bool)
+	for _, item := range rowErrors {
+		invalid[item.Row] = true
+	}
+	var valid []importRow
+	for _, row := range rows {
+		if problems := validateImportRow(row); len(problems) > 0 {
+			rowErrors = append(rowErrors, problems...)
+			invalid[row.Line] = true
+		}
+		if !invalid[row.Line] {
+			valid = append(valid, row)
+		}
+	}
+	return valid, rowErrors
+}
+
+// validateImportRow applies the same rules as the inspection API.
+func validateImportRow(row importRow) []importRowError {
+	err := validateInspectionPayload(row.Payload)
+	var fieldErrors validation.Errors
+	if !errors.As(err, &fieldErrors) {
+		return nil
+	}
+	result := make([]importRowError, 0, len(fieldErrors))
+	for _, item := range fieldErrors {
+		result = append(result, importRowError{
+			Row:     row.Line,
+			Field:   item.Field,
+			Value:   importFieldValue(row.Payload, item.Field),
+			Message: item.Message,
+		})
+	}
+	return result
+}
+
+// importFieldValue returns the imported value of a scalar field for the error
+// report.
+func importFieldValue(payload actPayload, field string) string {
+	switch field {
+	case "organization.ogrn":
+		return payload.Organization.Ogrn
+	case "inspection.formType":
+		return payload.Inspection.FormType
+	case "inspection.mzOrder.date":
+		return payload.Inspection.MzOrder.Date
+	case "inspection.period.startDate":
+		return payload.Inspection.Period.StartDate
+	case "inspection.period.endDate":
+		return payload.Inspection.Period.EndDate
+	case "inspection.period.earlyEndDate":
+		return payload.Inspection.Period.EarlyEndDate
+	default:
+		return ""
+	}
+}
+
+func buildImportReportCSV(rowErrors []importRowError) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	writer := csv.NewWriter(&buf)
+	writer.Comma = ';'
+	if err := writer.Write([]string{"Строка", "Поле", "Значение", "Ошибка"}); err != nil {
+		return nil, err
+	}
+	for _, item := range rowErrors {
+		if err := writer.Write([]string{strconv.Itoa(item.Row), item.Field, item.Value, item.Message}); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+type importReport struct {
+	Data      []byte
+	CreatedAt time.Time
+}
+
+// importReportStore keeps error reports in memory until they expire, so the
+// user can download the report of the last import.
+type importReportStore struct {
+	mu      sync.Mutex
+	reports map[string]importReport
+}
+
+func newImportReportStore() *importReportStore {
+	return &importReportStore{reports: make(map[string]importReport)}
+}
+
+func (s *importReportStore) add(data []byte) (string, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, report := range s.reports {
+		if time.Since(report.CreatedAt) > importReportTTL {
+			delete(s.reports, key)
+		}
+	}
+	s.reports[id] = importReport{Data: data, CreatedAt: time.Now()}
+	return id, nil
+}
+
+func (s *importReportStore) get(id string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report, ok := s.reports[id]
+	if !ok || time.Since(report.CreatedAt) > importReportTTL {
+		return nil, false
+	}
+	return report.Data, true
+}
+
+func importFormat(filename, requested string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(requested))
+	if format == "" {
+		lower := strings.ToLower(filename)
+		switch {
+		case strings.HasSuffix(lower, ".xlsx"):
+			format = "xlsx"
+		case strings.HasSuffix(lower, ".csv"):
+			format = "csv"
+		}
+	}
+	if format != "xlsx" && format != "csv" {
+		return "", errors.New("unsupported import format")
+	}
+	return format, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
+)
+
+func TestMapImportRowsFromXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newXLSXStreamWriter(&buf, "Импорт")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := [][]xlsxCell{
+		{},
+		{xlsxString("ОГРН"), xlsxString("Наименование"), xlsxString("Юридический адрес"), xlsxString("Форма проверки"),
+			xlsxString("Дата начала:"), xlsxString("Рабочих дней"), xlsxString("ФИО руководителя"), xlsxString("Адреса проверки"), xlsxString("Примечание")},
+		{xlsxString("1027700132195"), xlsxString("ООО Ромашка"), xlsxString("г. Москва"), xlsxString("Внеплановая выездная"),
+			xlsxDate("2024-03-01"), xlsxNumber(20), xlsxString("Иванов Иван Иванович"), xlsxString("г. Москва, ул. Тверская, д. 5; г. Москва, ул. Арбат, д. 1"), xlsxString("—")},
+		{xlsxString("1027700132196"), xlsxString("ООО Лютик"), xlsxString("г. Москва"), xlsxString("плановая документарная"),
+			xlsxString("31.02.2024"), xlsxString("пять")},
+		{},
+		{xlsxString(""), xlsxString("ООО Василёк"), xlsxString(""), xlsxString("внеплановая документарная"), xlsxString("01.03.2024")},
+	}
+	for _, row := range table {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := readImportTable(buf.Bytes(), "xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, rowErrors, unknown := mapImportRows(raw)
+
+	if !reflect.DeepEqual(unknown, []string{"Примечание"}) {
+		t.Errorf("unknown columns = %q, want [Примечание]", unknown)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	if lines := []int{rows[0].Line, rows[1].Line, rows[2].Line}; !reflect.DeepEqual(lines, []int{3, 4, 6}) {
+		t.Errorf("row lines = %v, want spreadsheet rows [3 4 6]", lines)
+	}
+
+	first := rows[0].Payload
+	if first.Organization.Ogrn != "1027700132195" || first.Inspection.FormType != domain.InspectionUnplannedOnsite {
+		t.Errorf("first row organization/form = %q/%q", first.Organization.Ogrn, first.Inspection.FormType)
+	}
+	if first.Inspection.Period.StartDate != "2024-03-01" || first.Inspection.Period.Days != "20" {
+		t.Errorf("first row period = %q/%q, want 2024-03-01/20", first.Inspection.Period.StartDate, first.Inspection.Period.Days)
+	}
+	if first.Head.LastName != "Иванов" || first.Head.NamePatronymic != "Иван Иванович" {
+		t.Errorf("first row head = %q %q", first.Head.LastName, first.Head.NamePatronymic)
+	}
+	if want := (stringList{"г. Москва, ул. Тверская, д. 5", "г. Москва, ул. Арбат, д. 1"}); !reflect.DeepEqual(first.Inspection.AddressNoIndex, want) {
+		t.Errorf("first row addresses = %q, want %q", first.Inspection.AddressNoIndex, want)
+	}
+	if errs := validateImportRow(rows[0]); len(errs) != 0 {
+		t.Errorf("first row is valid, got errors %+v", errs)
+	}
+
+	wantMapping := []importRowError{
+		{Row: 4, Field: "inspection.period.startDate", Value: "31.02.2024", Message: "Некорректная дата"},
+		{Row: 4, Field: "inspection.period.days", Value: "пять", Message: "Ожидается целое число"},
+	}
+	if !reflect.DeepEqual(rowErrors, wantMapping) {
+		t.Errorf("mapping errors = %+v, want %+v", rowErrors, wantMapping)
+	}
+
+	second := validateImportRow(rows[1])
+	if !hasImportError(second, 4, "organization.ogrn", "1027700132196") || !hasImportError(second, 4, "inspection.mzOrder.number", "") {
+		t.Errorf("second row errors = %+v, want the OGRN checksum and the missing order number", second)
+	}
+	third := validateImportRow(rows[2])
+	if !hasImportError(third, 6, "organization.ogrn", "") || !hasImportError(third, 6, "organization.address.legalAddress", "") {
+		t.Errorf("third row errors = %+v, want the missing OGRN and legal address", third)
+	}
+}
+
+func TestMapImportRowsFromCSV(t *testing.T) {
+	data := []byte("\ufeffОГРН;Дата приказа;Инспекторы\n1027700132195;2024-02-15T00:00:00;\"Петров П. П.\nСидоров С. С.\"\n")
+	raw, err := readImportTable(data, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, rowErrors, _ := mapImportRows(raw)
+	if len(rowErrors) != 0 || len(rows) != 1 {
+		t.Fatalf("rows = %+v, errors = %+v", rows, rowErrors)
+	}
+	if got := rows[0].Payload.Inspection.MzOrder.Date; got != "2024-02-15" {
+		t.Errorf("order date = %q, want 2024-02-15", got)
+	}
+	if got := rows[0].Payload.Inspection.Inspectors; !reflect.DeepEqual(got, []string{"Петров П. П.", "Сидоров С. С."}) {
+		t.Errorf("inspectors = %q", got)
+	}
+}
+
+func hasImportError(errs []importRowError, row int, field, value string) bool {
+	for _, item := range errs {
+		if item.Row == row && item.Field == field && item.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxSearchTerms      = 8
+	searchSnippetRadius = 40
+)
+
+type searchMatch struct {
+	Score      float64           `json:"score"`
+	Highlights []searchHighlight `json:"highlights"`
+}
+
+// searchHighlight holds an HTML-escaped fragment of a matched field with the
+// matched terms wrapped in <mark>.
+type searchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// actSearchContentExpr concatenates everything a user may remember about an
+// inspection into the single column covered by the FULLTEXT index.
+const actSearchContentExpr = `CONCAT_WS(' ',
+			org.organization_full_name,
+			org.organization_short_name,
+			org.organization_ogrn,
+			org.organization_legal_address,
+			org.organization_postal_address,
+			head.leader_last_name,
+			head.leader_first_name,
+			head.leader_middle_name,
+			insp.inspection_number,
+			insp.minzdrav_order_number,
+			insp.minzdrav_order_name,
+			insp.chomiaz_order_number,
+			insp.letter_number,
+			CAST(insp.addresses AS CHAR)
+		)`
+
+// parseSearchTerms splits the q parameter into words, dropping characters
+// that have a meaning in the FULLTEXT boolean syntax.
+func parseSearchTerms(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '/' && r != '-'
+	})
+	var terms []string
+	for _, field := range fields {
+		field = strings.Trim(field, ".-/")
+		if field == "" || containsString(terms, strings.ToLower(field)) {
+			continue
+		}
+		terms = append(terms, strings.ToLower(field))
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// buildSearchFilter returns the WHERE clause restricting acts to the search
+// terms and the expression used as the relevance score.
+func buildSearchFilter(terms []string, fullText bool) (string, []any, string, []any) {
+	if fullText {
+		against := buildBooleanQuery(terms)
+		clause := "act.id IN (SELECT act_id FROM act_search WHERE MATCH(content) AGAINST(? IN BOOLEAN MODE))"
+		score := "(SELECT MATCH(content) AGAINST(? IN BOOLEAN MODE) FROM act_search WHERE act_search.act_id = act.id)"
+		return clause, []any{against}, score, []any{against}
+	}
+
+	clauses := make([]string, 0, len(terms))
+	scores := make([]string, 0, len(terms))
+	var args, scoreArgs []any
+	for _, term := range terms {
+		clauses = append(clauses, "search.content LIKE ?")
+		scores = append(scores, "(search.content LIKE ?)")
+		args = append(args, likePattern(term))
+		scoreArgs = append(scoreArgs, likePattern(term))
+	}
+	clause := "act.id IN (SELECT search.act_id FROM act_search search WHERE " + strings.Join(clauses, " AND ") + ")"
+	score := "(SELECT " + strings.Join(scores, " + ") + " FROM act_search search WHERE search.act_id = act.id)"
+	return clause, args, score, scoreArgs
+}
+
+func buildBooleanQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		parts = append(parts, `+"`+term+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+func refreshActSearch(ctx context.Context, execer sqlExecer, id int64) error {
+	_, err := execer.ExecContext(ctx, `
+		INSERT INTO act_search (act_id, content)
+		SELECT act.id, `+actSearchContentExpr+`
+		`+inspectionFromClause+`
+		WHERE act.id=?
+		ON DUPLICATE KEY UPDATE content=VALUES(content)
+	`, id)
+	return err
+}
+
+// backfillSearchIndex indexes acts created before act_search existed.
+func (s *server) backfillSearchIndex(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO act_search (act_id, content)
+		SELECT act.id, `+actSearchContentExpr+`
+		`+inspectionFromClause+`
+		WHERE NOT EXISTS (SELECT 1 FROM act_search WHERE act_search.act_id = act.id)
+	`)
+	return err
+}
+
+func buildSearchHighlights(item inspectionResponse, terms []string) []searchHighlight {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"organization.name", item.Organization.Name},
+		{"organization.shortName", item.Organization.ShortName},
+		{"organization.ogrn", item.Organization.Ogrn},
+		{"organization.address.legalAddress", item.Organization.Address.LegalAddress},
+		{"organization.address.postalAddress", item.Organization.Address.PostalAddress},
+		{"head", strings.Join([]string{item.Head.LastName, item.Head.NamePatronymic, item.Head.LastNameTo}, " ")},
+		{"inspection.number", item.Inspection.Number},
+		{"inspection.mzOrder.number", item.Inspection.MzOrder.Number},
+		{"inspection.letter", buildLetterNumber(item.Inspection.Letter)},
+	}
+	for _, address := range item.Inspection.AddressNoIndex {
+		fields = append(fields, struct {
+			name  string
+			value string
+		}{"inspection.addressNoIndex", address})
+	}
+
+	highlights := []searchHighlight{}
+	for _, field := range fields {
+		if snippet, ok := highlightSnippet(field.value, terms); ok {
+			highlights = append(highlights, searchHighlight{Field: field.name, Snippet: snippet})
+		}
+	}
+	return highlights
+}
+
+// highlightSnippet cuts a window around the first matched term and marks
+// every term occurrence inside it. Matching is case-insensitive.
+func highlightSnippet(value string, terms []string) (string, bool) {
+	text := []rune(value)
+	lower := lowerRunes(value)
+
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(lower); i++ {
+		for _, term := range terms {
+			termRunes := lowerRunes(term)
+			if i+len(termRunes) <= len(lower) && string(lower[i:i+len(termRunes)]) == string(termRunes) {
+				spans = append(spans, span{i, i + len(termRunes)})
+				i += len(termRunes) - 1
+				break
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return "", false
+	}
+
+	from := max(spans[0].start-searchSnippetRadius, 0)
+	to := min(spans[0].end+searchSnippetRadius, len(text))
+
+	var builder strings.Builder
+	if from > 0 {
+		builder.WriteString("…")
+	}
+	cursor := from
+	for _, sp := range spans {
+		if sp.start < from || sp.end > to {
+			continue
+		}
+		builder.WriteString(html.EscapeString(string(text[cursor:sp.start])))
+		builder.WriteString("<mark>")
+		builder.WriteString(html.EscapeString(string(text[sp.start:sp.end])))
+		builder.WriteString("</mark>")
+		cursor = sp.end
+	}
+	builder.WriteString(html.EscapeString(string(text[cursor:to])))
+	if to < len(text) {
+		builder.WriteString("…")
+	}
+	return builder.String(), true
+}
+
+// lowerRunes folds the case of value rune by rune, so that the result lines
+// up with []rune(value); strings.ToLower may change the rune count.
+func lowerRunes(value string) []rune {
+	runes := []rune(value)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
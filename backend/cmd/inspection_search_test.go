@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	long := strings.Repeat("а", 50) + " Поликлиника " + strings.Repeat("б", 50)
+	tests := []struct {
+		name  string
+		value string
+		terms []string
+		want  string
+	}{
+		{"case-insensitive", "ГБУЗ Городская поликлиника", []string{"городская"}, "ГБУЗ <mark>Городская</mark> поликлиника"},
+		{"every term", "ООО Ромашка, г. Москва", []string{"ромашка", "москва"}, "ООО <mark>Ромашка</mark>, г. <mark>Москва</mark>"},
+		// strings.ToLower turns İ into two runes; the case still folds.
+		{"rune count changed by ToLower", "İzmir Клиника", []string{"клиника"}, "İzmir <mark>Клиника</mark>"},
+		{"escaped", `ООО "Ромашка" <1>`, []string{"ромашка"}, `ООО &#34;<mark>Ромашка</mark>&#34; &lt;1&gt;`},
+		{"window", long, []string{"поликлиника"},
+			"…" + strings.Repeat("а", 39) + " <mark>Поликлиника</mark> " + strings.Repeat("б", 39) + "…"},
+		{"no match", "ООО Ромашка", []string{"лютик"}, ""},
+	}
+	for _, tt := range tests {
+		got, ok := highlightSnippet(tt.value, tt.terms)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("%s: highlightSnippet = %q, %v; want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestBuildSearchFilterLike(t *testing.T) {
+	clause, args, score, scoreArgs := buildSearchFilter([]string{"ромашка", "50%_"}, false)
+
+	wantClause := "act.id IN (SELECT search.act_id FROM act_search search WHERE search.content LIKE ? AND search.content LIKE ?)"
+	if clause != wantClause {
+		t.Errorf("clause = %q, want %q", clause, wantClause)
+	}
+	wantScore := "(SELECT (search.content LIKE ?) + (search.content LIKE ?) FROM act_search search WHERE search.act_id = act.id)"
+	if score != wantScore {
+		t.Errorf("score = %q, want %q", score, wantScore)
+	}
+	wantArgs := []any{"%ромашка%", `%50\%\_%`}
+	if !reflect.DeepEqual(args, wantArgs) || !reflect.DeepEqual(scoreArgs, wantArgs) {
+		t.Errorf("args = %v, score args = %v; want %v", args, scoreArgs, wantArgs)
+	}
+}
+
+func TestBuildSearchFilterFullText(t *testing.T) {
+	_, args, _, scoreArgs := buildSearchFilter([]string{"ромашка", "москва"}, true)
+	want := []any{`+"ромашка" +"москва"`}
+	if !reflect.DeepEqual(args, want) || !reflect.DeepEqual(scoreArgs, want) {
+		t.Errorf("args = %v, score args = %v; want %v", args, scoreArgs, want)
+	}
+}
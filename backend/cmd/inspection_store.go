@@ -5,31 +5,68 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
 )
 
-func (s *server) fetchInspections(ctx context.Context, query inspectionListQuery) ([]inspectionResponse, int, error) {
-	where, args := buildInspectionFilters(query)
+const (
+	inspectionStatusDraft      = "draft"
+	inspectionStatusPlanned    = "planned"
+	inspectionStatusInProgress = "in_progress"
+	inspectionStatusCompleted  = "completed"
+)
 
-	countQuery := fmt.Sprintf(`
-		SELECT COUNT(*)
-		FROM act
+var inspectionStatuses = []string{
+	inspectionStatusDraft,
+	inspectionStatusPlanned,
+	inspectionStatusInProgress,
+	inspectionStatusCompleted,
+}
+
+var inspectionFormTypes = []string{
+	domain.InspectionPlannedDocumentary,
+	domain.InspectionPlannedOnsite,
+	domain.InspectionUnplannedDocumentary,
+	domain.InspectionUnplannedOnsite,
+}
+
+// inspectionStatusExpr derives the inspection status from its dates: there is
+// no stored status, an inspection is a draft until it gets a start date.
+const inspectionStatusExpr = `CASE
+			WHEN insp.date_start IS NULL THEN 'draft'
+			WHEN insp.date_start > CURRENT_DATE() THEN 'planned'
+			WHEN COALESCE(insp.date_early_end, insp.date_end) IS NULL
+				OR COALESCE(insp.date_early_end, insp.date_end) >= CURRENT_DATE() THEN 'in_progress'
+			ELSE 'completed'
+		END`
+
+type inspectionSortKey struct {
+	Field string
+	Desc  bool
+}
+
+var inspectionSortColumns = map[string]string{
+	"created_at":   "act.created_at",
+	"updated_at":   "act.updated_at",
+	"date_start":   "insp.date_start",
+	"date_end":     "insp.date_end",
+	"form_type":    "insp.inspection_type",
+	"organization": "org.organization_short_name",
+	"ogrn":         "org.organization_ogrn",
+	"number":       "insp.inspection_number",
+	"relevance":    "relevance",
+	"status":       "status",
+	"author":       "act.created_by",
+}
+
+const inspectionFromClause = `FROM act
 		INNER JOIN act_organization org ON org.act_id = act.id
 		INNER JOIN act_head head ON head.act_id = act.id
-		INNER JOIN act_inspection insp ON insp.act_id = act.id
-		WHERE %s
-	`, where)
-	var total int
-	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
-		return nil, 0, err
-	}
+		INNER JOIN act_inspection insp ON insp.act_id = act.id`
 
-	offset := (query.Page - 1) * query.PageSize
-	args = append(args, query.PageSize, offset)
-	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
-		SELECT
+const inspectionSelectColumns = `
 			act.id,
 			org.organization_full_name,
 			COALESCE(org.organization_short_name, ''),
@@ -43,82 +80,125 @@ func (s *server) fetchInspections(ctx context.Context, query inspectionListQuery
 			insp.inspection_type,
 			insp.minzdrav_order_number,
 			insp.minzdrav_order_date,
+			COALESCE(insp.minzdrav_order_name, ''),
+			COALESCE(insp.chomiaz_order_number, ''),
+			insp.chomiaz_order_date,
 			insp.inspection_number,
 			insp.date_start,
 			insp.date_end,
+			insp.date_early_end,
 			insp.duration_work_days,
 			insp.letter_number,
 			insp.letter_date,
 			insp.representative_document,
 			insp.addresses,
+			insp.addresses_raw,
 			insp.authorized_persons,
 			insp.signatories,
 			insp.representatives,
 			act.created_by,
 			act.updated_by,
 			act.created_at,
-			act.updated_at
-		FROM act
-		INNER JOIN act_organization org ON org.act_id = act.id
-		INNER JOIN act_head head ON head.act_id = act.id
-		INNER JOIN act_inspection insp ON insp.act_id = act.id
+			act.updated_at,
+			act.source_act_id,
+			` + inspectionStatusExpr + ` AS status`
+
+func (s *server) fetchInspections(ctx context.Context, query inspectionListQuery) ([]inspectionResponse, int, error) {
+	where, args, _, _ := s.buildInspectionSelection(query)
+
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		%s
 		WHERE %s
-		ORDER BY act.created_at DESC
-		LIMIT ? OFFSET ?
-	`, where), args...)
+	`, inspectionFromClause, where)
+	var total int
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	var items []inspectionResponse
+	offset := (query.Page - 1) * query.PageSize
+	err := s.eachInspection(ctx, query, query.PageSize, offset, func(item inspectionResponse) error {
+		items = append(items, item)
+		return nil
+	})
 	if err != nil {
 		return nil, 0, err
 	}
+	return items, total, nil
+}
+
+// buildInspectionSelection combines the list filters with the search clause
+// and returns the WHERE condition plus the relevance expression for SELECT.
+func (s *server) buildInspectionSelection(query inspectionListQuery) (string, []interface{}, string, []interface{}) {
+	where, args := buildInspectionFilters(query)
+
+	scoreExpr := "0"
+	var scoreArgs []interface{}
+	if len(query.SearchTerms) > 0 {
+		clause, clauseArgs, score, sArgs := buildSearchFilter(query.SearchTerms, s.config.FullTextSearch)
+		where += " AND " + clause
+		args = append(args, clauseArgs...)
+		scoreExpr, scoreArgs = score, sArgs
+	}
+	return where, args, scoreExpr, scoreArgs
+}
+
+// eachInspection reads the inspections matching query row by row and passes
+// them to fn, so callers can stream large results. A zero limit disables
+// pagination.
+func (s *server) eachInspection(ctx context.Context, query inspectionListQuery, limit, offset int, fn func(inspectionResponse) error) error {
+	where, args, scoreExpr, scoreArgs := s.buildInspectionSelection(query)
+
+	pagination := ""
+	args = append(scoreArgs, args...)
+	if limit > 0 {
+		pagination = "LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s,
+			%s AS relevance,
+			%s AS last_inspected_on
+		%s
+		WHERE %s
+		ORDER BY %s
+		%s
+	`, inspectionSelectColumns, scoreExpr, lastInspectedExpr, inspectionFromClause, where, buildInspectionOrder(query.Sort), pagination), args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
-	var items []inspectionResponse
 	for rows.Next() {
-		item, err := scanInspectionRow(rows)
+		var (
+			relevance     sql.NullFloat64
+			lastInspected sql.NullTime
+		)
+		item, err := scanInspectionRow(extraColumnsScanner{scanner: rows, extra: []any{&relevance, &lastInspected}})
 		if err != nil {
-			return nil, 0, err
+			return err
+		}
+		item.LastInspectedOn = formatDate(lastInspected)
+		if len(query.SearchTerms) > 0 {
+			item.Search = &searchMatch{
+				Score:      relevance.Float64,
+				Highlights: buildSearchHighlights(item, query.SearchTerms),
+			}
+		}
+		if err := fn(item); err != nil {
+			return err
 		}
-		items = append(items, item)
 	}
-	return items, total, nil
+	return rows.Err()
 }
 
 func (s *server) fetchInspectionByID(ctx context.Context, id int) (inspectionResponse, error) {
-	row := s.db.QueryRowContext(ctx, `
-		SELECT
-			act.id,
-			org.organization_full_name,
-			COALESCE(org.organization_short_name, ''),
-			org.organization_ogrn,
-			org.organization_legal_address,
-			COALESCE(org.organization_postal_address, ''),
-			head.leader_position,
-			head.leader_last_name,
-			head.leader_first_name,
-			COALESCE(head.leader_middle_name, ''),
-			insp.inspection_type,
-			insp.minzdrav_order_number,
-			insp.minzdrav_order_date,
-			insp.inspection_number,
-			insp.date_start,
-			insp.date_end,
-			insp.duration_work_days,
-			insp.letter_number,
-			insp.letter_date,
-			insp.representative_document,
-			insp.addresses,
-			insp.authorized_persons,
-			insp.signatories,
-			insp.representatives,
-			act.created_by,
-			act.updated_by,
-			act.created_at,
-			act.updated_at
-		FROM act
-		INNER JOIN act_organization org ON org.act_id = act.id
-		INNER JOIN act_head head ON head.act_id = act.id
-		INNER JOIN act_inspection insp ON insp.act_id = act.id
+	row := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		%s
 		WHERE act.id=?
-	`, id)
+	`, inspectionSelectColumns, inspectionFromClause), id)
 
 	item, err := scanInspectionRow(row)
 	if err != nil {
@@ -141,70 +221,173 @@ func buildInspectionFilters(query inspectionListQuery) (string, []interface{}) {
 		args = append(args, query.Year, query.Year, query.Year)
 	}
 
+	if len(query.FormTypes) > 0 {
+		clauses = append(clauses, "insp.inspection_type IN ("+placeholders(len(query.FormTypes))+")")
+		for _, formType := range query.FormTypes {
+			args = append(args, formType)
+		}
+	}
+
+	if len(query.Statuses) > 0 {
+		clauses = append(clauses, "("+inspectionStatusExpr+") IN ("+placeholders(len(query.Statuses))+")")
+		for _, status := range query.Statuses {
+			args = append(args, status)
+		}
+	}
+
+	if query.StartFrom != nil {
+		clauses = append(clauses, "insp.date_start >= ?")
+		args = append(args, *query.StartFrom)
+	}
+	if query.StartTo != nil {
+		clauses = append(clauses, "insp.date_start < ?")
+		args = append(args, query.StartTo.AddDate(0, 0, 1))
+	}
+	if query.EndFrom != nil {
+		clauses = append(clauses, "insp.date_end >= ?")
+		args = append(args, *query.EndFrom)
+	}
+	if query.EndTo != nil {
+		clauses = append(clauses, "insp.date_end < ?")
+		args = append(args, query.EndTo.AddDate(0, 0, 1))
+	}
+
+	if query.Organization != "" {
+		pattern := likePattern(query.Organization)
+		clauses = append(clauses, "(org.organization_full_name LIKE ? OR org.organization_short_name LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+
+	if query.Inspector != "" {
+		clauses = append(clauses, "JSON_SEARCH(insp.authorized_persons, 'one', ?) IS NOT NULL")
+		args = append(args, likePattern(query.Inspector))
+	}
+
+	if query.Author != "" {
+		clauses = append(clauses, "act.created_by LIKE ?")
+		args = append(args, likePattern(query.Author))
+	}
+
+	if query.OrderNumber != "" {
+		pattern := likePattern(query.OrderNumber)
+		clauses = append(clauses, "(insp.minzdrav_order_number LIKE ? OR insp.inspection_number LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+
+	if query.LetterNumber != "" {
+		clauses = append(clauses, "insp.letter_number LIKE ?")
+		args = append(args, likePattern(query.LetterNumber))
+	}
+
 	return strings.Join(clauses, " AND "), args
 }
 
+func buildInspectionOrder(keys []inspectionSortKey) string {
+	parts := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		column, ok := inspectionSortColumns[key.Field]
+		if !ok {
+			continue
+		}
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		parts = append(parts, column+" "+direction)
+	}
+	// act.id keeps the order stable between pages when sort values repeat.
+	parts = append(parts, "act.id DESC")
+	return strings.Join(parts, ", ")
+}
+
 func (s *server) insertInspection(ctx context.Context, payload actPayload, createdBy string) (inspectionResponse, error) {
-	letterNumber := buildLetterNumber(payload.Inspection.Letter)
-	var daysValue *int
-	if payload.Inspection.Period.Days != "" {
-		if v, err := strconv.Atoi(payload.Inspection.Period.Days); err == nil {
-			daysValue = &v
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
 		}
+	}()
+
+	id, err := s.insertInspectionTx(ctx, tx, payload, createdBy)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return inspectionResponse{}, err
 	}
+	return s.fetchInspectionByID(ctx, int(id))
+}
+
+// insertInspectionTx writes a new act with all its parts inside tx and returns
+// its ID. The caller owns the transaction.
+func (s *server) insertInspectionTx(ctx context.Context, tx *sql.Tx, payload actPayload, createdBy string) (int64, error) {
+	letterNumber := buildLetterNumber(payload.Inspection.Letter)
+	daysValue := inspectionDuration(payload.Inspection.Period)
 
 	addressesJSON, err := marshalStringSlice(buildAddresses(payload))
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
+	}
+	rawAddressesJSON, err := buildRawAddresses(payload, s.config.AddressKeepRaw)
+	if err != nil {
+		return 0, err
 	}
 	inspectorsJSON, err := marshalStringSlice(payload.Inspection.Inspectors)
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
 	}
 	signaturesJSON, err := marshalStringSlice(payload.Inspection.Signatures)
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
 	}
 	representatives := buildRepresentatives(payload)
 	representativesJSON, err := marshalStringSlice(representatives)
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
 	}
 	representativeDoc := firstOrEmpty(representatives)
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	organizationID, err := upsertOrganization(ctx, tx, payload.Organization)
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
 	}
-	defer func() {
+	head := payload.Head
+	if organizationID.Valid && headIsBlank(head) {
+		head, err = registryLeaderOn(ctx, tx, organizationID.Int64, payload.Inspection.Period.StartDate)
 		if err != nil {
-			_ = tx.Rollback()
+			return 0, err
 		}
-	}()
+	}
 
 	result, err := tx.ExecContext(ctx, `
 		INSERT INTO act (created_by, updated_by)
 		VALUES (?, ?)
 	`, createdBy, createdBy)
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
 	}
 
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO act_organization (
 			act_id,
+			organization_id,
 			organization_full_name,
 			organization_short_name,
 			organization_ogrn,
 			organization_legal_address,
 			organization_postal_address
-		) VALUES (?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
 	`,
 		id,
+		organizationID,
 		payload.Organization.Name,
 		payload.Organization.ShortName,
 		payload.Organization.Ogrn,
@@ -212,7 +395,7 @@ func (s *server) insertInspection(ctx context.Context, payload actPayload, creat
 		payload.Organization.Address.PostalAddress,
 	)
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
 	}
 
 	_, err = tx.ExecContext(ctx, `
@@ -225,13 +408,13 @@ func (s *server) insertInspection(ctx context.Context, payload actPayload, creat
 		) VALUES (?, ?, ?, ?, ?)
 	`,
 		id,
-		payload.Head.Role,
-		payload.Head.LastName,
-		payload.Head.NamePatronymic,
-		payload.Head.LastNameTo,
+		head.Role,
+		head.LastName,
+		head.NamePatronymic,
+		head.LastNameTo,
 	)
 	if err != nil {
-		return inspectionResponse{}, err
+		return 0, err
 	}
 
 	_, err = tx.ExecContext(ctx, `
@@ -240,40 +423,84 @@ func (s *server) insertInspection(ctx context.Context, payload actPayload, creat
 			inspection_type,
 			minzdrav_order_number,
 			minzdrav_order_date,
+			minzdrav_order_name,
+			chomiaz_order_number,
+			chomiaz_order_date,
 			inspection_number,
 			date_start,
 			date_end,
+			date_early_end,
 			duration_work_days,
 			letter_number,
 			letter_date,
 			representative_document,
 			addresses,
+			addresses_raw,
 			authorized_persons,
 			signatories,
 			representatives
 		) VALUES (
-			?, ?, ?, ?, ?,
-			?, ?, ?,
+			?, ?, ?, ?, ?, ?, ?, ?,
+			?, ?, ?, ?,
 			?, ?, ?,
-			?, ?, ?, ?
+			?, ?, ?, ?, ?
 		)
 	`,
 		id,
 		payload.Inspection.FormType,
 		payload.Inspection.MzOrder.Number,
 		parseDate(payload.Inspection.MzOrder.Date),
+		payload.Inspection.MzOrder.Name,
+		payload.Inspection.ChomiazOrder.Number,
+		parseDate(payload.Inspection.ChomiazOrder.Date),
 		payload.Inspection.Number,
 		parseDate(payload.Inspection.Period.StartDate),
 		parseDate(payload.Inspection.Period.EndDate),
+		parseDate(payload.Inspection.Period.EarlyEndDate),
 		daysValue,
 		letterNumber,
 		parseDate(payload.Inspection.Letter.Date),
 		representativeDoc,
 		addressesJSON,
+		rawAddressesJSON,
 		inspectorsJSON,
 		signaturesJSON,
 		representativesJSON,
 	)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := refreshActSearch(ctx, tx, id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// cloneInspection creates a draft from an existing inspection and links it to
+// the source through act.source_act_id.
+func (s *server) cloneInspection(ctx context.Context, sourceID int, formType string, createdBy string) (inspectionResponse, error) {
+	source, err := s.fetchInspectionByID(ctx, sourceID)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+	payload := clonePayload(source, formType)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	id, err := s.insertInspectionTx(ctx, tx, payload, createdBy)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE act SET source_act_id=? WHERE id=?", sourceID, id)
 	if err != nil {
 		return inspectionResponse{}, err
 	}
@@ -286,17 +513,16 @@ func (s *server) insertInspection(ctx context.Context, payload actPayload, creat
 
 func (s *server) updateInspection(ctx context.Context, id int, payload actPayload, updatedBy string) (inspectionResponse, error) {
 	letterNumber := buildLetterNumber(payload.Inspection.Letter)
-	var daysValue *int
-	if payload.Inspection.Period.Days != "" {
-		if v, err := strconv.Atoi(payload.Inspection.Period.Days); err == nil {
-			daysValue = &v
-		}
-	}
+	daysValue := inspectionDuration(payload.Inspection.Period)
 
 	addressesJSON, err := marshalStringSlice(buildAddresses(payload))
 	if err != nil {
 		return inspectionResponse{}, err
 	}
+	rawAddressesJSON, err := buildRawAddresses(payload, s.config.AddressKeepRaw)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
 	inspectorsJSON, err := marshalStringSlice(payload.Inspection.Inspectors)
 	if err != nil {
 		return inspectionResponse{}, err
@@ -322,6 +548,11 @@ func (s *server) updateInspection(ctx context.Context, id int, payload actPayloa
 		}
 	}()
 
+	organizationID, err := upsertOrganization(ctx, tx, payload.Organization)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+
 	res, err := tx.ExecContext(ctx, `
 		UPDATE act
 		SET updated_by=?
@@ -345,13 +576,15 @@ func (s *server) updateInspection(ctx context.Context, id int, payload actPayloa
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO act_organization (
 			act_id,
+			organization_id,
 			organization_full_name,
 			organization_short_name,
 			organization_ogrn,
 			organization_legal_address,
 			organization_postal_address
-		) VALUES (?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
+			organization_id=VALUES(organization_id),
 			organization_full_name=VALUES(organization_full_name),
 			organization_short_name=VALUES(organization_short_name),
 			organization_ogrn=VALUES(organization_ogrn),
@@ -359,6 +592,7 @@ func (s *server) updateInspection(ctx context.Context, id int, payload actPayloa
 			organization_postal_address=VALUES(organization_postal_address)
 	`,
 		id,
+		organizationID,
 		payload.Organization.Name,
 		payload.Organization.ShortName,
 		payload.Organization.Ogrn,
@@ -399,35 +633,45 @@ func (s *server) updateInspection(ctx context.Context, id int, payload actPayloa
 			inspection_type,
 			minzdrav_order_number,
 			minzdrav_order_date,
+			minzdrav_order_name,
+			chomiaz_order_number,
+			chomiaz_order_date,
 			inspection_number,
 			date_start,
 			date_end,
+			date_early_end,
 			duration_work_days,
 			letter_number,
 			letter_date,
 			representative_document,
 			addresses,
+			addresses_raw,
 			authorized_persons,
 			signatories,
 			representatives
 		) VALUES (
-			?, ?, ?, ?, ?,
-			?, ?, ?,
+			?, ?, ?, ?, ?, ?, ?, ?,
+			?, ?, ?, ?,
 			?, ?, ?,
-			?, ?, ?, ?
+			?, ?, ?, ?, ?
 		)
 		ON DUPLICATE KEY UPDATE
 			inspection_type=VALUES(inspection_type),
 			minzdrav_order_number=VALUES(minzdrav_order_number),
 			minzdrav_order_date=VALUES(minzdrav_order_date),
+			minzdrav_order_name=VALUES(minzdrav_order_name),
+			chomiaz_order_number=VALUES(chomiaz_order_number),
+			chomiaz_order_date=VALUES(chomiaz_order_date),
 			inspection_number=VALUES(inspection_number),
 			date_start=VALUES(date_start),
 			date_end=VALUES(date_end),
+			date_early_end=VALUES(date_early_end),
 			duration_work_days=VALUES(duration_work_days),
 			letter_number=VALUES(letter_number),
 			letter_date=VALUES(letter_date),
 			representative_document=VALUES(representative_document),
 			addresses=VALUES(addresses),
+			addresses_raw=VALUES(addresses_raw),
 			authorized_persons=VALUES(authorized_persons),
 			signatories=VALUES(signatories),
 			representatives=VALUES(representatives)
@@ -436,14 +680,19 @@ func (s *server) updateInspection(ctx context.Context, id int, payload actPayloa
 		payload.Inspection.FormType,
 		payload.Inspection.MzOrder.Number,
 		parseDate(payload.Inspection.MzOrder.Date),
+		payload.Inspection.MzOrder.Name,
+		payload.Inspection.ChomiazOrder.Number,
+		parseDate(payload.Inspection.ChomiazOrder.Date),
 		payload.Inspection.Number,
 		parseDate(payload.Inspection.Period.StartDate),
 		parseDate(payload.Inspection.Period.EndDate),
+		parseDate(payload.Inspection.Period.EarlyEndDate),
 		daysValue,
 		letterNumber,
 		parseDate(payload.Inspection.Letter.Date),
 		representativeDoc,
 		addressesJSON,
+		rawAddressesJSON,
 		inspectorsJSON,
 		signaturesJSON,
 		representativesJSON,
@@ -452,29 +701,50 @@ func (s *server) updateInspection(ctx context.Context, id int, payload actPayloa
 		return inspectionResponse{}, err
 	}
 
+	if err = refreshActSearch(ctx, tx, int64(id)); err != nil {
+		return inspectionResponse{}, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return inspectionResponse{}, err
 	}
 	return s.fetchInspectionByID(ctx, id)
 }
 
-func scanInspectionRow(scanner interface {
+type rowScanner interface {
 	Scan(dest ...any) error
-}) (inspectionResponse, error) {
+}
+
+// extraColumnsScanner lets scanInspectionRow read rows that carry additional
+// trailing columns; those are scanned into extra.
+type extraColumnsScanner struct {
+	scanner rowScanner
+	extra   []any
+}
+
+func (e extraColumnsScanner) Scan(dest ...any) error {
+	return e.scanner.Scan(append(dest, e.extra...)...)
+}
+
+func scanInspectionRow(scanner rowScanner) (inspectionResponse, error) {
 	var (
 		item                inspectionResponse
 		mzOrderDate         sql.NullTime
+		chomiazOrderDate    sql.NullTime
 		startDate           sql.NullTime
 		endDate             sql.NullTime
+		earlyEndDate        sql.NullTime
 		letterDate          sql.NullTime
 		representativeDoc   sql.NullString
 		addressesJSON       []byte
+		rawAddressesJSON    []byte
 		inspectorsJSON      []byte
 		signaturesJSON      []byte
 		representativesJSON []byte
 		createdAt           time.Time
 		updatedAt           time.Time
 		duration            sql.NullInt64
+		sourceID            sql.NullInt64
 	)
 
 	if err := scanner.Scan(
@@ -491,14 +761,19 @@ func scanInspectionRow(scanner interface {
 		&item.Inspection.FormType,
 		&item.Inspection.MzOrder.Number,
 		&mzOrderDate,
+		&item.Inspection.MzOrder.Name,
+		&item.Inspection.ChomiazOrder.Number,
+		&chomiazOrderDate,
 		&item.Inspection.Number,
 		&startDate,
 		&endDate,
+		&earlyEndDate,
 		&duration,
 		&item.Inspection.Letter.NumberLeft,
 		&letterDate,
 		&representativeDoc,
 		&addressesJSON,
+		&rawAddressesJSON,
 		&inspectorsJSON,
 		&signaturesJSON,
 		&representativesJSON,
@@ -506,13 +781,17 @@ func scanInspectionRow(scanner interface {
 		&item.UpdatedBy,
 		&createdAt,
 		&updatedAt,
+		&sourceID,
+		&item.Status,
 	); err != nil {
 		return inspectionResponse{}, err
 	}
 
 	item.Inspection.MzOrder.Date = formatDate(mzOrderDate)
+	item.Inspection.ChomiazOrder.Date = formatDate(chomiazOrderDate)
 	item.Inspection.Period.StartDate = formatDate(startDate)
 	item.Inspection.Period.EndDate = formatDate(endDate)
+	item.Inspection.Period.EarlyEndDate = formatDate(earlyEndDate)
 	item.Inspection.Period.Days = formatDays(duration)
 	item.Inspection.Letter.Date = formatDate(letterDate)
 	item.Inspection.Letter.NumberLeft, item.Inspection.Letter.NumberRight = splitLetterNumber(item.Inspection.Letter.NumberLeft)
@@ -535,6 +814,11 @@ func scanInspectionRow(scanner interface {
 	}
 
 	item.Inspection.AddressNoIndex = addresses
+	if rawAddressesJSON != nil {
+		if item.Inspection.AddressNoIndexRaw, err = unmarshalStringSlice(rawAddressesJSON); err != nil {
+			return inspectionResponse{}, err
+		}
+	}
 	item.Inspection.Inspectors = inspectors
 	item.Inspection.Signatures = signatures
 	if len(representatives) > 0 {
@@ -543,8 +827,28 @@ func scanInspectionRow(scanner interface {
 		item.Inspection.Representative = []string{representativeDoc.String}
 	}
 
+	if sourceID.Valid {
+		id := int(sourceID.Int64)
+		item.SourceID = &id
+	}
 	item.CreatedAt = createdAt.Format(time.RFC3339)
 	item.UpdatedAt = updatedAt.Format(time.RFC3339)
 
 	return item, nil
 }
+
+// recountInspectionDurations stores working days for acts saved before they
+// were counted: the form filled in calendar days from the start to the
+// planned end. Values typed over that, e.g. without public holidays, are
+// kept. It runs once, as a migration.
+func (s *server) recountInspectionDurations(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE act_inspection insp
+		SET insp.duration_work_days = `+statsWorkingDaysExpr+`
+		WHERE insp.date_start IS NOT NULL
+			AND COALESCE(insp.date_early_end, insp.date_end) >= insp.date_start
+			AND (insp.duration_work_days IS NULL
+				OR insp.duration_work_days = DATEDIFF(insp.date_end, insp.date_start) + 1)
+	`)
+	return err
+}
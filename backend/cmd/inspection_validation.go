@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+)
+
+// validateInspectionPayload checks an inspection before it reaches the
+// database. It returns validation.Errors listing every invalid field.
+func validateInspectionPayload(payload actPayload) error {
+	v := validation.New()
+
+	ogrn := strings.TrimSpace(payload.Organization.Ogrn)
+	if v.Required("organization.ogrn", ogrn, "Укажите ОГРН или ОГРНИП") && !validation.OGRN(ogrn) {
+		v.Add("organization.ogrn", validation.CodeChecksum, "Некорректный ОГРН (13 цифр) или ОГРНИП (15 цифр)")
+	}
+	v.Required("organization.name", payload.Organization.Name, "Укажите наименование организации")
+	v.Required("organization.address.legalAddress", payload.Organization.Address.LegalAddress, "Укажите юридический адрес")
+
+	formType := payload.Inspection.FormType
+	if v.Required("inspection.formType", formType, "Укажите форму проверки") {
+		v.OneOf("inspection.formType", formType, inspectionFormTypes, "Неизвестная форма проверки")
+	}
+	switch formType {
+	case domain.InspectionPlannedDocumentary, domain.InspectionPlannedOnsite:
+		v.Required("inspection.mzOrder.number", payload.Inspection.MzOrder.Number, "Для плановой проверки укажите номер приказа")
+	}
+	switch formType {
+	case domain.InspectionPlannedOnsite, domain.InspectionUnplannedOnsite:
+		if len(buildAddresses(payload)) == 0 {
+			v.Add("inspection.addressNoIndex", validation.CodeRequired, "Для выездной проверки укажите адрес проведения")
+		}
+	}
+
+	orderDate := v.Date("inspection.mzOrder.date", payload.Inspection.MzOrder.Date)
+	start := v.Date("inspection.period.startDate", payload.Inspection.Period.StartDate)
+	end := v.Date("inspection.period.endDate", payload.Inspection.Period.EndDate)
+	earlyEnd := v.Date("inspection.period.earlyEndDate", payload.Inspection.Period.EarlyEndDate)
+	v.Date("inspection.chomiazOrder.date", payload.Inspection.ChomiazOrder.Date)
+	v.Date("inspection.letter.date", payload.Inspection.Letter.Date)
+
+	v.NotBefore("inspection.period.endDate", end, start, "Дата окончания раньше даты начала")
+	v.NotBefore("inspection.period.startDate", start, orderDate, "Проверка начинается раньше даты приказа")
+	if earlyEnd != nil && start == nil && !v.HasError("inspection.period.startDate") {
+		v.Add("inspection.period.startDate", validation.CodeRequired, "Для досрочного окончания укажите дату начала")
+	}
+	v.NotBefore("inspection.period.earlyEndDate", earlyEnd, start, "Досрочное окончание раньше даты начала")
+	v.NotAfter("inspection.period.earlyEndDate", earlyEnd, end, "Досрочное окончание позже даты окончания")
+
+	if days := strings.TrimSpace(payload.Inspection.Period.Days); days != "" {
+		if value, err := strconv.Atoi(days); err != nil || value < 0 {
+			v.Add("inspection.period.days", validation.CodeInvalidFormat, "Количество рабочих дней должно быть целым неотрицательным числом")
+		}
+	}
+
+	return v.Err()
+}
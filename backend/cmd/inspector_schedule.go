@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
+)
+
+const (
+	inspectorOverlapWarn   = "warn"
+	inspectorOverlapReject = "reject"
+)
+
+// maxCalendarDays limits the range of the inspector calendar.
+const maxCalendarDays = 366
+
+// scheduledInspection is an inspection placed on the calendar. EndDate is the
+// early end when there is one, since the inspector is free after it.
+type scheduledInspection struct {
+	ID           int    `json:"id"`
+	Number       string `json:"number"`
+	FormType     string `json:"form_type"`
+	Organization string `json:"organization"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	Status       string `json:"status"`
+	Overlaps     bool   `json:"overlaps"`
+
+	start      time.Time
+	end        time.Time
+	inspectors []string
+}
+
+// inspectorConflict reports that Inspector is already busy on an overlapping
+// on-site inspection.
+type inspectorConflict struct {
+	Field      string              `json:"field"`
+	Inspector  string              `json:"inspector"`
+	Message    string              `json:"message"`
+	Inspection scheduledInspection `json:"inspection"`
+}
+
+type inspectorCalendar struct {
+	EmployeeID  int                   `json:"employee_id"`
+	Name        string                `json:"name"`
+	BusyDays    int                   `json:"busy_days"`
+	Inspections []scheduledInspection `json:"inspections"`
+}
+
+type calendarResponse struct {
+	From      string              `json:"from"`
+	To        string              `json:"to"`
+	Employees []inspectorCalendar `json:"employees"`
+}
+
+func isOnsiteForm(formType string) bool {
+	return formType == domain.InspectionPlannedOnsite || formType == domain.InspectionUnplannedOnsite
+}
+
+// scheduledInspections returns the inspections with a start date whose period
+// intersects [from, to]. An inspection without an end date occupies its start
+// day only.
+func (s *server) scheduledInspections(ctx context.Context, from, to time.Time, onsiteOnly bool, excludeID int) ([]scheduledInspection, error) {
+	query := `
+		SELECT
+			act.id,
+			COALESCE(insp.inspection_number, ''),
+			insp.inspection_type,
+			COALESCE(NULLIF(org.organization_short_name, ''), org.organization_full_name),
+			insp.date_start,
+			COALESCE(insp.date_early_end, insp.date_end, insp.date_start),
+			insp.authorized_persons,
+			` + inspectionStatusExpr + `
+		` + inspectionFromClause + `
+		WHERE insp.date_start IS NOT NULL
+			AND insp.date_start <= ?
+			AND COALESCE(insp.date_early_end, insp.date_end, insp.date_start) >= ?
+			AND act.id <> ?`
+	args := []any{to, from, excludeID}
+	if onsiteOnly {
+		query += " AND insp.inspection_type IN (?, ?)"
+		args = append(args, domain.InspectionPlannedOnsite, domain.InspectionUnplannedOnsite)
+	}
+	query += " ORDER BY insp.date_start, act.id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []scheduledInspection
+	for rows.Next() {
+		var (
+			item           scheduledInspection
+			inspectorsJSON []byte
+		)
+		if err := rows.Scan(
+			&item.ID,
+			&item.Number,
+			&item.FormType,
+			&item.Organization,
+			&item.start,
+			&item.end,
+			&inspectorsJSON,
+			&item.Status,
+		); err != nil {
+			return nil, err
+		}
+		if item.inspectors, err = unmarshalStringSlice(inspectorsJSON); err != nil {
+			return nil, err
+		}
+		item.StartDate = item.start.Format(dateLayout)
+		item.EndDate = item.end.Format(dateLayout)
+		list = append(list, item)
+	}
+	return list, rows.Err()
+}
+
+// findInspectorConflicts lists the other on-site inspections that share an
+// inspector with payload and overlap its period. Documentary inspections and
+// inspections without a start date never conflict.
+func (s *server) findInspectorConflicts(ctx context.Context, payload actPayload, excludeID int) ([]inspectorConflict, error) {
+	if !isOnsiteForm(payload.Inspection.FormType) || len(payload.Inspection.Inspectors) == 0 {
+		return nil, nil
+	}
+	start := parseDate(payload.Inspection.Period.StartDate)
+	if start == nil {
+		return nil, nil
+	}
+	end := start
+	if value := parseDate(payload.Inspection.Period.EndDate); value != nil {
+		end = value
+	}
+	if value := parseDate(payload.Inspection.Period.EarlyEndDate); value != nil {
+		end = value
+	}
+
+	others, err := s.scheduledInspections(ctx, *start, *end, true, excludeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []inspectorConflict
+	for _, inspector := range payload.Inspection.Inspectors {
+		name := normalizeEmployeeName(inspector)
+		if name == "" {
+			continue
+		}
+		for _, other := range others {
+			if !hasInspector(other.inspectors, name) {
+				continue
+			}
+			conflicts = append(conflicts, inspectorConflict{
+				Field:      "inspection.inspectors",
+				Inspector:  inspector,
+				Message:    fmt.Sprintf("%s уже участвует в выездной проверке %s (%s) с %s по %s", inspector, formatValue(other.Number), other.Organization, other.StartDate, other.EndDate),
+				Inspection: other,
+			})
+		}
+	}
+	return conflicts, nil
+}
+
+// inspectorCalendars groups the inspections of [from, to] by employee.
+// Inspectors are matched to employees by full name. Overlaps marks on-site
+// inspections that intersect another on-site inspection of the same employee.
+func (s *server) inspectorCalendars(ctx context.Context, from, to time.Time, employeeID int) ([]inspectorCalendar, error) {
+	employees, err := s.loadEmployeeNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inspections, err := s.scheduledInspections(ctx, from, to, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendars []inspectorCalendar
+	for id, name := range employees {
+		if employeeID != 0 && id != employeeID {
+			continue
+		}
+		calendar := inspectorCalendar{EmployeeID: id, Name: name, Inspections: []scheduledInspection{}}
+		normalized := normalizeEmployeeName(name)
+		for _, item := range inspections {
+			if hasInspector(item.inspectors, normalized) {
+				calendar.Inspections = append(calendar.Inspections, item)
+			}
+		}
+		markOverlaps(calendar.Inspections)
+		calendar.BusyDays = busyWorkingDays(calendar.Inspections, from, to)
+		calendars = append(calendars, calendar)
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		return strings.ToLower(calendars[i].Name) < strings.ToLower(calendars[j].Name)
+	})
+	return calendars, nil
+}
+
+func hasInspector(inspectors []string, normalizedName string) bool {
+	for _, inspector := range inspectors {
+		if normalizeEmployeeName(inspector) == normalizedName {
+			return true
+		}
+	}
+	return false
+}
+
+func markOverlaps(items []scheduledInspection) {
+	for i := range items {
+		if !isOnsiteForm(items[i].FormType) {
+			continue
+		}
+		for j := i + 1; j < len(items); j++ {
+			if !isOnsiteForm(items[j].FormType) {
+				continue
+			}
+			if !items[i].start.After(items[j].end) && !items[j].start.After(items[i].end) {
+				items[i].Overlaps = true
+				items[j].Overlaps = true
+			}
+		}
+	}
+}
+
+// busyWorkingDays counts the working days of [from, to] covered by at least
+// one inspection.
+func busyWorkingDays(items []scheduledInspection, from, to time.Time) int {
+	busy := make(map[string]bool)
+	for _, item := range items {
+		start, end := item.start, item.end
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if isWorkingDay(day) {
+				busy[day.Format(dateLayout)] = true
+			}
+		}
+	}
+	return len(busy)
+}
+
+// parseCalendarRange reads from/to (YYYY-MM-DD); by default the current month
+// is shown.
+func parseCalendarRange(fromRaw, toRaw string, now time.Time) (time.Time, time.Time, error) {
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	if value := strings.TrimSpace(fromRaw); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = parsed
+	}
+	if value := strings.TrimSpace(toRaw); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = parsed
+	}
+	return from, to, nil
+}
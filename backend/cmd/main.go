@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/repository/psql"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/service"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/transport/rest"
 	"github.com/gorilla/mux"
 )
 
@@ -28,7 +32,23 @@ func main() {
 		log.Fatal(err)
 	}
 
-	srv := newServer(db)
+	config := loadServerConfig()
+	srv := newServer(db, config)
+	if srv.deadlineRules, err = loadDeadlineRules(config.DeadlineRulesFile); err != nil {
+		log.Fatal(err)
+	}
+	if err := srv.runMigrations(context.Background()); err != nil {
+		log.Fatal("migration error: ", err)
+	}
+	if err := srv.backfillSearchIndex(context.Background()); err != nil {
+		log.Println("search index backfill error:", err)
+	}
+	if err := srv.backfillOrganizations(context.Background()); err != nil {
+		log.Println("organization registry backfill error:", err)
+	}
+	go srv.runReminderScheduler(context.Background())
+	go srv.runIdempotencyCleanup(context.Background())
+	go srv.runOrganizationRefresh(context.Background())
 	router := setupRouter(srv)
 
 	server := &http.Server{
@@ -57,19 +77,60 @@ func setupRouter(srv *server) http.Handler {
 	api.HandleFunc("/users", srv.withAuth(srv.requireAdmin(srv.handleUserCreate))).Methods(http.MethodPost)
 	api.HandleFunc("/users/{id:[0-9]+}", srv.withAuth(srv.requireAdmin(srv.handleUserDelete))).Methods(http.MethodDelete)
 	api.HandleFunc("/users/{id:[0-9]+}/role", srv.withAuth(srv.requireAdmin(srv.handleUserRoleUpdate))).Methods(http.MethodPatch)
+	api.HandleFunc("/users/{id:[0-9]+}/email", srv.withAuth(srv.requireAdmin(srv.handleUserEmailUpdate))).Methods(http.MethodPatch)
 	api.HandleFunc("/employees", srv.withAuth(srv.requireAdmin(srv.handleEmployeesList))).Methods(http.MethodGet)
 	api.HandleFunc("/employees", srv.withAuth(srv.requireAdmin(srv.handleEmployeeCreate))).Methods(http.MethodPost)
 	api.HandleFunc("/employees/{id:[0-9]+}", srv.withAuth(srv.requireAdmin(srv.handleEmployeeDelete))).Methods(http.MethodDelete)
 
 	api.HandleFunc("/inspections", srv.withAuth(srv.handleInspectionsList)).Methods(http.MethodGet)
-	api.HandleFunc("/inspections", srv.withAuth(srv.handleInspectionCreate)).Methods(http.MethodPost)
+	api.HandleFunc("/inspections", srv.idempotent(srv.withAuth(srv.handleInspectionCreate))).Methods(http.MethodPost)
+	api.HandleFunc("/inspections/export", srv.withAuth(srv.handleInspectionsExport)).Methods(http.MethodGet)
+	api.HandleFunc("/inspections/import", srv.withAuth(srv.handleInspectionsImport)).Methods(http.MethodPost)
+	api.HandleFunc("/inspections/import/reports/{id:[0-9a-f]+}", srv.withAuth(srv.handleImportReport)).Methods(http.MethodGet)
 	api.HandleFunc("/inspections/{id:[0-9]+}", srv.withAuth(srv.handleInspectionGet)).Methods(http.MethodGet)
 	api.HandleFunc("/inspections/{id:[0-9]+}", srv.withAuth(srv.handleInspectionUpdate)).Methods(http.MethodPut)
 	api.HandleFunc("/inspections/{id:[0-9]+}", srv.withAuth(srv.handleInspectionDelete)).Methods(http.MethodDelete)
+	api.HandleFunc("/inspections/{id:[0-9]+}/clone", srv.withAuth(srv.handleInspectionClone)).Methods(http.MethodPost)
 	api.HandleFunc("/inspections/{id:[0-9]+}/export/docx", srv.withAuth(srv.handleInspectionDocx)).Methods(http.MethodGet)
+	api.HandleFunc("/inspections/{id:[0-9]+}/documents/sends", srv.withAuth(srv.handleDocumentSends)).Methods(http.MethodGet)
+	api.HandleFunc("/inspections/{id:[0-9]+}/documents/{kind}/send", srv.withAuth(srv.handleDocumentSend)).Methods(http.MethodPost)
+	api.HandleFunc("/inspections/{id:[0-9]+}/findings", srv.withAuth(srv.handleFindingsGet)).Methods(http.MethodGet)
+	api.HandleFunc("/inspections/{id:[0-9]+}/findings", srv.withAuth(srv.handleFindingsUpdate)).Methods(http.MethodPut)
+	api.HandleFunc("/organizations/duplicates", srv.withAuth(srv.requireAdmin(srv.handleOrganizationDuplicates))).Methods(http.MethodGet)
+	api.HandleFunc("/organizations/merge", srv.withAuth(srv.requireAdmin(srv.handleOrganizationMerge))).Methods(http.MethodPost)
+	api.HandleFunc("/organizations/merges", srv.withAuth(srv.requireAdmin(srv.handleOrganizationMerges))).Methods(http.MethodGet)
+	api.HandleFunc("/organizations/merges/{id:[0-9]+}/undo", srv.withAuth(srv.requireAdmin(srv.handleOrganizationMergeUndo))).Methods(http.MethodPost)
+	api.HandleFunc("/organizations/refresh", srv.withAuth(srv.requireAdmin(srv.handleOrganizationRefresh))).Methods(http.MethodPost)
+	api.HandleFunc("/organizations/changes", srv.withAuth(srv.requireAdmin(srv.handleOrganizationChanges))).Methods(http.MethodGet)
+	api.HandleFunc("/organizations/changes/{id:[0-9]+}/{decision:accept|reject}", srv.withAuth(srv.requireAdmin(srv.handleOrganizationChangeReview))).Methods(http.MethodPost)
+	api.HandleFunc("/organizations/{ogrn:[0-9]{13,15}}/history", srv.withAuth(srv.handleOrganizationHistory)).Methods(http.MethodGet)
+	api.HandleFunc("/calendar/inspectors", srv.withAuth(srv.handleInspectorCalendar)).Methods(http.MethodGet)
+	api.HandleFunc("/calendar/feed", srv.withAuth(srv.handleCalendarFeedGet)).Methods(http.MethodGet)
+	api.HandleFunc("/calendar/feed/reset", srv.withAuth(srv.handleCalendarFeedReset)).Methods(http.MethodPost)
+	api.HandleFunc("/calendar/feed/{token:[0-9a-f]+}.ics", srv.handleCalendarFeed).Methods(http.MethodGet)
+	api.HandleFunc("/reports/annual", srv.withAuth(srv.handleAnnualReport)).Methods(http.MethodGet)
+	api.HandleFunc("/stats", srv.withAuth(srv.handleStats)).Methods(http.MethodGet)
+	api.HandleFunc("/stats/{group}", srv.withAuth(srv.handleStatsGroup)).Methods(http.MethodGet)
+	api.HandleFunc("/reminders", srv.withAuth(srv.handleRemindersList)).Methods(http.MethodGet)
+	api.HandleFunc("/reminders/{id:[0-9]+}/{rule}/complete", srv.withAuth(srv.handleReminderComplete)).Methods(http.MethodPost, http.MethodDelete)
+	api.HandleFunc("/plans", srv.withAuth(srv.handlePlansList)).Methods(http.MethodGet)
+	api.HandleFunc("/plans", srv.withAuth(srv.requireAdmin(srv.handlePlanCreate))).Methods(http.MethodPost)
+	api.HandleFunc("/plans/{id:[0-9]+}", srv.withAuth(srv.handlePlanGet)).Methods(http.MethodGet)
+	api.HandleFunc("/plans/{id:[0-9]+}", srv.withAuth(srv.requireAdmin(srv.handlePlanUpdate))).Methods(http.MethodPut)
+	api.HandleFunc("/plans/{id:[0-9]+}", srv.withAuth(srv.requireAdmin(srv.handlePlanDelete))).Methods(http.MethodDelete)
+	api.HandleFunc("/plans/{id:[0-9]+}/import", srv.withAuth(srv.requireAdmin(srv.handlePlanImport))).Methods(http.MethodPost)
+	api.HandleFunc("/plans/{id:[0-9]+}/items", srv.withAuth(srv.requireAdmin(srv.handlePlanItemCreate))).Methods(http.MethodPost)
+	api.HandleFunc("/plans/{id:[0-9]+}/items/{itemId:[0-9]+}", srv.withAuth(srv.requireAdmin(srv.handlePlanItemUpdate))).Methods(http.MethodPut)
+	api.HandleFunc("/plans/{id:[0-9]+}/items/{itemId:[0-9]+}", srv.withAuth(srv.requireAdmin(srv.handlePlanItemDelete))).Methods(http.MethodDelete)
+	api.HandleFunc("/plans/{id:[0-9]+}/items/{itemId:[0-9]+}/inspection", srv.withAuth(srv.handlePlanItemInspection)).Methods(http.MethodPost)
 	api.HandleFunc("/verification-areas", srv.withAuth(srv.handleVerificationAreasGet)).Methods(http.MethodGet)
 	api.HandleFunc("/verification-areas", srv.withAuth(srv.handleVerificationAreasUpsert)).Methods(http.MethodPut)
 	api.HandleFunc("/dadata/organization", srv.withAuth(srv.handleDadataOrganization)).Methods(http.MethodPost)
+	api.HandleFunc("/dadata/organizations/suggest", srv.withAuth(srv.handleDadataOrganizationSuggest)).Methods(http.MethodPost)
+	api.HandleFunc("/dadata/addresses/suggest", srv.withAuth(srv.handleDadataAddressSuggest)).Methods(http.MethodPost)
+
+	docs := rest.NewDocs(service.NewDocs(psql.NewDocs(srv.db)))
+	docs.Register(api, srv.authenticated, srv.adminOnly, srv.idempotent)
 
 	return router
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// migration changes the schema or the data of an existing database once.
+// Applied migrations are recorded by name in schema_migration, so a name must
+// never change.
+type migration struct {
+	Name  string
+	Apply func(s *server, ctx context.Context) error
+}
+
+// migrations run in order at startup, each once per database.
+var migrations = []migration{
+	{"0001_inspection_duration_working_days", (*server).recountInspectionDurations},
+	{"0002_act_clone_source", migrateActCloneSource},
+	{"0003_organization_registry", migrateOrganizationRegistry},
+	{"0004_act_search", migrateActSearch},
+	{"0005_inspection_addresses_raw", migrateInspectionAddressesRaw},
+	{"0006_findings_and_document_sends", migrateFindingsAndDocumentSends},
+	{"0007_user_calendar_and_email", migrateUserCalendarAndEmail},
+	{"0008_deadlines", migrateDeadlines},
+	{"0009_inspection_plan", migrateInspectionPlan},
+	{"0010_dadata_cache", migrateDadataCache},
+	{"0011_idempotency_keys", migrateIdempotencyKeys},
+}
+
+// runMigrations applies the migrations not recorded yet and stops at the
+// first failing one; it runs again on the next start.
+func (s *server) runMigrations(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migration (
+			name VARCHAR(255) PRIMARY KEY,
+			applied_at DATETIME NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM schema_migration")
+	if err != nil {
+		return err
+	}
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range migrations {
+		if applied[item.Name] {
+			continue
+		}
+		if err := item.Apply(s, ctx); err != nil {
+			return fmt.Errorf("migration %s: %w", item.Name, err)
+		}
+		if _, err := s.db.ExecContext(ctx, `
+			INSERT INTO schema_migration (name, applied_at) VALUES (?, NOW())
+		`, item.Name); err != nil {
+			return err
+		}
+		log.Println("migration applied:", item.Name)
+	}
+	return nil
+}
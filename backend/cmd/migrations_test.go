@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRunMigrationsSkipsApplied(t *testing.T) {
+	db, fake := newFakeDB(t, fakeResult{
+		match:   "FROM schema_migration",
+		columns: []string{"name"},
+		rows:    [][]driver.Value{{"0001_first"}},
+	})
+	saved := migrations
+	defer func() { migrations = saved }()
+	var ran []string
+	migrations = []migration{
+		{"0001_first", func(s *server, ctx context.Context) error { ran = append(ran, "0001_first"); return nil }},
+		{"0002_second", func(s *server, ctx context.Context) error { ran = append(ran, "0002_second"); return nil }},
+	}
+
+	if err := (&server{db: db}).runMigrations(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 1 || ran[0] != "0002_second" {
+		t.Errorf("ran %v, want [0002_second]", ran)
+	}
+	recorded := fake.executed("INSERT INTO schema_migration")
+	if len(recorded) != 1 || recorded[0].args[0] != "0002_second" {
+		t.Errorf("recorded %+v, want 0002_second", recorded)
+	}
+}
+
+func TestAlterIfMissing(t *testing.T) {
+	for _, count := range []int64{0, 1} {
+		db, fake := newFakeDB(t, fakeResult{
+			match:   "FROM information_schema.COLUMNS",
+			columns: []string{"count"},
+			rows:    [][]driver.Value{{count}},
+		})
+		step := addColumn("users", "email", "VARCHAR(255) NULL")
+		if err := step(&server{db: db}, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		checks := fake.queried("information_schema.COLUMNS")
+		if len(checks) != 1 || checks[0].args[0] != "users" || checks[0].args[1] != "email" {
+			t.Errorf("checks = %+v, want users.email", checks)
+		}
+		altered := fake.executed("ALTER TABLE users ADD COLUMN email VARCHAR(255) NULL")
+		if want := 1 - int(count); len(altered) != want {
+			t.Errorf("existing columns %d: %d ALTER statements, want %d", count, len(altered), want)
+		}
+	}
+}
+
+// TestSchemaMigrationsCoverInitSQL checks that every table added to
+// mysql/init.sql after the first schema is created by a migration.
+func TestSchemaMigrationsCoverInitSQL(t *testing.T) {
+	firstSchema := map[string]bool{
+		"act": true, "act_organization": true, "act_head": true, "act_inspection": true,
+		"users": true, "employees": true, "info": true, "field": true, "verification_areas_store": true,
+		"schema_migration": true,
+	}
+	initSQL, err := os.ReadFile(filepath.Join("..", "..", "mysql", "init.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, fake := newFakeDB(t, fakeResult{
+		match:   "FROM information_schema",
+		columns: []string{"count"},
+		rows:    [][]driver.Value{{int64(0)}},
+	})
+	srv := &server{db: db}
+	for _, item := range migrations {
+		if strings.HasPrefix(item.Name, "0001_") {
+			continue
+		}
+		if err := item.Apply(srv, context.Background()); err != nil {
+			t.Fatalf("%s: %v", item.Name, err)
+		}
+	}
+
+	for _, match := range regexp.MustCompile(`(?m)^CREATE TABLE (\w+)`).FindAllStringSubmatch(string(initSQL), -1) {
+		table := match[1]
+		if firstSchema[table] {
+			continue
+		}
+		if len(fake.executed("CREATE TABLE IF NOT EXISTS "+table+" (")) != 1 {
+			t.Errorf("table %s of init.sql is not created by a migration", table)
+		}
+	}
+}
@@ -6,6 +6,7 @@ type user struct {
 	Login    string `json:"login"`
 	Password string `json:"password,omitempty"`
 	Role     string `json:"role"`
+	Email    string `json:"email,omitempty"`
 }
 
 type employee struct {
@@ -42,6 +43,7 @@ type headDTO struct {
 type inspectionDTO struct {
 	FormType       string     `json:"formType"`
 	MzOrder        orderDTO   `json:"mzOrder"`
+	ChomiazOrder   orderDTO   `json:"chomiazOrder"`
 	Number         string     `json:"number"`
 	Period         periodDTO  `json:"period"`
 	Letter         letterDTO  `json:"letter"`
@@ -49,17 +51,23 @@ type inspectionDTO struct {
 	Representative stringList `json:"representative"`
 	Inspectors     []string   `json:"inspectors"`
 	Signatures     []string   `json:"signatures"`
+
+	// AddressNoIndexRaw is the addresses as entered, kept when
+	// ADDRESS_KEEP_RAW is on. It is ignored on input.
+	AddressNoIndexRaw []string `json:"addressNoIndexRaw,omitempty"`
 }
 
 type orderDTO struct {
 	Number string `json:"number"`
 	Date   string `json:"date"`
+	Name   string `json:"name,omitempty"`
 }
 
 type periodDTO struct {
-	StartDate string `json:"startDate"`
-	EndDate   string `json:"endDate"`
-	Days      string `json:"days"`
+	StartDate    string `json:"startDate"`
+	EndDate      string `json:"endDate"`
+	EarlyEndDate string `json:"earlyEndDate"`
+	Days         string `json:"days"`
 }
 
 type letterDTO struct {
@@ -69,14 +77,22 @@ type letterDTO struct {
 }
 
 type inspectionResponse struct {
-	ID           int             `json:"id"`
-	CreatedAt    string          `json:"created_at"`
-	UpdatedAt    string          `json:"updated_at"`
-	CreatedBy    string          `json:"created_by"`
-	UpdatedBy    string          `json:"updated_by"`
-	Organization organizationDTO `json:"organization"`
-	Head         headDTO         `json:"head"`
-	Inspection   inspectionDTO   `json:"inspection"`
+	ID           int                 `json:"id"`
+	CreatedAt    string              `json:"created_at"`
+	UpdatedAt    string              `json:"updated_at"`
+	CreatedBy    string              `json:"created_by"`
+	UpdatedBy    string              `json:"updated_by"`
+	Status       string              `json:"status"`
+	SourceID     *int                `json:"source_id,omitempty"`
+	Organization organizationDTO     `json:"organization"`
+	Head         headDTO             `json:"head"`
+	Inspection   inspectionDTO       `json:"inspection"`
+	Search       *searchMatch        `json:"search,omitempty"`
+	Conflicts    []inspectorConflict `json:"conflicts,omitempty"`
+
+	// LastInspectedOn is the start of the previous inspection of the
+	// organization; filled in list responses only.
+	LastInspectedOn string `json:"last_inspected_on,omitempty"`
 }
 
 type inspectionListResponse struct {
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Reasons why two registry records are suspected to be one organization. An
+// equal OGRN cannot happen: it is unique in the registry.
+const (
+	duplicateSimilarOGRN = "similar_ogrn"
+	duplicateSameName    = "same_name"
+	duplicateSimilarName = "similar_name"
+)
+
+// organizationLegalForms maps spelled-out legal forms to their abbreviations,
+// so that "ГБУЗ" and "Государственное бюджетное учреждение здравоохранения"
+// compare equal. Longer forms go first.
+var organizationLegalForms = []struct{ Full, Short string }{
+	{"государственное бюджетное учреждение здравоохранения", "гбуз"},
+	{"государственное автономное учреждение здравоохранения", "гауз"},
+	{"государственное казенное учреждение здравоохранения", "гкуз"},
+	{"муниципальное бюджетное учреждение здравоохранения", "мбуз"},
+	{"федеральное государственное бюджетное учреждение", "фгбу"},
+	{"государственное бюджетное учреждение", "гбу"},
+	{"муниципальное бюджетное учреждение", "мбу"},
+	{"публичное акционерное общество", "пао"},
+	{"общество с ограниченной ответственностью", "ооо"},
+	{"акционерное общество", "ао"},
+	{"индивидуальный предприниматель", "ип"},
+}
+
+type duplicateOrganization struct {
+	ID        int64  `json:"id"`
+	OGRN      string `json:"ogrn"`
+	FullName  string `json:"full_name"`
+	ShortName string `json:"short_name,omitempty"`
+	Acts      int    `json:"acts"`
+	Leaders   int    `json:"leaders"`
+}
+
+// duplicateGroup is a set of registry records that are likely the same
+// organization; Organizations are ordered by the number of acts, so the
+// first one is the suggested survivor.
+type duplicateGroup struct {
+	Organizations []duplicateOrganization `json:"organizations"`
+	Reasons       []string                `json:"reasons"`
+}
+
+// normalizeOrganizationName reduces a name to lower-case words without
+// punctuation, quotes and spelled-out legal forms.
+func normalizeOrganizationName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+	for _, form := range organizationLegalForms {
+		name = strings.ReplaceAll(name, form.Full, form.Short)
+	}
+	return name
+}
+
+// similarNames allows about one typo per ten letters.
+func similarNames(a, b string) bool {
+	limit := max(len([]rune(a)), len([]rune(b))) / 10
+	if limit < 2 {
+		limit = 2
+	}
+	return levenshtein(a, b) <= limit
+}
+
+// similarOGRNs reports numbers of the same length that differ in one digit
+// or in two swapped neighbouring digits, the usual typing errors.
+func similarOGRNs(a, b string) bool {
+	if len(a) != len(b) || a == b {
+		return false
+	}
+	var diff []int
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			diff = append(diff, i)
+		}
+	}
+	switch len(diff) {
+	case 1:
+		return true
+	case 2:
+		i, j := diff[0], diff[1]
+		return j == i+1 && a[i] == b[j] && a[j] == b[i]
+	}
+	return false
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func onlyDigits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// duplicateReasons compares two registry records; no reasons means they are
+// not suspected duplicates.
+func duplicateReasons(a, b duplicateOrganization) []string {
+	var reasons []string
+	if similarOGRNs(onlyDigits(a.OGRN), onlyDigits(b.OGRN)) {
+		reasons = append(reasons, duplicateSimilarOGRN)
+	}
+
+	nameA, nameB := normalizeOrganizationName(a.FullName), normalizeOrganizationName(b.FullName)
+	shortA, shortB := normalizeOrganizationName(a.ShortName), normalizeOrganizationName(b.ShortName)
+	switch {
+	case nameA == nameB || (shortA != "" && shortA == shortB):
+		reasons = append(reasons, duplicateSameName)
+	case similarNames(nameA, nameB):
+		reasons = append(reasons, duplicateSimilarName)
+	}
+	// A similar name alone is common among branches of one network, so it
+	// only counts together with an OGRN match.
+	if len(reasons) == 1 && reasons[0] == duplicateSimilarName {
+		return nil
+	}
+	return reasons
+}
+
+// duplicateCandidates returns the pairs of orgs indexes that may be
+// duplicates, so that only those are compared in full. A pair qualifies
+// with an equal normalized name or with a similar OGRN, and records sharing
+// one of these keys land in the same bucket: the name itself, the OGRN with
+// one digit masked or with two neighbouring digits put in order.
+func duplicateCandidates(orgs []duplicateOrganization) [][2]int {
+	buckets := make(map[string][]int)
+	for i, org := range orgs {
+		keys := []string{"name:" + normalizeOrganizationName(org.FullName)}
+		if short := normalizeOrganizationName(org.ShortName); short != "" {
+			keys = append(keys, "name:"+short)
+		}
+		ogrn := []byte(onlyDigits(org.OGRN))
+		for pos := range ogrn {
+			masked := append([]byte{}, ogrn...)
+			masked[pos] = '*'
+			keys = append(keys, "ogrn:"+string(masked))
+			if pos+1 < len(ogrn) && ogrn[pos] != ogrn[pos+1] {
+				swapped := append([]byte{}, ogrn...)
+				swapped[pos], swapped[pos+1] = min(ogrn[pos], ogrn[pos+1]), max(ogrn[pos], ogrn[pos+1])
+				keys = append(keys, fmt.Sprintf("swap:%d:%s", pos, swapped))
+			}
+		}
+		for _, key := range keys {
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	seen := make(map[[2]int]bool)
+	var pairs [][2]int
+	for _, members := range buckets {
+		for a := 0; a < len(members); a++ {
+			for b := a + 1; b < len(members); b++ {
+				pair := [2]int{min(members[a], members[b]), max(members[a], members[b])}
+				if pair[0] != pair[1] && !seen[pair] {
+					seen[pair] = true
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+// findDuplicateOrganizations compares the candidate pairs of registry records
+// and joins the suspected duplicates into groups.
+func (s *server) findDuplicateOrganizations(ctx context.Context) ([]duplicateGroup, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			registry.id,
+			registry.ogrn,
+			registry.full_name,
+			COALESCE(registry.short_name, ''),
+			COALESCE(acts.total, 0),
+			COALESCE(leaders.total, 0)
+		FROM organizations registry
+		LEFT JOIN (
+			SELECT organization_id, COUNT(*) AS total
+			FROM act_organization
+			GROUP BY organization_id
+		) acts ON acts.organization_id = registry.id
+		LEFT JOIN (
+			SELECT organization_id, COUNT(*) AS total
+			FROM leader
+			GROUP BY organization_id
+		) leaders ON leaders.organization_id = registry.id
+		ORDER BY registry.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []duplicateOrganization
+	for rows.Next() {
+		var org duplicateOrganization
+		if err := rows.Scan(&org.ID, &org.OGRN, &org.FullName, &org.ShortName, &org.Acts, &org.Leaders); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Union-find over the indexes of orgs.
+	parent := make([]int, len(orgs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	reasons := make(map[int][]string)
+	for _, pair := range duplicateCandidates(orgs) {
+		i, j := pair[0], pair[1]
+		pairReasons := duplicateReasons(orgs[i], orgs[j])
+		if len(pairReasons) == 0 {
+			continue
+		}
+		ri, rj := find(i), find(j)
+		if ri != rj {
+			parent[rj] = ri
+			reasons[ri] = append(reasons[ri], reasons[rj]...)
+			delete(reasons, rj)
+		}
+		reasons[ri] = append(reasons[ri], pairReasons...)
+	}
+
+	members := make(map[int][]duplicateOrganization)
+	var roots []int
+	for i, org := range orgs {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], org)
+	}
+
+	groups := []duplicateGroup{}
+	for _, root := range roots {
+		if len(members[root]) < 2 {
+			continue
+		}
+		group := duplicateGroup{Organizations: members[root]}
+		sort.SliceStable(group.Organizations, func(a, b int) bool {
+			return group.Organizations[a].Acts > group.Organizations[b].Acts
+		})
+		for _, reason := range []string{duplicateSimilarOGRN, duplicateSameName, duplicateSimilarName} {
+			if containsString(reasons[root], reason) {
+				group.Reasons = append(group.Reasons, reason)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
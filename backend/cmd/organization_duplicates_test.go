@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// TestDuplicateCandidatesCoverAllDuplicates checks the blocking against the
+// comparison of every pair: no suspected duplicate may be missed.
+func TestDuplicateCandidatesCoverAllDuplicates(t *testing.T) {
+	names := []string{
+		"ГБУЗ Городская больница № 1",
+		"Государственное бюджетное учреждение здравоохранения \"Городская больница № 1\"",
+		"ООО Ромашка",
+		"Общество с ограниченной ответственностью Ромашка",
+		"ООО Ромашкa",
+		"ИП Иванов Иван Иванович",
+	}
+	random := rand.New(rand.NewSource(1))
+	base := []byte("1027700132195")
+	var orgs []duplicateOrganization
+	seen := map[string]bool{}
+	for len(orgs) < 200 {
+		ogrn := append([]byte{}, base...)
+		switch random.Intn(3) {
+		case 0:
+			ogrn[random.Intn(len(ogrn))] = byte('0' + random.Intn(10))
+		case 1:
+			pos := random.Intn(len(ogrn) - 1)
+			ogrn[pos], ogrn[pos+1] = ogrn[pos+1], ogrn[pos]
+		default:
+			for i := range ogrn {
+				ogrn[i] = byte('0' + random.Intn(10))
+			}
+		}
+		if seen[string(ogrn)] {
+			continue
+		}
+		seen[string(ogrn)] = true
+		orgs = append(orgs, duplicateOrganization{
+			ID:       int64(len(orgs) + 1),
+			OGRN:     string(ogrn),
+			FullName: names[random.Intn(len(names))],
+		})
+	}
+
+	var want [][2]int
+	for i := range orgs {
+		for j := i + 1; j < len(orgs); j++ {
+			if len(duplicateReasons(orgs[i], orgs[j])) > 0 {
+				want = append(want, [2]int{i, j})
+			}
+		}
+	}
+	var got [][2]int
+	for _, pair := range duplicateCandidates(orgs) {
+		if len(duplicateReasons(orgs[pair[0]], orgs[pair[1]])) > 0 {
+			got = append(got, pair)
+		}
+	}
+	if len(want) == 0 {
+		t.Fatal("the sample has no duplicates")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("blocking found %d duplicate pairs, comparing all pairs found %d", len(got), len(want))
+	}
+}
+
+func TestDuplicateReasons(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b duplicateOrganization
+		want []string
+	}{
+		{
+			name: "swapped digits and spelled-out legal form",
+			a:    duplicateOrganization{OGRN: "1027700132195", FullName: "ООО Ромашка"},
+			b:    duplicateOrganization{OGRN: "1027700131295", FullName: "Общество с ограниченной ответственностью \"Ромашка\""},
+			want: []string{duplicateSimilarOGRN, duplicateSameName},
+		},
+		{
+			name: "same name, unrelated OGRN",
+			a:    duplicateOrganization{OGRN: "1027700132195", FullName: "ООО Ромашка"},
+			b:    duplicateOrganization{OGRN: "1037739010891", FullName: "ООО «Ромашка»"},
+			want: []string{duplicateSameName},
+		},
+		{
+			name: "similar name alone",
+			a:    duplicateOrganization{OGRN: "1027700132195", FullName: "ООО Ромашка"},
+			b:    duplicateOrganization{OGRN: "1037739010891", FullName: "ООО Ромашки"},
+		},
+	}
+	for _, tt := range tests {
+		if got := duplicateReasons(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: reasons = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
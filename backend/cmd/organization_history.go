@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// lastInspectedExpr is the start of the latest earlier inspection of the
+// organization of the current row: for a draft without dates, the latest one
+// that has already started.
+const lastInspectedExpr = `(
+			SELECT MAX(prev_insp.date_start)
+			FROM act_organization prev_org
+			INNER JOIN act_inspection prev_insp ON prev_insp.act_id = prev_org.act_id
+			WHERE prev_org.organization_ogrn = org.organization_ogrn
+				AND prev_org.act_id <> act.id
+				AND prev_insp.date_start <= CURRENT_DATE()
+				AND (insp.date_start IS NULL OR prev_insp.date_start < insp.date_start)
+		)`
+
+type organizationHistoryItem struct {
+	ID           int    `json:"id"`
+	Number       string `json:"number"`
+	FormType     string `json:"form_type"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	EarlyEndDate string `json:"early_end_date,omitempty"`
+	Status       string `json:"status"`
+	// OrganizationName is the name as written in the act.
+	OrganizationName string `json:"organization_name"`
+	// Findings counts the findings of the act by kind.
+	Findings map[string]int `json:"findings"`
+	// FindingTags counts the findings of the act by verification area and
+	// item, most frequent first.
+	FindingTags []findingTagCount `json:"finding_tags"`
+}
+
+// findingTagCount is the number of findings of one act tagged with a
+// verification area and item.
+type findingTagCount struct {
+	Area  string `json:"area"`
+	Item  string `json:"item,omitempty"`
+	Count int    `json:"count"`
+}
+
+type organizationHistory struct {
+	OGRN            string                    `json:"ogrn"`
+	Name            string                    `json:"name"`
+	LastInspectedOn string                    `json:"last_inspected_on,omitempty"`
+	Items           []organizationHistoryItem `json:"items"`
+	Total           int                       `json:"total"`
+}
+
+// organizationHistory lists every act of the OGRN, latest first. The name is
+// taken from the registry, or from the latest act when the registry does not
+// know the organization.
+func (s *server) organizationHistory(ctx context.Context, ogrn string) (organizationHistory, error) {
+	history := organizationHistory{OGRN: ogrn, Items: []organizationHistoryItem{}}
+
+	err := s.db.QueryRowContext(ctx, "SELECT full_name FROM organizations WHERE ogrn=?", ogrn).Scan(&history.Name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return organizationHistory{}, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			act.id,
+			insp.inspection_number,
+			insp.inspection_type,
+			insp.date_start,
+			insp.date_end,
+			insp.date_early_end,
+			`+inspectionStatusExpr+`,
+			org.organization_full_name,
+			COALESCE(findings.violations, 0),
+			COALESCE(findings.deficiencies, 0)
+		`+inspectionFromClause+`
+		LEFT JOIN (
+			SELECT act_id, SUM(kind = ?) AS violations, SUM(kind = ?) AS deficiencies
+			FROM inspection_finding
+			GROUP BY act_id
+		) findings ON findings.act_id = act.id
+		WHERE org.organization_ogrn = ?
+		ORDER BY insp.date_start IS NULL, insp.date_start DESC, act.id DESC
+	`, findingKindViolation, findingKindDeficiency, ogrn)
+	if err != nil {
+		return organizationHistory{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item                     organizationHistoryItem
+			start, end, earlyEnd     sql.NullTime
+			violations, deficiencies int
+		)
+		err := rows.Scan(&item.ID, &item.Number, &item.FormType, &start, &end, &earlyEnd, &item.Status,
+			&item.OrganizationName, &violations, &deficiencies)
+		if err != nil {
+			return organizationHistory{}, err
+		}
+		item.StartDate = formatDate(start)
+		item.EndDate = formatDate(end)
+		item.EarlyEndDate = formatDate(earlyEnd)
+		item.Findings = map[string]int{
+			findingKindViolation:  violations,
+			findingKindDeficiency: deficiencies,
+		}
+		item.FindingTags = []findingTagCount{}
+		if history.LastInspectedOn == "" && (item.Status == "in_progress" || item.Status == "completed") {
+			history.LastInspectedOn = item.StartDate
+		}
+		history.Items = append(history.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return organizationHistory{}, err
+	}
+
+	tags, err := s.organizationFindingTags(ctx, ogrn)
+	if err != nil {
+		return organizationHistory{}, err
+	}
+	for i := range history.Items {
+		if list, ok := tags[history.Items[i].ID]; ok {
+			history.Items[i].FindingTags = list
+		}
+	}
+
+	history.Total = len(history.Items)
+	if history.Name == "" && history.Total > 0 {
+		history.Name = history.Items[0].OrganizationName
+	}
+	return history, nil
+}
+
+// organizationFindingTags counts the findings of every act of the OGRN by
+// area and item, keyed by act id.
+func (s *server) organizationFindingTags(ctx context.Context, ogrn string) (map[int][]findingTagCount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT finding.act_id, finding.area, finding.item, COUNT(*)
+		FROM inspection_finding finding
+		INNER JOIN act_organization org ON org.act_id = finding.act_id
+		WHERE org.organization_ogrn = ?
+		GROUP BY finding.act_id, finding.area, finding.item
+		ORDER BY finding.act_id, COUNT(*) DESC, finding.area, finding.item
+	`, ogrn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]findingTagCount)
+	for rows.Next() {
+		var (
+			actID int
+			tag   findingTagCount
+		)
+		if err := rows.Scan(&actID, &tag.Area, &tag.Item, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags[actID] = append(tags[actID], tag)
+	}
+	return tags, rows.Err()
+}
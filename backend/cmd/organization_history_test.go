@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestOrganizationHistoryFindingTags(t *testing.T) {
+	db, _ := newFakeDB(t,
+		fakeResult{
+			match:   "GROUP BY finding.act_id, finding.area, finding.item",
+			columns: []string{"act_id", "area", "item", "count"},
+			rows: [][]driver.Value{
+				{int64(2), "Лицензирование", "Соответствие адресов", int64(2)},
+				{int64(2), "Кадры", "", int64(1)},
+			},
+		},
+		fakeResult{
+			match:   "FROM organizations WHERE ogrn",
+			columns: []string{"full_name"},
+			rows:    [][]driver.Value{{"ООО Ромашка"}},
+		},
+		fakeResult{
+			match:   "WHERE org.organization_ogrn = ?",
+			columns: []string{"id", "number", "type", "start", "end", "early_end", "status", "name", "violations", "deficiencies"},
+			rows: [][]driver.Value{
+				{int64(2), "2/2024", "плановая выездная", date("2024-03-04"), date("2024-03-15"), nil, "completed", "ООО Ромашка", int64(2), int64(1)},
+				{int64(1), "1/2023", "плановая выездная", date("2023-03-06"), date("2023-03-17"), nil, "completed", "ООО Ромашка", int64(0), int64(0)},
+			},
+		},
+	)
+
+	history, err := (&server{db: db}).organizationHistory(context.Background(), "1027700132195")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Items) != 2 {
+		t.Fatalf("items = %+v, want 2", history.Items)
+	}
+	want := []findingTagCount{
+		{Area: "Лицензирование", Item: "Соответствие адресов", Count: 2},
+		{Area: "Кадры", Count: 1},
+	}
+	if !reflect.DeepEqual(history.Items[0].FindingTags, want) {
+		t.Errorf("tags of act 2 = %+v, want %+v", history.Items[0].FindingTags, want)
+	}
+	if tags := history.Items[1].FindingTags; tags == nil || len(tags) != 0 {
+		t.Errorf("tags of act 1 = %#v, want an empty list", tags)
+	}
+	if history.LastInspectedOn != "2024-03-04" {
+		t.Errorf("last inspected on %q, want 2024-03-04", history.LastInspectedOn)
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+)
+
+var (
+	errMergeUndone       = errors.New("merge already undone")
+	errMergeTargetGone   = errors.New("merge target no longer exists")
+	errMergeSourceExists = errors.New("merged organization OGRN is taken again")
+	errMergeLeaderTerms  = errors.New("merged leader terms overlap")
+)
+
+type organizationMergePayload struct {
+	TargetID  int64   `json:"target_id"`
+	SourceIDs []int64 `json:"source_ids"`
+}
+
+// mergedOrganization is a deleted registry record as it was before a merge.
+type mergedOrganization struct {
+	ID            int64     `json:"id"`
+	FullName      string    `json:"full_name"`
+	ShortName     string    `json:"short_name,omitempty"`
+	OGRN          string    `json:"ogrn"`
+	LegalAddress  string    `json:"legal_address"`
+	PostalAddress string    `json:"postal_address,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// mergedAct remembers the OGRN an act carried before it was re-pointed.
+type mergedAct struct {
+	ActID int64  `json:"act_id"`
+	OGRN  string `json:"ogrn"`
+}
+
+// mergedChange is a DaData difference of a merged record; deleting the
+// record deletes it too.
+type mergedChange struct {
+	ID         int64           `json:"id"`
+	Field      string          `json:"field"`
+	OldValue   string          `json:"old_value"`
+	NewValue   string          `json:"new_value"`
+	Leader     json.RawMessage `json:"leader,omitempty"`
+	Status     string          `json:"status"`
+	DetectedAt time.Time       `json:"detected_at"`
+	ReviewedBy string          `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time      `json:"reviewed_at,omitempty"`
+}
+
+// organizationMergeSource is everything undo needs to restore one merged
+// record: the record itself, what pointed at it and its DaData differences.
+type organizationMergeSource struct {
+	Organization mergedOrganization `json:"organization"`
+	Acts         []mergedAct        `json:"acts"`
+	Leaders      []int64            `json:"leaders"`
+	Changes      []mergedChange     `json:"changes"`
+}
+
+type organizationMerge struct {
+	ID         int                       `json:"id"`
+	TargetID   int64                     `json:"target_id"`
+	TargetName string                    `json:"target_name,omitempty"`
+	Sources    []organizationMergeSource `json:"sources"`
+	MergedBy   string                    `json:"merged_by"`
+	MergedAt   string                    `json:"merged_at"`
+	UndoneBy   string                    `json:"undone_by,omitempty"`
+	UndoneAt   string                    `json:"undone_at,omitempty"`
+}
+
+func validateOrganizationMergePayload(payload organizationMergePayload) error {
+	v := validation.New()
+	if payload.TargetID <= 0 {
+		v.Add("target_id", validation.CodeRequired, "Укажите организацию, которая останется")
+	}
+	if len(payload.SourceIDs) == 0 {
+		v.Add("source_ids", validation.CodeRequired, "Укажите организации, которые нужно объединить")
+	}
+	seen := make(map[int64]bool)
+	for i, id := range payload.SourceIDs {
+		field := fmt.Sprintf("source_ids[%d]", i)
+		switch {
+		case id == payload.TargetID:
+			v.Add(field, validation.CodeInvalidValue, "Организация не может быть объединена сама с собой")
+		case seen[id]:
+			v.Add(field, validation.CodeInvalidValue, "Организация указана дважды")
+		}
+		seen[id] = true
+	}
+	return v.Err()
+}
+
+// mergeOrganizations moves the acts and leaders of the sources to the target
+// and deletes the sources. Acts take the OGRN of the target, their other
+// organization data stays as written in the act. When the moved leader terms
+// overlap the merge is refused with errMergeLeaderTerms: the terms have to be
+// fixed by hand first. The removed records and the previous links are written
+// to the merge log for undo.
+func (s *server) mergeOrganizations(ctx context.Context, payload organizationMergePayload, mergedBy string) (merge organizationMerge, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return organizationMerge{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var targetOGRN string
+	err = tx.QueryRowContext(ctx, "SELECT ogrn FROM organizations WHERE id=? FOR UPDATE", payload.TargetID).Scan(&targetOGRN)
+	if err != nil {
+		return organizationMerge{}, err
+	}
+
+	var sources []organizationMergeSource
+	for _, sourceID := range payload.SourceIDs {
+		source, err := loadMergeSource(ctx, tx, sourceID)
+		if err != nil {
+			return organizationMerge{}, err
+		}
+		sources = append(sources, source)
+
+		if _, err = tx.ExecContext(ctx, `
+			UPDATE act_organization
+			SET organization_id=?, organization_ogrn=?
+			WHERE organization_id=?
+		`, payload.TargetID, targetOGRN, sourceID); err != nil {
+			return organizationMerge{}, err
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE leader SET organization_id=? WHERE organization_id=?", payload.TargetID, sourceID); err != nil {
+			return organizationMerge{}, err
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM organizations WHERE id=?", sourceID); err != nil {
+			return organizationMerge{}, err
+		}
+		for _, act := range source.Acts {
+			if err = refreshActSearch(ctx, tx, act.ActID); err != nil {
+				return organizationMerge{}, err
+			}
+		}
+	}
+
+	if err = checkMergedLeaderTerms(ctx, tx, payload.TargetID); err != nil {
+		return organizationMerge{}, err
+	}
+
+	sourcesJSON, err := json.Marshal(sources)
+	if err != nil {
+		return organizationMerge{}, err
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO organization_merge (target_id, sources, merged_by)
+		VALUES (?, ?, ?)
+	`, payload.TargetID, sourcesJSON, mergedBy)
+	if err != nil {
+		return organizationMerge{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return organizationMerge{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return organizationMerge{}, err
+	}
+	return s.fetchOrganizationMerge(ctx, int(id))
+}
+
+// checkMergedLeaderTerms returns errMergeLeaderTerms when two leader terms of
+// the organization intersect, so the merge never leaves two leaders in charge
+// on the same day.
+func checkMergedLeaderTerms(ctx context.Context, tx *sql.Tx, orgID int64) error {
+	var count int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM leader a
+		JOIN leader b ON b.organization_id = a.organization_id AND b.id > a.id
+		WHERE a.organization_id = ?
+			AND a.effective_from <= COALESCE(b.effective_to, '9999-12-31')
+			AND b.effective_from <= COALESCE(a.effective_to, '9999-12-31')
+	`, orgID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errMergeLeaderTerms
+	}
+	return nil
+}
+
+// loadMergeSource reads a registry record with the IDs of the acts and
+// leaders linked to it, locking them for the merge, and its DaData
+// differences.
+func loadMergeSource(ctx context.Context, tx *sql.Tx, id int64) (organizationMergeSource, error) {
+	source := organizationMergeSource{Acts: []mergedAct{}, Leaders: []int64{}, Changes: []mergedChange{}}
+	org := &source.Organization
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, full_name, COALESCE(short_name, ''), ogrn, legal_address, COALESCE(postal_address, ''), created_at
+		FROM organizations
+		WHERE id=?
+		FOR UPDATE
+	`, id).Scan(&org.ID, &org.FullName, &org.ShortName, &org.OGRN, &org.LegalAddress, &org.PostalAddress, &org.CreatedAt)
+	if err != nil {
+		return organizationMergeSource{}, err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT act_id, organization_ogrn FROM act_organization WHERE organization_id=? FOR UPDATE", id)
+	if err != nil {
+		return organizationMergeSource{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var act mergedAct
+		if err := rows.Scan(&act.ActID, &act.OGRN); err != nil {
+			return organizationMergeSource{}, err
+		}
+		source.Acts = append(source.Acts, act)
+	}
+	if err := rows.Err(); err != nil {
+		return organizationMergeSource{}, err
+	}
+
+	leaderRows, err := tx.QueryContext(ctx, "SELECT id FROM leader WHERE organization_id=? FOR UPDATE", id)
+	if err != nil {
+		return organizationMergeSource{}, err
+	}
+	defer leaderRows.Close()
+	for leaderRows.Next() {
+		var leaderID int64
+		if err := leaderRows.Scan(&leaderID); err != nil {
+			return organizationMergeSource{}, err
+		}
+		source.Leaders = append(source.Leaders, leaderID)
+	}
+	if err := leaderRows.Err(); err != nil {
+		return organizationMergeSource{}, err
+	}
+
+	changeRows, err := tx.QueryContext(ctx, `
+		SELECT id, field, old_value, new_value, leader, status, detected_at, COALESCE(reviewed_by, ''), reviewed_at
+		FROM organization_change
+		WHERE organization_id=?
+		ORDER BY id
+		FOR UPDATE
+	`, id)
+	if err != nil {
+		return organizationMergeSource{}, err
+	}
+	defer changeRows.Close()
+	for changeRows.Next() {
+		var (
+			change     mergedChange
+			leader     []byte
+			reviewedAt sql.NullTime
+		)
+		err := changeRows.Scan(&change.ID, &change.Field, &change.OldValue, &change.NewValue, &leader,
+			&change.Status, &change.DetectedAt, &change.ReviewedBy, &reviewedAt)
+		if err != nil {
+			return organizationMergeSource{}, err
+		}
+		if len(leader) > 0 {
+			change.Leader = json.RawMessage(leader)
+		}
+		if reviewedAt.Valid {
+			change.ReviewedAt = &reviewedAt.Time
+		}
+		source.Changes = append(source.Changes, change)
+	}
+	return source, changeRows.Err()
+}
+
+// undoOrganizationMerge restores the merged records and their DaData
+// differences under their former IDs and moves back the acts and leaders that
+// still belong to the target.
+func (s *server) undoOrganizationMerge(ctx context.Context, id int, undoneBy string) (merge organizationMerge, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return organizationMerge{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		targetID    int64
+		sourcesJSON []byte
+		undoneAt    sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, "SELECT target_id, sources, undone_at FROM organization_merge WHERE id=? FOR UPDATE", id).
+		Scan(&targetID, &sourcesJSON, &undoneAt)
+	if err != nil {
+		return organizationMerge{}, err
+	}
+	if undoneAt.Valid {
+		return organizationMerge{}, errMergeUndone
+	}
+	var exists int
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM organizations WHERE id=? FOR UPDATE", targetID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return organizationMerge{}, errMergeTargetGone
+	}
+	if err != nil {
+		return organizationMerge{}, err
+	}
+
+	var sources []organizationMergeSource
+	if err = json.Unmarshal(sourcesJSON, &sources); err != nil {
+		return organizationMerge{}, err
+	}
+	for _, source := range sources {
+		org := source.Organization
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO organizations (id, full_name, short_name, ogrn, legal_address, postal_address, created_at)
+			VALUES (?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), ?)
+		`, org.ID, org.FullName, org.ShortName, org.OGRN, org.LegalAddress, org.PostalAddress, org.CreatedAt)
+		if err != nil {
+			if isDuplicateEntry(err) {
+				err = errMergeSourceExists
+			}
+			return organizationMerge{}, err
+		}
+		for _, act := range source.Acts {
+			if _, err = tx.ExecContext(ctx, `
+				UPDATE act_organization
+				SET organization_id=?, organization_ogrn=?
+				WHERE act_id=? AND organization_id=?
+			`, org.ID, act.OGRN, act.ActID, targetID); err != nil {
+				return organizationMerge{}, err
+			}
+			if err = refreshActSearch(ctx, tx, act.ActID); err != nil {
+				return organizationMerge{}, err
+			}
+		}
+		if len(source.Leaders) > 0 {
+			args := []any{org.ID, targetID}
+			for _, leaderID := range source.Leaders {
+				args = append(args, leaderID)
+			}
+			if _, err = tx.ExecContext(ctx, `
+				UPDATE leader SET organization_id=?
+				WHERE organization_id=? AND id IN (`+placeholders(len(source.Leaders))+`)
+			`, args...); err != nil {
+				return organizationMerge{}, err
+			}
+		}
+		for _, change := range source.Changes {
+			var leader any
+			if len(change.Leader) > 0 {
+				leader = []byte(change.Leader)
+			}
+			if _, err = tx.ExecContext(ctx, `
+				INSERT INTO organization_change
+					(id, organization_id, field, old_value, new_value, leader, status, detected_at, reviewed_by, reviewed_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+			`, change.ID, org.ID, change.Field, change.OldValue, change.NewValue, leader,
+				change.Status, change.DetectedAt, change.ReviewedBy, change.ReviewedAt); err != nil {
+				return organizationMerge{}, err
+			}
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE organization_merge SET undone_by=?, undone_at=NOW() WHERE id=?", undoneBy, id); err != nil {
+		return organizationMerge{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return organizationMerge{}, err
+	}
+	return s.fetchOrganizationMerge(ctx, id)
+}
+
+const organizationMergeSelect = `
+	SELECT
+		merge_log.id,
+		merge_log.target_id,
+		COALESCE(registry.full_name, ''),
+		merge_log.sources,
+		merge_log.merged_by,
+		merge_log.merged_at,
+		COALESCE(merge_log.undone_by, ''),
+		merge_log.undone_at
+	FROM organization_merge merge_log
+	LEFT JOIN organizations registry ON registry.id = merge_log.target_id`
+
+func (s *server) fetchOrganizationMerge(ctx context.Context, id int) (organizationMerge, error) {
+	return scanOrganizationMerge(s.db.QueryRowContext(ctx, organizationMergeSelect+" WHERE merge_log.id=?", id))
+}
+
+// listOrganizationMerges returns the merge log, newest first.
+func (s *server) listOrganizationMerges(ctx context.Context) ([]organizationMerge, error) {
+	rows, err := s.db.QueryContext(ctx, organizationMergeSelect+" ORDER BY merge_log.id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []organizationMerge{}
+	for rows.Next() {
+		merge, err := scanOrganizationMerge(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, merge)
+	}
+	return list, rows.Err()
+}
+
+func scanOrganizationMerge(scanner rowScanner) (organizationMerge, error) {
+	var (
+		merge       organizationMerge
+		sourcesJSON []byte
+		mergedAt    time.Time
+		undoneAt    sql.NullTime
+	)
+	err := scanner.Scan(&merge.ID, &merge.TargetID, &merge.TargetName, &sourcesJSON, &merge.MergedBy, &mergedAt, &merge.UndoneBy, &undoneAt)
+	if err != nil {
+		return organizationMerge{}, err
+	}
+	if err := json.Unmarshal(sourcesJSON, &merge.Sources); err != nil {
+		return organizationMerge{}, err
+	}
+	merge.MergedAt = mergedAt.Format(time.RFC3339)
+	if undoneAt.Valid {
+		merge.UndoneAt = undoneAt.Time.Format(time.RFC3339)
+	}
+	return merge, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOrganizationMergeKeepsChanges(t *testing.T) {
+	detected := time.Date(2024, time.March, 1, 3, 0, 0, 0, time.UTC)
+	reviewed := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	mergedAt := time.Date(2024, time.March, 5, 12, 0, 0, 0, time.UTC)
+	organizationRow := []driver.Value{int64(2), "ООО Ромашка", "", "1027700132195", "г Москва", "", detected}
+	mergeColumns := []string{"id", "target_id", "target_name", "sources", "merged_by", "merged_at", "undone_by", "undone_at"}
+
+	db, fake := newFakeDB(t,
+		fakeResult{match: "SELECT ogrn FROM organizations", columns: []string{"ogrn"}, rows: [][]driver.Value{{"1027700132196"}}},
+		fakeResult{match: "SELECT id, full_name", columns: []string{"id", "full_name", "short_name", "ogrn", "legal_address", "postal_address", "created_at"}, rows: [][]driver.Value{organizationRow}},
+		fakeResult{match: "FROM act_organization", columns: []string{"act_id", "ogrn"}},
+		fakeResult{match: "SELECT id FROM leader", columns: []string{"id"}},
+		fakeResult{match: "FROM organization_change", columns: []string{"id", "field", "old_value", "new_value", "leader", "status", "detected_at", "reviewed_by", "reviewed_at"}, rows: [][]driver.Value{
+			{int64(7), "leader", "Иванов", "Петров", []byte(`{"post":"Директор","name":"Петров"}`), "pending", detected, "", nil},
+			{int64(8), "full_name", "ООО Ромашка", "ООО «Ромашка»", nil, "rejected", detected, "admin", reviewed},
+		}},
+		fakeResult{match: "SELECT COUNT(*)", columns: []string{"count"}, rows: [][]driver.Value{{int64(0)}}},
+		fakeResult{match: "FROM organization_merge merge_log", columns: mergeColumns, rows: [][]driver.Value{{int64(1), int64(1), "", []byte("[]"), "admin", mergedAt, "", nil}}},
+	)
+	srv := &server{db: db}
+
+	if _, err := srv.mergeOrganizations(context.Background(), organizationMergePayload{TargetID: 1, SourceIDs: []int64{2}}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	logged := fake.executed("INSERT INTO organization_merge")
+	if len(logged) != 1 {
+		t.Fatalf("merge log inserts = %+v, want 1", logged)
+	}
+	sourcesJSON := logged[0].args[1].([]byte)
+	var sources []organizationMergeSource
+	if err := json.Unmarshal(sourcesJSON, &sources); err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 1 || len(sources[0].Changes) != 2 {
+		t.Fatalf("logged sources %s, want the two changes of the source", sourcesJSON)
+	}
+
+	db, fake = newFakeDB(t,
+		fakeResult{match: "SELECT target_id, sources, undone_at", columns: []string{"target_id", "sources", "undone_at"}, rows: [][]driver.Value{{int64(1), sourcesJSON, nil}}},
+		fakeResult{match: "SELECT 1 FROM organizations", columns: []string{"exists"}, rows: [][]driver.Value{{int64(1)}}},
+		fakeResult{match: "FROM organization_merge merge_log", columns: mergeColumns, rows: [][]driver.Value{{int64(1), int64(1), "", sourcesJSON, "admin", mergedAt, "admin", mergedAt}}},
+	)
+	srv = &server{db: db}
+	if _, err := srv.undoOrganizationMerge(context.Background(), 1, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	restored := fake.executed("INSERT INTO organization_change")
+	if len(restored) != 2 {
+		t.Fatalf("restored changes = %+v, want 2", restored)
+	}
+	pending, rejected := restored[0].args, restored[1].args
+	if pending[0] != int64(7) || pending[1] != int64(2) || string(pending[5].([]byte)) != `{"post":"Директор","name":"Петров"}` || pending[9] != nil {
+		t.Errorf("pending change restored as %v", pending)
+	}
+	if rejected[0] != int64(8) || rejected[5] != nil || rejected[6] != "rejected" || rejected[8] != "admin" ||
+		!rejected[9].(time.Time).Equal(reviewed) {
+		t.Errorf("rejected change restored as %v", rejected)
+	}
+}
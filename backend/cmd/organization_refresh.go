@@ -0,0 +1,624 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/address"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/dadata"
+)
+
+const (
+	organizationChangePending  = "pending"
+	organizationChangeAccepted = "accepted"
+	organizationChangeRejected = "rejected"
+)
+
+var organizationChangeStatuses = []string{
+	organizationChangePending,
+	organizationChangeAccepted,
+	organizationChangeRejected,
+}
+
+// Registry fields the refresh job compares with DaData.
+const (
+	organizationFieldFullName     = "full_name"
+	organizationFieldShortName    = "short_name"
+	organizationFieldLegalAddress = "legal_address"
+	organizationFieldLeader       = "leader"
+)
+
+var (
+	errOrganizationChangeReviewed = errors.New("organization change already reviewed")
+	errLeaderStartsToday          = errors.New("a leader term already starts today")
+)
+
+// partyFinder is the part of the DaData client the refresh job needs, so
+// that a stub can stand in for the API. RefreshPartyByID skips the response
+// cache: the job looks for changes.
+type partyFinder interface {
+	RefreshPartyByID(ctx context.Context, id string) ([]dadata.Party, error)
+}
+
+// changeLeader is the head of the organization proposed by DaData.
+type changeLeader struct {
+	Position   string `json:"position"`
+	LastName   string `json:"last_name"`
+	FirstName  string `json:"first_name"`
+	MiddleName string `json:"middle_name"`
+}
+
+func (l changeLeader) String() string {
+	name := strings.Join(strings.Fields(l.LastName+" "+l.FirstName+" "+l.MiddleName), " ")
+	if l.Position == "" {
+		return name
+	}
+	return name + " (" + l.Position + ")"
+}
+
+// organizationChange is a difference between the registry and DaData waiting
+// for an admin, or already accepted or rejected.
+type organizationChange struct {
+	ID             int64         `json:"id"`
+	OrganizationID int64         `json:"organization_id"`
+	OGRN           string        `json:"ogrn"`
+	Organization   string        `json:"organization"`
+	Field          string        `json:"field"`
+	OldValue       string        `json:"old_value"`
+	NewValue       string        `json:"new_value"`
+	Leader         *changeLeader `json:"leader,omitempty"`
+	Status         string        `json:"status"`
+	DetectedAt     string        `json:"detected_at"`
+	ReviewedBy     string        `json:"reviewed_by,omitempty"`
+	ReviewedAt     string        `json:"reviewed_at,omitempty"`
+}
+
+// organizationSnapshot is a registry record with the leader in charge today.
+type organizationSnapshot struct {
+	ID           int64
+	OGRN         string
+	FullName     string
+	ShortName    string
+	LegalAddress string
+	Leader       changeLeader
+}
+
+type organizationRefreshResult struct {
+	Checked  int `json:"checked"`
+	Changes  int `json:"changes"`
+	NotFound int `json:"not_found"`
+	Failed   int `json:"failed"`
+}
+
+// runOrganizationRefresh re-checks the registry against DaData at startup
+// and then every DadataRefreshInterval until ctx is done.
+func (s *server) runOrganizationRefresh(ctx context.Context) {
+	if s.parties == nil || s.config.DadataRefreshInterval == 0 {
+		log.Println("organization refresh disabled")
+		return
+	}
+	ticker := time.NewTicker(s.config.DadataRefreshInterval)
+	defer ticker.Stop()
+	for {
+		// A refresh started by an administrator is not run twice.
+		if s.refreshing.TryLock() {
+			result, err := s.refreshOrganizations(ctx, s.parties)
+			s.refreshing.Unlock()
+			if err != nil {
+				log.Println("organization refresh error:", err)
+			} else {
+				log.Printf("organization refresh: %+v", result)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshOrganizations looks every registry OGRN up in DaData and queues the
+// differences in name, legal address and head for review. An organization
+// that DaData does not answer for is skipped; the run goes on.
+func (s *server) refreshOrganizations(ctx context.Context, finder partyFinder) (organizationRefreshResult, error) {
+	var result organizationRefreshResult
+	snapshots, err := s.organizationSnapshots(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, snapshot := range snapshots {
+		changes, found, err := lookupOrganizationChanges(ctx, finder, snapshot)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			log.Printf("organization refresh %s: %v", snapshot.OGRN, err)
+			result.Failed++
+			continue
+		}
+		result.Checked++
+		if !found {
+			result.NotFound++
+			continue
+		}
+		queued, err := s.queueOrganizationChanges(ctx, snapshot.ID, changes)
+		if err != nil {
+			return result, err
+		}
+		result.Changes += queued
+	}
+	return result, nil
+}
+
+// lookupOrganizationChanges asks finder for the OGRN of snapshot and returns
+// the differences; found is false when DaData does not know the OGRN.
+func lookupOrganizationChanges(ctx context.Context, finder partyFinder, snapshot organizationSnapshot) (changes []organizationChange, found bool, err error) {
+	parties, err := finder.RefreshPartyByID(ctx, snapshot.OGRN)
+	if err != nil || len(parties) == 0 {
+		return nil, false, err
+	}
+	return diffOrganization(snapshot, mainParty(parties)), true, nil
+}
+
+func (s *server) organizationSnapshots(ctx context.Context) ([]organizationSnapshot, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			registry.id,
+			registry.ogrn,
+			registry.full_name,
+			COALESCE(registry.short_name, ''),
+			registry.legal_address,
+			COALESCE(current_leader.position, ''),
+			COALESCE(current_leader.last_name, ''),
+			COALESCE(current_leader.first_name, ''),
+			COALESCE(current_leader.middle_name, '')
+		FROM organizations registry
+		LEFT JOIN leader current_leader ON current_leader.id = (
+			SELECT id
+			FROM leader
+			WHERE organization_id = registry.id
+				AND effective_from <= CURDATE()
+				AND (effective_to IS NULL OR effective_to >= CURDATE())
+			ORDER BY effective_from DESC, id DESC
+			LIMIT 1
+		)
+		ORDER BY registry.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []organizationSnapshot
+	for rows.Next() {
+		var item organizationSnapshot
+		if err := rows.Scan(
+			&item.ID,
+			&item.OGRN,
+			&item.FullName,
+			&item.ShortName,
+			&item.LegalAddress,
+			&item.Leader.Position,
+			&item.Leader.LastName,
+			&item.Leader.FirstName,
+			&item.Leader.MiddleName,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, rows.Err()
+}
+
+// diffOrganization returns the fields where DaData differs from the registry.
+// Blank DaData values never propose clearing a field; case, spacing and the
+// written form of addresses do not count as a difference.
+func diffOrganization(stored organizationSnapshot, data dadata.PartyData) []organizationChange {
+	var changes []organizationChange
+	add := func(field, oldValue, newValue string, same bool) {
+		if strings.TrimSpace(newValue) == "" || same {
+			return
+		}
+		changes = append(changes, organizationChange{Field: field, OldValue: oldValue, NewValue: newValue})
+	}
+	add(organizationFieldFullName, stored.FullName, data.Name.FullWithOpf, sameText(stored.FullName, data.Name.FullWithOpf))
+	add(organizationFieldShortName, stored.ShortName, data.Name.ShortWithOpf, sameText(stored.ShortName, data.Name.ShortWithOpf))
+	add(organizationFieldLegalAddress, stored.LegalAddress, data.Address.Value,
+		sameText(address.Normalize(stored.LegalAddress), address.Normalize(data.Address.Value)))
+
+	if data.Management == nil {
+		return changes
+	}
+	lastName, rest := splitManagerName(data.Management.Name)
+	if lastName == "" {
+		return changes
+	}
+	leader := changeLeader{Position: strings.TrimSpace(data.Management.Post), LastName: lastName}
+	leader.FirstName, leader.MiddleName, _ = strings.Cut(rest, " ")
+	if !sameText(stored.Leader.String(), leader.String()) {
+		changes = append(changes, organizationChange{
+			Field:    organizationFieldLeader,
+			OldValue: stored.Leader.String(),
+			NewValue: leader.String(),
+			Leader:   &leader,
+		})
+	}
+	return changes
+}
+
+func sameText(a, b string) bool {
+	normalize := func(value string) string {
+		return strings.ReplaceAll(strings.ToLower(strings.Join(strings.Fields(value), " ")), "ё", "е")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// organizationChangePlan is what queueing the differences of one
+// organization does to its pending changes.
+type organizationChangePlan struct {
+	Insert []organizationChange
+	// Update carries the ID of the pending change it replaces.
+	Update []organizationChange
+	Delete []int64
+}
+
+// planOrganizationChanges compares the differences found now with the
+// pending and rejected changes of the organization. A pending change is
+// replaced when DaData reports another value and dropped once the registry
+// agrees; a value an admin rejected is not proposed again.
+func planOrganizationChanges(existing, changes []organizationChange) organizationChangePlan {
+	var plan organizationChangePlan
+	pending := make(map[string]organizationChange)
+	rejected := make(map[string]bool)
+	for _, change := range existing {
+		switch change.Status {
+		case organizationChangePending:
+			pending[change.Field] = change
+		case organizationChangeRejected:
+			rejected[change.Field+"\x00"+change.NewValue] = true
+		}
+	}
+
+	current := make(map[string]bool)
+	for _, change := range changes {
+		current[change.Field] = true
+		queued, isPending := pending[change.Field]
+		switch {
+		case rejected[change.Field+"\x00"+change.NewValue]:
+			if isPending {
+				plan.Delete = append(plan.Delete, queued.ID)
+			}
+		case !isPending:
+			plan.Insert = append(plan.Insert, change)
+		case queued.NewValue != change.NewValue:
+			change.ID = queued.ID
+			plan.Update = append(plan.Update, change)
+		}
+	}
+	for _, change := range existing {
+		if change.Status == organizationChangePending && !current[change.Field] {
+			plan.Delete = append(plan.Delete, change.ID)
+		}
+	}
+	return plan
+}
+
+// queueOrganizationChanges stores the current differences of an organization
+// as pending changes and returns how many are new or updated.
+func (s *server) queueOrganizationChanges(ctx context.Context, organizationID int64, changes []organizationChange) (queued int, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, field, new_value, status FROM organization_change
+		WHERE organization_id=? AND status IN ('pending', 'rejected')
+		FOR UPDATE
+	`, organizationID)
+	if err != nil {
+		return 0, err
+	}
+	var existing []organizationChange
+	for rows.Next() {
+		var change organizationChange
+		if err = rows.Scan(&change.ID, &change.Field, &change.NewValue, &change.Status); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		existing = append(existing, change)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	plan := planOrganizationChanges(existing, changes)
+	for _, id := range plan.Delete {
+		if _, err = tx.ExecContext(ctx, "DELETE FROM organization_change WHERE id=?", id); err != nil {
+			return 0, err
+		}
+	}
+	for _, change := range plan.Insert {
+		var leaderJSON []byte
+		if leaderJSON, err = marshalChangeLeader(change.Leader); err != nil {
+			return 0, err
+		}
+		if _, err = tx.ExecContext(ctx, `
+			INSERT INTO organization_change (organization_id, field, old_value, new_value, leader)
+			VALUES (?, ?, ?, ?, ?)
+		`, organizationID, change.Field, change.OldValue, change.NewValue, leaderJSON); err != nil {
+			return 0, err
+		}
+	}
+	for _, change := range plan.Update {
+		var leaderJSON []byte
+		if leaderJSON, err = marshalChangeLeader(change.Leader); err != nil {
+			return 0, err
+		}
+		if _, err = tx.ExecContext(ctx, `
+			UPDATE organization_change
+			SET old_value=?, new_value=?, leader=?, detected_at=NOW()
+			WHERE id=?
+		`, change.OldValue, change.NewValue, leaderJSON, change.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(plan.Insert) + len(plan.Update), tx.Commit()
+}
+
+func marshalChangeLeader(leader *changeLeader) ([]byte, error) {
+	if leader == nil {
+		return nil, nil
+	}
+	return json.Marshal(leader)
+}
+
+// reviewOrganizationChange accepts or rejects a pending change. Accepting
+// writes the new value to the registry; a new head starts a leader term
+// today and closes the previous one. Acts keep the data they were written
+// with.
+func (s *server) reviewOrganizationChange(ctx context.Context, id int, accept bool, reviewedBy string) (change organizationChange, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return organizationChange{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	change, err = scanOrganizationChange(tx.QueryRowContext(ctx, organizationChangeSelect+" WHERE change_log.id=? FOR UPDATE", id))
+	if err != nil {
+		return organizationChange{}, err
+	}
+	if change.Status != organizationChangePending {
+		return organizationChange{}, errOrganizationChangeReviewed
+	}
+
+	status := organizationChangeRejected
+	if accept {
+		status = organizationChangeAccepted
+		if err = applyOrganizationChange(ctx, tx, change); err != nil {
+			return organizationChange{}, err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE organization_change SET status=?, reviewed_by=?, reviewed_at=NOW() WHERE id=?
+	`, status, reviewedBy, id); err != nil {
+		return organizationChange{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return organizationChange{}, err
+	}
+	return s.fetchOrganizationChange(ctx, id)
+}
+
+func applyOrganizationChange(ctx context.Context, tx *sql.Tx, change organizationChange) error {
+	switch change.Field {
+	case organizationFieldFullName, organizationFieldShortName, organizationFieldLegalAddress:
+		// The column name comes from the constants above, never from input.
+		_, err := tx.ExecContext(ctx, "UPDATE organizations SET "+change.Field+"=? WHERE id=?", change.NewValue, change.OrganizationID)
+		return err
+	case organizationFieldLeader:
+		if change.Leader == nil {
+			return fmt.Errorf("organization change %d has no leader", change.ID)
+		}
+		return startLeaderTermToday(ctx, tx, change.OrganizationID, *change.Leader)
+	default:
+		return fmt.Errorf("unknown organization change field %q", change.Field)
+	}
+}
+
+// startLeaderTermToday makes leader the head of the organization from today,
+// ending the open term of the previous head yesterday.
+func startLeaderTermToday(ctx context.Context, tx *sql.Tx, organizationID int64, leader changeLeader) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE leader
+		SET effective_to = DATE_SUB(CURDATE(), INTERVAL 1 DAY)
+		WHERE organization_id = ? AND effective_to IS NULL AND effective_from < CURDATE()
+	`, organizationID); err != nil {
+		return err
+	}
+	var current int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM leader
+		WHERE organization_id = ? AND (effective_to IS NULL OR effective_to >= CURDATE())
+	`, organizationID).Scan(&current)
+	if err != nil {
+		return err
+	}
+	if current > 0 {
+		return errLeaderStartsToday
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO leader (organization_id, position, last_name, first_name, middle_name, initials_name_im, initials_name_dat, effective_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURDATE())
+	`, organizationID, leader.Position, leader.LastName, leader.FirstName, leader.MiddleName,
+		initialsName(leader.LastName, leader), initialsName(dativeSurname(leader.LastName, leader.MiddleName), leader))
+	return err
+}
+
+// initialsName writes lastName with the initials of leader: "Иванов И.И.".
+// It returns "" for a blank lastName.
+func initialsName(lastName string, leader changeLeader) string {
+	if lastName == "" {
+		return ""
+	}
+	initials := ""
+	for _, name := range []string{leader.FirstName, leader.MiddleName} {
+		for _, ch := range name {
+			initials += string(ch) + "."
+			break
+		}
+	}
+	return strings.TrimSpace(lastName + " " + initials)
+}
+
+// dativeSurname declines a surname for "кому?" when the patronymic tells the
+// gender and the ending is unambiguous, and returns "" otherwise so that the
+// act asks for it.
+func dativeSurname(lastName, patronymic string) string {
+	lower := strings.ToLower(lastName)
+	patronymic = strings.ToLower(patronymic)
+	switch {
+	case strings.HasSuffix(patronymic, "ич"):
+		switch {
+		case hasAnySuffix(lower, "ский", "цкий", "ой"):
+			return trimRunes(lastName, 2) + "ому"
+		case hasAnySuffix(lower, "ь"):
+			return trimRunes(lastName, 1) + "ю"
+		case hasAnySuffix(lower, "о", "е", "и", "у", "ы", "э", "ю"):
+			return lastName
+		case hasAnySuffix(lower, "а", "я", "й", "ь", "ъ"):
+			return ""
+		default:
+			return lastName + "у"
+		}
+	case strings.HasSuffix(patronymic, "на"):
+		switch {
+		case hasAnySuffix(lower, "ова", "ева", "ёва", "ина", "ына"):
+			return trimRunes(lastName, 1) + "ой"
+		case hasAnySuffix(lower, "ская", "цкая"):
+			return trimRunes(lastName, 2) + "ой"
+		case hasAnySuffix(lower, "а", "я", "ь", "й"):
+			return ""
+		default:
+			// Other women's surnames do not decline.
+			return lastName
+		}
+	}
+	return ""
+}
+
+func hasAnySuffix(value string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(value, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func trimRunes(value string, n int) string {
+	runes := []rune(value)
+	return string(runes[:len(runes)-n])
+}
+
+const organizationChangeSelect = `
+	SELECT
+		change_log.id,
+		change_log.organization_id,
+		registry.ogrn,
+		COALESCE(NULLIF(registry.short_name, ''), registry.full_name),
+		change_log.field,
+		change_log.old_value,
+		change_log.new_value,
+		change_log.leader,
+		change_log.status,
+		change_log.detected_at,
+		COALESCE(change_log.reviewed_by, ''),
+		change_log.reviewed_at
+	FROM organization_change change_log
+	INNER JOIN organizations registry ON registry.id = change_log.organization_id`
+
+func (s *server) fetchOrganizationChange(ctx context.Context, id int) (organizationChange, error) {
+	return scanOrganizationChange(s.db.QueryRowContext(ctx, organizationChangeSelect+" WHERE change_log.id=?", id))
+}
+
+// listOrganizationChanges returns the changes with one of the statuses, the
+// oldest pending first and the latest reviewed first.
+func (s *server) listOrganizationChanges(ctx context.Context, statuses []string) ([]organizationChange, error) {
+	args := make([]any, 0, len(statuses))
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+	rows, err := s.db.QueryContext(ctx, organizationChangeSelect+`
+		WHERE change_log.status IN (`+placeholders(len(statuses))+`)
+		ORDER BY change_log.reviewed_at IS NOT NULL, change_log.reviewed_at DESC, change_log.detected_at, change_log.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []organizationChange{}
+	for rows.Next() {
+		change, err := scanOrganizationChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, change)
+	}
+	return list, rows.Err()
+}
+
+func scanOrganizationChange(scanner rowScanner) (organizationChange, error) {
+	var (
+		change     organizationChange
+		leaderJSON []byte
+		detectedAt time.Time
+		reviewedAt sql.NullTime
+	)
+	err := scanner.Scan(
+		&change.ID,
+		&change.OrganizationID,
+		&change.OGRN,
+		&change.Organization,
+		&change.Field,
+		&change.OldValue,
+		&change.NewValue,
+		&leaderJSON,
+		&change.Status,
+		&detectedAt,
+		&change.ReviewedBy,
+		&reviewedAt,
+	)
+	if err != nil {
+		return organizationChange{}, err
+	}
+	if leaderJSON != nil {
+		change.Leader = &changeLeader{}
+		if err := json.Unmarshal(leaderJSON, change.Leader); err != nil {
+			return organizationChange{}, err
+		}
+	}
+	change.DetectedAt = detectedAt.Format(time.RFC3339)
+	if reviewedAt.Valid {
+		change.ReviewedAt = reviewedAt.Time.Format(time.RFC3339)
+	}
+	return change, nil
+}
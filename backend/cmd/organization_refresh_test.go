@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/dadata"
+)
+
+const refreshStub = `{
+	"1027700132195": [
+		{"value": "ПАО СБЕРБАНК", "data": {
+			"ogrn": "1027700132195",
+			"branch_type": "MAIN",
+			"name": {"full_with_opf": "ПУБЛИЧНОЕ АКЦИОНЕРНОЕ ОБЩЕСТВО \"СБЕРБАНК РОССИИ\"", "short_with_opf": "ПАО СБЕРБАНК"},
+			"address": {"value": "г Москва, ул Вавилова, д 19"},
+			"management": {"post": "Президент", "name": "Греф Герман Оскарович"}
+		}}
+	]
+}`
+
+func loadRefreshStub(t *testing.T) dadata.Stub {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stub.json")
+	if err := os.WriteFile(path, []byte(refreshStub), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stub, err := dadata.LoadStub(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stub
+}
+
+func TestLookupOrganizationChanges(t *testing.T) {
+	stub := loadRefreshStub(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		snapshot organizationSnapshot
+		want     []string
+	}{
+		{
+			name: "same data written differently",
+			snapshot: organizationSnapshot{
+				OGRN:         "1027700132195",
+				FullName:     "Публичное акционерное общество  \"Сбербанк России\"",
+				ShortName:    "ПАО Сбербанк",
+				LegalAddress: "117997, г. Москва, ул. Вавилова, д. 19",
+				Leader:       changeLeader{Position: "президент", LastName: "Греф", FirstName: "Герман", MiddleName: "Оскарович"},
+			},
+		},
+		{
+			name: "renamed, moved and new head",
+			snapshot: organizationSnapshot{
+				OGRN:         "1027700132195",
+				FullName:     "ОАО \"Сбербанк России\"",
+				ShortName:    "",
+				LegalAddress: "г. Москва, ул. Вавилова, д. 17",
+				Leader:       changeLeader{Position: "Президент", LastName: "Иванов", FirstName: "Иван", MiddleName: "Иванович"},
+			},
+			want: []string{organizationFieldFullName, organizationFieldShortName, organizationFieldLegalAddress, organizationFieldLeader},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, found, err := lookupOrganizationChanges(ctx, stub, tt.snapshot)
+			if err != nil || !found {
+				t.Fatalf("found=%v err=%v", found, err)
+			}
+			var fields []string
+			for _, change := range changes {
+				fields = append(fields, change.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.want) {
+				t.Fatalf("fields = %v, want %v", fields, tt.want)
+			}
+			for _, change := range changes {
+				if change.Field != organizationFieldLeader {
+					continue
+				}
+				want := changeLeader{Position: "Президент", LastName: "Греф", FirstName: "Герман", MiddleName: "Оскарович"}
+				if change.Leader == nil || *change.Leader != want {
+					t.Errorf("leader = %+v, want %+v", change.Leader, want)
+				}
+			}
+		})
+	}
+
+	if _, found, err := lookupOrganizationChanges(ctx, stub, organizationSnapshot{OGRN: "1037739010891"}); found || err != nil {
+		t.Errorf("unknown OGRN: found=%v err=%v", found, err)
+	}
+}
+
+func TestPlanOrganizationChanges(t *testing.T) {
+	changes := []organizationChange{
+		{Field: organizationFieldFullName, NewValue: "ПАО \"Ромашка\""},
+		{Field: organizationFieldShortName, NewValue: "ПАО Ромашка"},
+		{Field: organizationFieldLegalAddress, NewValue: "г. Москва, ул. Ленина, д. 1"},
+		{Field: organizationFieldLeader, NewValue: "Петров Петр Петрович"},
+	}
+	existing := []organizationChange{
+		// Same value still pending: nothing to do.
+		{ID: 1, Field: organizationFieldFullName, NewValue: "ПАО \"Ромашка\"", Status: organizationChangePending},
+		// DaData moved on: the pending change is replaced.
+		{ID: 2, Field: organizationFieldShortName, NewValue: "АО Ромашка", Status: organizationChangePending},
+		// Rejected before: not proposed again, the pending one goes away.
+		{ID: 3, Field: organizationFieldLegalAddress, NewValue: "г. Москва, ул. Ленина, д. 1", Status: organizationChangeRejected},
+		{ID: 4, Field: organizationFieldLegalAddress, NewValue: "г. Москва, ул. Ленина, д. 2", Status: organizationChangePending},
+		// Rejected with another value: the new one is proposed.
+		{ID: 5, Field: organizationFieldLeader, NewValue: "Сидоров Сидор Сидорович", Status: organizationChangeRejected},
+	}
+
+	plan := planOrganizationChanges(existing, changes)
+	if len(plan.Insert) != 1 || plan.Insert[0].Field != organizationFieldLeader {
+		t.Errorf("insert = %+v", plan.Insert)
+	}
+	if len(plan.Update) != 1 || plan.Update[0].ID != 2 || plan.Update[0].NewValue != "ПАО Ромашка" {
+		t.Errorf("update = %+v", plan.Update)
+	}
+	if !reflect.DeepEqual(plan.Delete, []int64{4}) {
+		t.Errorf("delete = %v, want [4]", plan.Delete)
+	}
+
+	// The registry agrees with DaData again: pending changes are dropped.
+	plan = planOrganizationChanges(existing, nil)
+	if len(plan.Insert) != 0 || len(plan.Update) != 0 || !reflect.DeepEqual(plan.Delete, []int64{1, 2, 4}) {
+		t.Errorf("plan without changes = %+v", plan)
+	}
+}
+
+func TestChangeLeaderRoundTrip(t *testing.T) {
+	leader := &changeLeader{Position: "Директор", LastName: "Петров", FirstName: "Петр", MiddleName: "Петрович"}
+	data, err := marshalChangeLeader(leader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded changeLeader
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded != *leader {
+		t.Fatalf("decoded = %+v err=%v", decoded, err)
+	}
+	if data, err := marshalChangeLeader(nil); data != nil || err != nil {
+		t.Fatalf("nil leader = %s err=%v, want NULL", data, err)
+	}
+}
+
+func TestDativeSurname(t *testing.T) {
+	tests := []struct {
+		lastName, patronymic, want string
+	}{
+		{"Иванов", "Иванович", "Иванову"},
+		{"Пушкин", "Сергеевич", "Пушкину"},
+		{"Достоевский", "Михайлович", "Достоевскому"},
+		{"Толстой", "Николаевич", "Толстому"},
+		{"Гоголь", "Васильевич", "Гоголю"},
+		{"Шевченко", "Григорьевич", "Шевченко"},
+		{"Дума", "Петрович", ""},
+		{"Иванова", "Петровна", "Ивановой"},
+		{"Ковалевская", "Васильевна", "Ковалевской"},
+		{"Шевчук", "Ивановна", "Шевчук"},
+		{"Петров", "", ""},
+	}
+	for _, tt := range tests {
+		if got := dativeSurname(tt.lastName, tt.patronymic); got != tt.want {
+			t.Errorf("dativeSurname(%q, %q) = %q, want %q", tt.lastName, tt.patronymic, got, tt.want)
+		}
+	}
+	leader := changeLeader{LastName: "Греф", FirstName: "Герман", MiddleName: "Оскарович"}
+	if got := initialsName(dativeSurname(leader.LastName, leader.MiddleName), leader); got != "Грефу Г.О." {
+		t.Errorf("dative initials = %q", got)
+	}
+	if got := dativeLastName(initialsName("Грефу", leader)); got != "Грефу" {
+		t.Errorf("dativeLastName = %q, want the dative stored for the act", got)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// upsertOrganization returns the registry ID of the organization with the
+// OGRN of org, creating the record on first sight. An existing record keeps
+// its data, which is edited through the organizations API; only its blank
+// fields are filled from the act. Acts without an OGRN are not linked.
+func upsertOrganization(ctx context.Context, execer sqlExecer, org organizationDTO) (sql.NullInt64, error) {
+	ogrn := strings.TrimSpace(org.Ogrn)
+	if ogrn == "" {
+		return sql.NullInt64{}, nil
+	}
+	// LAST_INSERT_ID(id) makes LastInsertId return the existing row on a
+	// duplicate OGRN.
+	result, err := execer.ExecContext(ctx, `
+		INSERT INTO organizations (full_name, short_name, ogrn, legal_address, postal_address)
+		VALUES (?, NULLIF(?, ''), ?, ?, NULLIF(?, ''))
+		ON DUPLICATE KEY UPDATE
+			id=LAST_INSERT_ID(id),
+			short_name=COALESCE(NULLIF(short_name, ''), VALUES(short_name)),
+			postal_address=COALESCE(NULLIF(postal_address, ''), VALUES(postal_address))
+	`, org.Name, org.ShortName, ogrn, org.Address.LegalAddress, org.Address.PostalAddress)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
+
+// backfillOrganizations fills the registry from acts saved before it existed,
+// taking the latest snapshot of each OGRN, and links those acts.
+func (s *server) backfillOrganizations(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO organizations (full_name, short_name, ogrn, legal_address, postal_address)
+		SELECT full_name, short_name, ogrn, legal_address, postal_address
+		FROM (
+			SELECT
+				org.organization_full_name AS full_name,
+				NULLIF(org.organization_short_name, '') AS short_name,
+				org.organization_ogrn AS ogrn,
+				org.organization_legal_address AS legal_address,
+				NULLIF(org.organization_postal_address, '') AS postal_address,
+				ROW_NUMBER() OVER (PARTITION BY org.organization_ogrn ORDER BY act.updated_at DESC, act.id DESC) AS position
+			FROM act_organization org
+			INNER JOIN act ON act.id = org.act_id
+			WHERE org.organization_id IS NULL AND org.organization_ogrn <> ''
+		) latest
+		WHERE latest.position = 1
+		ON DUPLICATE KEY UPDATE id=id
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE act_organization org
+		INNER JOIN organizations registry ON registry.ogrn = org.organization_ogrn
+		SET org.organization_id = registry.id
+		WHERE org.organization_id IS NULL
+	`)
+	return err
+}
+
+func headIsBlank(head headDTO) bool {
+	return strings.TrimSpace(head.Role+head.LastName+head.NamePatronymic+head.LastNameTo) == ""
+}
+
+// registryLeaderOn returns the leader of the registry organization in charge
+// on date (YYYY-MM-DD, today when blank) as the head of an act. An empty head
+// is returned when the registry knows no leader for that day, or no dative
+// last name for it, which is then entered by hand.
+func registryLeaderOn(ctx context.Context, tx *sql.Tx, organizationID int64, date string) (headDTO, error) {
+	var (
+		head        headDTO
+		initialsDat string
+	)
+	err := tx.QueryRowContext(ctx, `
+		SELECT position, last_name, TRIM(CONCAT(first_name, ' ', middle_name)), initials_name_dat
+		FROM leader
+		WHERE organization_id = ?
+			AND effective_from <= COALESCE(NULLIF(?, ''), CURDATE())
+			AND (effective_to IS NULL OR effective_to >= COALESCE(NULLIF(?, ''), CURDATE()))
+		ORDER BY effective_from DESC, id DESC
+		LIMIT 1
+	`, organizationID, date, date).Scan(&head.Role, &head.LastName, &head.NamePatronymic, &initialsDat)
+	if errors.Is(err, sql.ErrNoRows) {
+		return headDTO{}, nil
+	}
+	if err != nil {
+		return headDTO{}, err
+	}
+	head.LastNameTo = dativeLastName(initialsDat)
+	if head.LastNameTo == "" {
+		return headDTO{}, nil
+	}
+	return head, nil
+}
+
+// dativeLastName picks the last name out of a dative "Иванову И.И." or
+// "И.И. Иванову": the word that is not made of initials.
+func dativeLastName(initials string) string {
+	for _, word := range strings.Fields(initials) {
+		if !strings.Contains(word, ".") {
+			return word
+		}
+	}
+	return ""
+}
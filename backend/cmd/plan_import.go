@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
+)
+
+// monthStems are the shortest prefixes telling Russian month names apart in
+// any grammatical case. "ма" (май, мая) comes after "мар" so that March wins.
+var monthStems = []string{
+	"янв", "фев", "мар", "апр", "ма", "июн",
+	"июл", "авг", "сен", "окт", "ноя", "дек",
+}
+
+// planImportField maps a spreadsheet header onto a plan line field.
+type planImportField struct {
+	Field   string
+	Headers []string
+	Apply   func(payload *planItemPayload, value string, employees map[string]int) error
+}
+
+var planImportFields = []planImportField{
+	{"ogrn", []string{"ОГРН", "ОГРНИП", "ОГРН/ОГРНИП"}, func(p *planItemPayload, v string, _ map[string]int) error {
+		p.Ogrn = v
+		return nil
+	}},
+	{"organization_name", []string{"Организация", "Наименование", "Наименование организации"}, func(p *planItemPayload, v string, _ map[string]int) error {
+		p.OrganizationName = v
+		return nil
+	}},
+	{"month", []string{"Месяц", "Месяц проведения", "Срок проведения"}, func(p *planItemPayload, v string, _ map[string]int) error {
+		month, ok := parsePlanMonth(v)
+		if !ok {
+			return errors.New("Ожидается номер или название месяца")
+		}
+		p.Month = month
+		return nil
+	}},
+	{"form_type", []string{"Форма проверки", "Вид проверки"}, func(p *planItemPayload, v string, _ map[string]int) error {
+		p.FormType = strings.ToLower(v)
+		return nil
+	}},
+	{"employee_id", []string{"Ответственный", "Ответственный сотрудник", "Инспектор"}, func(p *planItemPayload, v string, employees map[string]int) error {
+		id, ok := employees[normalizeEmployeeName(v)]
+		if !ok {
+			return errors.New("Сотрудник не найден в справочнике")
+		}
+		p.EmployeeID = &id
+		return nil
+	}},
+}
+
+type planImportRow struct {
+	Line    int
+	Payload planItemPayload
+}
+
+// validatePlanItemPayload checks a plan line. employees holds the names of
+// the known employees by ID.
+func validatePlanItemPayload(payload planItemPayload, employees map[int]string) error {
+	v := validation.New()
+
+	ogrn := strings.TrimSpace(payload.Ogrn)
+	if v.Required("ogrn", ogrn, "Укажите ОГРН или ОГРНИП") && !validation.OGRN(ogrn) {
+		v.Add("ogrn", validation.CodeChecksum, "Некорректный ОГРН (13 цифр) или ОГРНИП (15 цифр)")
+	}
+	if payload.Month < 1 || payload.Month > 12 {
+		v.Add("month", validation.CodeOutOfRange, "Месяц должен быть от 1 до 12")
+	}
+	if v.Required("form_type", payload.FormType, "Укажите форму проверки") {
+		v.OneOf("form_type", payload.FormType, planFormTypes, "В план включаются только плановые проверки")
+	}
+	if payload.EmployeeID != nil {
+		if _, ok := employees[*payload.EmployeeID]; !ok {
+			v.Add("employee_id", validation.CodeInvalidValue, "Сотрудник не найден в справочнике")
+		}
+	}
+
+	return v.Err()
+}
+
+func validatePlanPayload(payload planPayload) error {
+	v := validation.New()
+	if payload.Year < 2000 || payload.Year > 2100 {
+		v.Add("year", validation.CodeOutOfRange, "Укажите год плана")
+	}
+	v.Required("title", payload.Title, "Укажите наименование плана")
+	v.Date("approved_at", payload.ApprovedAt)
+	return v.Err()
+}
+
+func (s *server) loadEmployeeNames(ctx context.Context) (map[int]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, fio FROM employees")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var (
+			id   int
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// mapPlanImportRows turns table rows into plan lines the same way
+// mapImportRows does for inspections. Employees are matched by full name.
+func mapPlanImportRows(table [][]string, employees map[int]string) ([]planImportRow, []importRowError, []string) {
+	headerLine := importHeaderLine(table)
+	if headerLine < 0 {
+		return nil, nil, nil
+	}
+
+	byName := make(map[string]int, len(employees))
+	for id, name := range employees {
+		byName[normalizeEmployeeName(name)] = id
+	}
+
+	columns := make(map[int]planImportField)
+	var unknown []string
+	for index, header := range table[headerLine] {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		field, ok := findPlanImportField(header)
+		if !ok {
+			unknown = append(unknown, header)
+			continue
+		}
+		columns[index] = field
+	}
+
+	var rows []planImportRow
+	var rowErrors []importRowError
+	for index := headerLine + 1; index < len(table); index++ {
+		raw := table[index]
+		if isBlankRow(raw) {
+			continue
+		}
+		line := index + 1
+		row := planImportRow{Line: line}
+		for column, value := range raw {
+			field, ok := columns[column]
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if err := field.Apply(&row.Payload, value, byName); err != nil {
+				rowErrors = append(rowErrors, importRowError{Row: line, Field: field.Field, Value: value, Message: err.Error()})
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, unknown
+}
+
+// validatePlanImportRows checks the mapped plan lines like
+// validateImportRows does for inspections. A field that already failed to map
+// is not reported again.
+func validatePlanImportRows(rows []planImportRow, rowErrors []importRowError, employees map[int]string) ([]planItemPayload, []importRowError) {
+	invalid := make(map[int]bool)
+	reported := make(map[string]bool)
+	for _, item := range rowErrors {
+		invalid[item.Row] = true
+		reported[strconv.Itoa(item.Row)+":"+item.Field] = true
+	}
+	var valid []planItemPayload
+	for _, row := range rows {
+		var fieldErrors validation.Errors
+		if errors.As(validatePlanItemPayload(row.Payload, employees), &fieldErrors) {
+			for _, item := range fieldErrors {
+				if reported[strconv.Itoa(row.Line)+":"+item.Field] {
+					continue
+				}
+				rowErrors = append(rowErrors, importRowError{
+					Row:     row.Line,
+					Field:   item.Field,
+					Value:   planFieldValue(row.Payload, item.Field),
+					Message: item.Message,
+				})
+			}
+			invalid[row.Line] = true
+		}
+		if !invalid[row.Line] {
+			valid = append(valid, row.Payload)
+		}
+	}
+	return valid, rowErrors
+}
+
+func findPlanImportField(header string) (planImportField, bool) {
+	normalized := normalizeImportHeader(header)
+	for _, field := range planImportFields {
+		for _, candidate := range field.Headers {
+			if normalizeImportHeader(candidate) == normalized {
+				return field, true
+			}
+		}
+	}
+	return planImportField{}, false
+}
+
+// parsePlanMonth accepts a month number, including spreadsheet numbers like
+// "3.0", or a Russian month name in any case ("март", "Марта", "мар.").
+func parsePlanMonth(value string) (int, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64); err == nil {
+		month := int(number)
+		return month, float64(month) == number && month >= 1 && month <= 12
+	}
+	for index, stem := range monthStems {
+		if strings.HasPrefix(value, stem) {
+			return index + 1, true
+		}
+	}
+	return 0, false
+}
+
+func normalizeEmployeeName(value string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(strings.ToLower(value), "ё", "е")), " ")
+}
+
+// planFieldValue returns the imported value of a plan line field for the error
+// report.
+func planFieldValue(payload planItemPayload, field string) string {
+	switch field {
+	case "ogrn":
+		return payload.Ogrn
+	case "form_type":
+		return payload.FormType
+	case "month":
+		if payload.Month == 0 {
+			return ""
+		}
+		return strconv.Itoa(payload.Month)
+	default:
+		return ""
+	}
+}
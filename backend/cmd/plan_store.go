@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
+)
+
+// planItemStatusNotStarted is the status of a plan line without an act; lines
+// with an act take the status of the inspection.
+const planItemStatusNotStarted = "not_started"
+
+// planFormTypes are the form types a plan line may have: the plan covers
+// planned inspections only.
+var planFormTypes = []string{
+	domain.InspectionPlannedDocumentary,
+	domain.InspectionPlannedOnsite,
+}
+
+var errPlanItemHasInspection = errors.New("plan item already has an inspection")
+
+type inspectionPlan struct {
+	ID         int          `json:"id"`
+	Year       int          `json:"year"`
+	Title      string       `json:"title"`
+	ApprovedAt string       `json:"approved_at"`
+	CreatedBy  string       `json:"created_by"`
+	CreatedAt  string       `json:"created_at"`
+	Progress   planProgress `json:"progress"`
+	Items      []planItem   `json:"items,omitempty"`
+}
+
+// planProgress counts plan lines by how far they got: Started lines have an
+// act, Completed ones an act whose period is over, Overdue lines are not
+// completed although their month has passed.
+type planProgress struct {
+	Total     int `json:"total"`
+	Started   int `json:"started"`
+	Completed int `json:"completed"`
+	Overdue   int `json:"overdue"`
+}
+
+type planItem struct {
+	ID               int    `json:"id"`
+	PlanID           int    `json:"plan_id"`
+	Ogrn             string `json:"ogrn"`
+	OrganizationName string `json:"organization_name"`
+	Month            int    `json:"month"`
+	FormType         string `json:"form_type"`
+	EmployeeID       *int   `json:"employee_id"`
+	EmployeeName     string `json:"employee_name"`
+	ActID            *int   `json:"act_id"`
+	Status           string `json:"status"`
+	Overdue          bool   `json:"overdue"`
+}
+
+type planPayload struct {
+	Year       int    `json:"year"`
+	Title      string `json:"title"`
+	ApprovedAt string `json:"approved_at"`
+}
+
+type planItemPayload struct {
+	Ogrn             string `json:"ogrn"`
+	OrganizationName string `json:"organization_name"`
+	Month            int    `json:"month"`
+	FormType         string `json:"form_type"`
+	EmployeeID       *int   `json:"employee_id"`
+}
+
+// planItemActExpr finds the act of a plan line: the one created from it or,
+// for inspections entered by hand, a planned act of the same organization and
+// form type started in the plan year.
+const planItemActExpr = `COALESCE(item.act_id, (
+			SELECT MIN(matched.act_id)
+			FROM act_organization matched
+			INNER JOIN act_inspection matched_insp ON matched_insp.act_id = matched.act_id
+			WHERE matched.organization_ogrn = item.organization_ogrn
+				AND matched_insp.inspection_type = item.inspection_type
+				AND YEAR(matched_insp.date_start) = plan.year
+		))`
+
+const planItemSelect = `
+		SELECT
+			lines.id,
+			lines.plan_id,
+			lines.organization_ogrn,
+			COALESCE(lines.organization_name, ''),
+			lines.planned_month,
+			lines.inspection_type,
+			lines.employee_id,
+			COALESCE(emp.fio, ''),
+			lines.plan_year,
+			lines.matched_act_id,
+			CASE WHEN lines.matched_act_id IS NULL THEN '` + planItemStatusNotStarted + `' ELSE ` + inspectionStatusExpr + ` END
+		FROM (
+			SELECT item.*, plan.year AS plan_year, ` + planItemActExpr + ` AS matched_act_id
+			FROM inspection_plan_item item
+			INNER JOIN inspection_plan plan ON plan.id = item.plan_id
+		) lines
+		LEFT JOIN employees emp ON emp.id = lines.employee_id
+		LEFT JOIN act_inspection insp ON insp.act_id = lines.matched_act_id`
+
+func (s *server) listPlans(ctx context.Context) ([]inspectionPlan, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, year, title, approved_at, COALESCE(created_by, ''), created_at
+		FROM inspection_plan
+		ORDER BY year DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []inspectionPlan{}
+	index := make(map[int]int)
+	for rows.Next() {
+		plan, err := scanPlanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[plan.ID] = len(plans)
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := s.queryPlanItems(ctx, "1=1")
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if position, ok := index[item.PlanID]; ok {
+			plans[position].Progress.add(item)
+		}
+	}
+	return plans, nil
+}
+
+func (s *server) fetchPlan(ctx context.Context, id int) (inspectionPlan, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, year, title, approved_at, COALESCE(created_by, ''), created_at
+		FROM inspection_plan
+		WHERE id=?
+	`, id)
+	plan, err := scanPlanRow(row)
+	if err != nil {
+		return inspectionPlan{}, err
+	}
+
+	plan.Items, err = s.queryPlanItems(ctx, "lines.plan_id=?", id)
+	if err != nil {
+		return inspectionPlan{}, err
+	}
+	for _, item := range plan.Items {
+		plan.Progress.add(item)
+	}
+	return plan, nil
+}
+
+func (s *server) fetchPlanItem(ctx context.Context, planID, itemID int) (planItem, error) {
+	items, err := s.queryPlanItems(ctx, "lines.plan_id=? AND lines.id=?", planID, itemID)
+	if err != nil {
+		return planItem{}, err
+	}
+	if len(items) == 0 {
+		return planItem{}, sql.ErrNoRows
+	}
+	return items[0], nil
+}
+
+func (s *server) queryPlanItems(ctx context.Context, where string, args ...any) ([]planItem, error) {
+	rows, err := s.db.QueryContext(ctx, planItemSelect+`
+		WHERE `+where+`
+		ORDER BY lines.planned_month, lines.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []planItem{}
+	now := time.Now()
+	for rows.Next() {
+		var (
+			item       planItem
+			employeeID sql.NullInt64
+			actID      sql.NullInt64
+			year       int
+		)
+		if err := rows.Scan(
+			&item.ID,
+			&item.PlanID,
+			&item.Ogrn,
+			&item.OrganizationName,
+			&item.Month,
+			&item.FormType,
+			&employeeID,
+			&item.EmployeeName,
+			&year,
+			&actID,
+			&item.Status,
+		); err != nil {
+			return nil, err
+		}
+		if employeeID.Valid {
+			id := int(employeeID.Int64)
+			item.EmployeeID = &id
+		}
+		if actID.Valid {
+			id := int(actID.Int64)
+			item.ActID = &id
+		}
+		monthEnd := time.Date(year, time.Month(item.Month)+1, 1, 0, 0, 0, 0, time.Local)
+		item.Overdue = item.Status != inspectionStatusCompleted && !now.Before(monthEnd)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (p *planProgress) add(item planItem) {
+	p.Total++
+	if item.ActID != nil {
+		p.Started++
+	}
+	if item.Status == inspectionStatusCompleted {
+		p.Completed++
+	}
+	if item.Overdue {
+		p.Overdue++
+	}
+}
+
+func scanPlanRow(scanner rowScanner) (inspectionPlan, error) {
+	var (
+		plan       inspectionPlan
+		approvedAt sql.NullTime
+		createdAt  time.Time
+	)
+	if err := scanner.Scan(&plan.ID, &plan.Year, &plan.Title, &approvedAt, &plan.CreatedBy, &createdAt); err != nil {
+		return inspectionPlan{}, err
+	}
+	plan.ApprovedAt = formatDate(approvedAt)
+	plan.CreatedAt = createdAt.Format(time.RFC3339)
+	return plan, nil
+}
+
+func (s *server) insertPlan(ctx context.Context, payload planPayload, createdBy string) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO inspection_plan (year, title, approved_at, created_by)
+		VALUES (?, ?, ?, ?)
+	`, payload.Year, payload.Title, parseDate(payload.ApprovedAt), createdBy)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *server) updatePlan(ctx context.Context, id int, payload planPayload) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE inspection_plan
+		SET year=?, title=?, approved_at=?
+		WHERE id=?
+	`, payload.Year, payload.Title, parseDate(payload.ApprovedAt), id)
+	if err != nil {
+		return false, err
+	}
+	return s.planExists(ctx, id, result)
+}
+
+// planExists tells a missing plan from an update that changed nothing, since
+// MySQL reports zero affected rows for both.
+func (s *server) planExists(ctx context.Context, id int, result sql.Result) (bool, error) {
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		return true, nil
+	}
+	var found int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM inspection_plan WHERE id=?", id).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *server) deletePlan(ctx context.Context, id int) (bool, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM inspection_plan WHERE id=?", id)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func insertPlanItemTx(ctx context.Context, execer sqlExecer, planID int, payload planItemPayload) (int64, error) {
+	result, err := execer.ExecContext(ctx, `
+		INSERT INTO inspection_plan_item (
+			plan_id,
+			organization_ogrn,
+			organization_name,
+			planned_month,
+			inspection_type,
+			employee_id
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`, planID, payload.Ogrn, payload.OrganizationName, payload.Month, payload.FormType, payload.EmployeeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (s *server) insertPlanItems(ctx context.Context, planID int, payloads []planItemPayload) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	ids := make([]int64, 0, len(payloads))
+	for _, payload := range payloads {
+		var id int64
+		id, err = insertPlanItemTx(ctx, tx, planID, payload)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (s *server) updatePlanItem(ctx context.Context, planID, itemID int, payload planItemPayload) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE inspection_plan_item
+		SET organization_ogrn=?, organization_name=?, planned_month=?, inspection_type=?, employee_id=?
+		WHERE plan_id=? AND id=?
+	`, payload.Ogrn, payload.OrganizationName, payload.Month, payload.FormType, payload.EmployeeID, planID, itemID)
+	return err
+}
+
+func (s *server) deletePlanItem(ctx context.Context, planID, itemID int) (bool, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM inspection_plan_item WHERE plan_id=? AND id=?", planID, itemID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// createInspectionFromPlanItem creates a draft for a plan line and links it
+// to the line. Organization, head and addresses are taken from the latest act
+// of the same OGRN when there is one; the responsible employee becomes the
+// inspector.
+func (s *server) createInspectionFromPlanItem(ctx context.Context, item planItem, createdBy string) (inspectionResponse, error) {
+	if item.ActID != nil {
+		return inspectionResponse{}, errPlanItemHasInspection
+	}
+
+	payload := actPayload{
+		Organization: organizationDTO{Ogrn: item.Ogrn, Name: item.OrganizationName},
+		Inspection:   inspectionDTO{FormType: item.FormType},
+	}
+	var latestID int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT act_id
+		FROM act_organization
+		WHERE organization_ogrn=?
+		ORDER BY act_id DESC
+		LIMIT 1
+	`, item.Ogrn).Scan(&latestID)
+	switch {
+	case err == nil:
+		latest, err := s.fetchInspectionByID(ctx, latestID)
+		if err != nil {
+			return inspectionResponse{}, err
+		}
+		payload = clonePayload(latest, item.FormType)
+	case !errors.Is(err, sql.ErrNoRows):
+		return inspectionResponse{}, err
+	}
+	if item.EmployeeName != "" {
+		payload.Inspection.Inspectors = []string{item.EmployeeName}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	id, err := s.insertInspectionTx(ctx, tx, payload, createdBy)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+	result, err := tx.ExecContext(ctx, `
+		UPDATE inspection_plan_item
+		SET act_id=?
+		WHERE id=? AND act_id IS NULL
+	`, id, item.ID)
+	if err != nil {
+		return inspectionResponse{}, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		err = errPlanItemHasInspection
+		return inspectionResponse{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return inspectionResponse{}, err
+	}
+	return s.fetchInspectionByID(ctx, int(id))
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/mail"
+)
+
+// reminder is one deadline of one inspection.
+type reminder struct {
+	ActID        int      `json:"act_id"`
+	Number       string   `json:"number"`
+	Organization string   `json:"organization"`
+	FormType     string   `json:"form_type"`
+	Rule         string   `json:"rule"`
+	Title        string   `json:"title"`
+	DueDate      string   `json:"due_date"`
+	Status       string   `json:"status"`
+	DaysLeft     int      `json:"days_left"`
+	Inspectors   []string `json:"inspectors"`
+	CompletedAt  string   `json:"completed_at,omitempty"`
+	CompletedBy  string   `json:"completed_by,omitempty"`
+
+	dueNotified     bool
+	overdueNotified bool
+}
+
+type deadlineMark struct {
+	completedAt     sql.NullTime
+	completedBy     string
+	dueNotified     bool
+	overdueNotified bool
+}
+
+type deadlineKey struct {
+	actID int
+	rule  string
+}
+
+// computeReminders applies the deadline rules to the dated inspections and
+// returns the deadlines sorted by due date. Nothing is stored: deadlines
+// follow the inspection dates as they are edited.
+func (s *server) computeReminders(ctx context.Context, today time.Time) ([]reminder, error) {
+	marks, err := s.loadDeadlineMarks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			act.id,
+			COALESCE(insp.inspection_number, ''),
+			insp.inspection_type,
+			COALESCE(NULLIF(org.organization_short_name, ''), org.organization_full_name),
+			insp.authorized_persons,
+			insp.date_start,
+			COALESCE(insp.date_early_end, insp.date_end, insp.date_start)
+		`+inspectionFromClause+`
+		WHERE insp.date_start IS NOT NULL
+			AND COALESCE(insp.date_early_end, insp.date_end, insp.date_start) >= ?
+		ORDER BY insp.date_start, act.id
+	`, today.AddDate(0, 0, -reminderLookbackDays(s.deadlineRules)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []reminder
+	for rows.Next() {
+		var (
+			base           reminder
+			inspectorsJSON []byte
+			start, end     time.Time
+		)
+		if err := rows.Scan(&base.ActID, &base.Number, &base.FormType, &base.Organization, &inspectorsJSON, &start, &end); err != nil {
+			return nil, err
+		}
+		if base.Inspectors, err = unmarshalStringSlice(inspectorsJSON); err != nil {
+			return nil, err
+		}
+		for _, rule := range s.deadlineRules {
+			deadline, ok := deadlineFor(rule, base.FormType, &start, &end)
+			if !ok {
+				continue
+			}
+			item := base
+			item.Rule = rule.Code
+			item.Title = rule.Title
+			item.DueDate = deadline.Format(dateLayout)
+			item.DaysLeft = int(deadline.Sub(today).Hours() / 24)
+
+			mark := marks[deadlineKey{base.ActID, rule.Code}]
+			if mark.completedAt.Valid {
+				item.CompletedAt = mark.completedAt.Time.Format(time.RFC3339)
+				item.CompletedBy = mark.completedBy
+			}
+			item.dueNotified = mark.dueNotified
+			item.overdueNotified = mark.overdueNotified
+			item.Status = reminderStatus(rule, deadline, today, mark.completedAt.Valid)
+			list = append(list, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].DueDate < list[j].DueDate
+	})
+	return list, nil
+}
+
+// reminderLookbackDays limits reminders to inspections that ended recently:
+// the longest offset of rules past the end, counted in calendar days, plus
+// the RemindDays of that rule. An overdue deadline stays listed that many
+// days after it came.
+func reminderLookbackDays(rules []deadlineRule) int {
+	longest := 0
+	for _, rule := range rules {
+		days := rule.OffsetDays
+		if rule.WorkingDays && days > 0 {
+			// Every five working days span a weekend, and a result falling
+			// on a weekend moves past one more.
+			days += 2 * (days/5 + 1)
+		}
+		if days+rule.RemindDays > longest {
+			longest = days + rule.RemindDays
+		}
+	}
+	return longest
+}
+
+func (s *server) loadDeadlineMarks(ctx context.Context) (map[deadlineKey]deadlineMark, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			act_id,
+			rule_code,
+			completed_at,
+			COALESCE(completed_by, ''),
+			due_notified_at IS NOT NULL,
+			overdue_notified_at IS NOT NULL
+		FROM deadline_status
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	marks := make(map[deadlineKey]deadlineMark)
+	for rows.Next() {
+		var (
+			key  deadlineKey
+			mark deadlineMark
+		)
+		if err := rows.Scan(&key.actID, &key.rule, &mark.completedAt, &mark.completedBy, &mark.dueNotified, &mark.overdueNotified); err != nil {
+			return nil, err
+		}
+		marks[key] = mark
+	}
+	return marks, rows.Err()
+}
+
+func (s *server) findDeadlineRule(code string) (deadlineRule, bool) {
+	for _, rule := range s.deadlineRules {
+		if rule.Code == code {
+			return rule, true
+		}
+	}
+	return deadlineRule{}, false
+}
+
+// setDeadlineCompleted marks a deadline done, or open again when done is false.
+func (s *server) setDeadlineCompleted(ctx context.Context, actID int, rule string, done bool, by string) error {
+	if !done {
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE deadline_status SET completed_at=NULL, completed_by=NULL
+			WHERE act_id=? AND rule_code=?
+		`, actID, rule)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO deadline_status (act_id, rule_code, completed_at, completed_by)
+		VALUES (?, ?, NOW(), ?)
+		ON DUPLICATE KEY UPDATE completed_at=VALUES(completed_at), completed_by=VALUES(completed_by)
+	`, actID, rule, by)
+	return err
+}
+
+// markDeadlineNotified records that the reminder for status was mailed, so the
+// scheduler sends each of "due" and "overdue" only once.
+func (s *server) markDeadlineNotified(ctx context.Context, actID int, rule, status string) error {
+	column := "due_notified_at"
+	if status == reminderStatusOverdue {
+		column = "overdue_notified_at"
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO deadline_status (act_id, rule_code, `+column+`)
+		VALUES (?, ?, NOW())
+		ON DUPLICATE KEY UPDATE `+column+`=VALUES(`+column+`)
+	`, actID, rule)
+	return err
+}
+
+type reminderRecipient struct {
+	Name  string
+	Email string
+}
+
+// reminderRecipients returns users with an email address; they get the
+// reminders of inspections where they are among the inspectors.
+func (s *server) reminderRecipients(ctx context.Context) ([]reminderRecipient, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT fio, email
+		FROM users
+		WHERE email IS NOT NULL AND email <> '' AND role <> 'no_access'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []reminderRecipient
+	for rows.Next() {
+		var item reminderRecipient
+		if err := rows.Scan(&item.Name, &item.Email); err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, rows.Err()
+}
+
+// runReminderScheduler mails due and overdue deadlines every
+// ReminderInterval until ctx is done.
+func (s *server) runReminderScheduler(ctx context.Context) {
+	if s.mailer == nil || s.config.ReminderInterval == 0 {
+		log.Println("reminder scheduler disabled")
+		return
+	}
+	ticker := time.NewTicker(s.config.ReminderInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.sendReminders(ctx, time.Now()); err != nil {
+			log.Println("reminder scheduler error:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reminderNotifySince returns the day the reminders were first sent,
+// storing today on the first run.
+func (s *server) reminderNotifySince(ctx context.Context, today time.Time) (time.Time, error) {
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO reminder_state (id, notify_since)
+		VALUES (1, ?)
+		ON DUPLICATE KEY UPDATE id=id
+	`, today); err != nil {
+		return time.Time{}, err
+	}
+	var since time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT notify_since
+		FROM reminder_state
+		WHERE id=1
+	`).Scan(&since)
+	return since, err
+}
+
+// sendReminders mails one digest per recipient with the deadlines that
+// became due or overdue since the last run. Deadlines that were already
+// overdue before the first run are not mailed. A deadline is marked notified
+// once at least one digest with it was delivered.
+func (s *server) sendReminders(ctx context.Context, now time.Time) error {
+	today := reminderDay(now, s.config.ReminderTimeZone)
+	notifySince, err := s.reminderNotifySince(ctx, today)
+	if err != nil {
+		return err
+	}
+	all, err := s.computeReminders(ctx, today)
+	if err != nil {
+		return err
+	}
+	var pending []reminder
+	for _, item := range all {
+		switch {
+		case item.Status == reminderStatusDue && !item.dueNotified:
+			pending = append(pending, item)
+		case item.Status == reminderStatusOverdue && !item.overdueNotified &&
+			item.DueDate >= notifySince.Format(dateLayout):
+			pending = append(pending, item)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	recipients, err := s.reminderRecipients(ctx)
+	if err != nil {
+		return err
+	}
+	digests := make(map[string][]reminder)
+	for _, address := range s.config.ReminderEmailTo {
+		digests[address] = pending
+	}
+	for _, recipient := range recipients {
+		name := normalizeEmployeeName(recipient.Name)
+		for _, item := range pending {
+			if hasInspector(item.Inspectors, name) {
+				digests[recipient.Email] = append(digests[recipient.Email], item)
+			}
+		}
+	}
+
+	delivered := make(map[deadlineKey]bool)
+	for address, items := range digests {
+		_, err := s.mailer.Send(ctx, mail.Message{
+			To:      []string{address},
+			Subject: fmt.Sprintf("Сроки по проверкам: %d", len(items)),
+			Body:    reminderDigest(items),
+		})
+		if err != nil {
+			log.Printf("reminder mail to %s failed: %v", address, err)
+			continue
+		}
+		for _, item := range items {
+			delivered[deadlineKey{item.ActID, item.Rule}] = true
+		}
+	}
+
+	for _, item := range pending {
+		if !delivered[deadlineKey{item.ActID, item.Rule}] {
+			continue
+		}
+		// A failed mark only repeats this deadline in the next digest.
+		if err := s.markDeadlineNotified(ctx, item.ActID, item.Rule, item.Status); err != nil {
+			log.Printf("reminder mark %d/%s failed: %v", item.ActID, item.Rule, err)
+		}
+	}
+	return nil
+}
+
+func reminderDigest(items []reminder) string {
+	var b strings.Builder
+	b.WriteString("Сроки по проверкам, требующие внимания:\n\n")
+	for _, item := range items {
+		state := "срок"
+		if item.Status == reminderStatusOverdue {
+			state = "просрочено, срок"
+		}
+		fmt.Fprintf(&b, "- %s: %s, проверка %s (%s); %s %s\n",
+			item.Title, item.Organization, formatValue(item.Number), item.FormType, state, item.DueDate)
+	}
+	return b.String()
+}
+
+// reminderDay returns the date of now in zone as a UTC midnight, the way
+// the stored dates are read.
+func reminderDay(now time.Time, zone *time.Location) time.Time {
+	local := now.In(zone)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
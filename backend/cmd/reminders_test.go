@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/mail"
+)
+
+func TestReminderDay(t *testing.T) {
+	yekaterinburg := time.FixedZone("UTC+5", 5*60*60)
+	tests := []struct {
+		name string
+		now  time.Time
+		zone *time.Location
+		want string
+	}{
+		{"evening in UTC is the next day in UTC+5", time.Date(2024, time.March, 1, 20, 30, 0, 0, time.UTC), yekaterinburg, "2024-03-02"},
+		{"early morning in UTC+5", time.Date(2024, time.March, 2, 0, 15, 0, 0, yekaterinburg), yekaterinburg, "2024-03-02"},
+		{"same instant counted in UTC", time.Date(2024, time.March, 2, 0, 15, 0, 0, yekaterinburg), time.UTC, "2024-03-01"},
+	}
+	for _, tt := range tests {
+		got := reminderDay(tt.now, tt.zone)
+		if got.Format(dateLayout) != tt.want || got.Location() != time.UTC || got.Hour() != 0 {
+			t.Errorf("%s: reminderDay = %v, want %s 00:00 UTC", tt.name, got, tt.want)
+		}
+	}
+}
+
+func date(value string) time.Time {
+	parsed, err := time.Parse(dateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func TestAddWorkingDays(t *testing.T) {
+	tests := []struct {
+		from string
+		days int
+		want string
+	}{
+		{"2024-03-07", 3, "2024-03-12"},  // Thu + 3 skips the weekend
+		{"2024-03-08", 1, "2024-03-11"},  // Fri + 1 is Monday
+		{"2024-03-09", 0, "2024-03-11"},  // a Saturday moves forward
+		{"2024-03-11", -3, "2024-03-06"}, // Mon - 3 skips the weekend
+		{"2024-03-10", 0, "2024-03-11"},  // a Sunday moves forward too
+		{"2024-03-04", 10, "2024-03-18"}, // two full weeks
+	}
+	for _, tt := range tests {
+		if got := addWorkingDays(date(tt.from), tt.days).Format(dateLayout); got != tt.want {
+			t.Errorf("addWorkingDays(%s, %d) = %s, want %s", tt.from, tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestDeadlineFor(t *testing.T) {
+	start, end := date("2024-03-04"), date("2024-03-07")
+	rules := make(map[string]deadlineRule)
+	for _, rule := range defaultDeadlineRules {
+		rules[rule.Code] = rule
+	}
+	tests := []struct {
+		rule     string
+		formType string
+		end      *time.Time
+		want     string
+	}{
+		{"notice_planned", domain.InspectionPlannedOnsite, &end, "2024-02-28"},
+		{"notice_planned", domain.InspectionUnplannedOnsite, &end, ""},
+		{"notice_unplanned", domain.InspectionUnplannedOnsite, &end, "2024-03-03"},
+		{"act", domain.InspectionUnplannedDocumentary, &end, "2024-03-12"},
+		{"act", domain.InspectionUnplannedDocumentary, nil, ""},
+		{"prescription", domain.InspectionPlannedDocumentary, &end, "2024-04-06"},
+	}
+	for _, tt := range tests {
+		deadline, ok := deadlineFor(rules[tt.rule], tt.formType, &start, tt.end)
+		got := ""
+		if ok {
+			got = deadline.Format(dateLayout)
+		}
+		if got != tt.want {
+			t.Errorf("deadlineFor(%s, %s) = %q, want %q", tt.rule, tt.formType, got, tt.want)
+		}
+	}
+}
+
+func TestReminderStatus(t *testing.T) {
+	rule := deadlineRule{RemindDays: 2}
+	deadline := date("2024-03-12")
+	tests := []struct {
+		today     string
+		completed bool
+		want      string
+	}{
+		{"2024-03-09", false, reminderStatusUpcoming},
+		{"2024-03-10", false, reminderStatusDue},
+		{"2024-03-12", false, reminderStatusDue},
+		{"2024-03-13", false, reminderStatusOverdue},
+		{"2024-03-13", true, reminderStatusDone},
+	}
+	for _, tt := range tests {
+		if got := reminderStatus(rule, deadline, date(tt.today), tt.completed); got != tt.want {
+			t.Errorf("reminderStatus on %s (completed %v) = %s, want %s", tt.today, tt.completed, got, tt.want)
+		}
+	}
+}
+
+func TestReminderLookbackDays(t *testing.T) {
+	// prescription: 30 calendar days + 7; act: 3 working days (up to 5
+	// calendar days with the weekend) + 2.
+	if got := reminderLookbackDays(defaultDeadlineRules); got != 37 {
+		t.Errorf("reminderLookbackDays(default) = %d, want 37", got)
+	}
+	rules := []deadlineRule{{OffsetDays: 10, WorkingDays: true, RemindDays: 1}}
+	if got := reminderLookbackDays(rules); got != 17 {
+		t.Errorf("reminderLookbackDays(10 working days) = %d, want 17", got)
+	}
+}
+
+// fakeSender records the delivered messages and fails for the addresses in
+// fail.
+type fakeSender struct {
+	fail map[string]bool
+	sent []mail.Message
+}
+
+func (f *fakeSender) Send(_ context.Context, msg mail.Message) (string, error) {
+	if f.fail[msg.To[0]] {
+		return "", errors.New("mailbox unavailable")
+	}
+	f.sent = append(f.sent, msg)
+	return "<id>", nil
+}
+
+func TestSendReminders(t *testing.T) {
+	inspection := func(id int, number, end, inspector string) []driver.Value {
+		return []driver.Value{int64(id), number, domain.InspectionUnplannedDocumentary, "ООО Ромашка",
+			[]byte(`["` + inspector + `"]`), date(end).AddDate(0, 0, -3), date(end)}
+	}
+	db, fake := newFakeDB(t,
+		fakeResult{
+			match:   "insp.date_start IS NOT NULL",
+			columns: []string{"id", "number", "type", "organization", "inspectors", "start", "end"},
+			rows: [][]driver.Value{
+				// act due on 2024-03-12
+				inspection(1, "1/2024", "2024-03-07", "Иванов Иван Иванович"),
+				// act overdue since 2024-02-23, before the first run
+				inspection(2, "2/2024", "2024-02-20", "Иванов Иван Иванович"),
+				// act overdue since 2024-03-07, after the first run
+				inspection(3, "3/2024", "2024-03-04", "Петрова Анна Сергеевна"),
+				// act overdue since 2024-03-07, already mailed
+				inspection(4, "4/2024", "2024-03-04", "Иванов Иван Иванович"),
+			},
+		},
+		fakeResult{
+			match:   "FROM deadline_status",
+			columns: []string{"act_id", "rule_code", "completed_at", "completed_by", "due", "overdue"},
+			rows:    [][]driver.Value{{int64(4), "act", nil, "", false, true}},
+		},
+		fakeResult{
+			match:   "FROM reminder_state",
+			columns: []string{"notify_since"},
+			rows:    [][]driver.Value{{date("2024-03-01")}},
+		},
+		fakeResult{
+			match:   "FROM users",
+			columns: []string{"fio", "email"},
+			rows: [][]driver.Value{
+				{"Иванов Иван Иванович", "ivanov@example.com"},
+				{"Петрова Анна Сергеевна", "petrova@example.com"},
+			},
+		},
+	)
+	sender := &fakeSender{fail: map[string]bool{"petrova@example.com": true}}
+	srv := &server{
+		db:            db,
+		config:        serverConfig{ReminderTimeZone: time.UTC},
+		mailer:        sender,
+		deadlineRules: defaultDeadlineRules,
+	}
+
+	if err := srv.sendReminders(context.Background(), time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sender.sent) != 1 || sender.sent[0].To[0] != "ivanov@example.com" {
+		t.Fatalf("sent %+v, want one digest to ivanov@example.com", sender.sent)
+	}
+	body := sender.sent[0].Body
+	if !strings.Contains(body, "проверка 1/2024") {
+		t.Errorf("digest misses the due act:\n%s", body)
+	}
+	for _, number := range []string{"2/2024", "4/2024"} {
+		if strings.Contains(body, number) {
+			t.Errorf("digest mails %s again or from before the first run:\n%s", number, body)
+		}
+	}
+
+	marks := fake.executed("INSERT INTO deadline_status")
+	if len(marks) != 1 || marks[0].args[0] != int64(1) || !strings.Contains(marks[0].query, "due_notified_at") {
+		t.Errorf("marked %+v, want the due act of inspection 1 only", marks)
+	}
+
+	lookback := fake.queried("insp.date_start IS NOT NULL")
+	if len(lookback) != 1 || !lookback[0].args[0].(time.Time).Equal(date("2024-02-03")) {
+		t.Errorf("lookback query %+v, want inspections ended since 2024-02-03", lookback)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import "context"
+
+// schemaStep brings one table, column, index or constraint of
+// mysql/init.sql to a database created before it. Every step checks the
+// schema first, so a migration interrupted halfway can run again.
+type schemaStep func(s *server, ctx context.Context) error
+
+// schemaMigration applies steps in order.
+func schemaMigration(steps ...schemaStep) func(s *server, ctx context.Context) error {
+	return func(s *server, ctx context.Context) error {
+		for _, step := range steps {
+			if err := step(s, ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func createTable(statement string) schemaStep {
+	return func(s *server, ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, statement)
+		return err
+	}
+}
+
+func addColumn(table, column, definition string) schemaStep {
+	return alterIfMissing(table, `
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?
+	`, column, "ADD COLUMN "+column+" "+definition)
+}
+
+func addIndex(table, index, definition string) schemaStep {
+	return alterIfMissing(table, `
+		SELECT COUNT(*) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?
+	`, index, "ADD INDEX "+index+" "+definition)
+}
+
+func addConstraint(table, constraint, definition string) schemaStep {
+	return alterIfMissing(table, `
+		SELECT COUNT(*) FROM information_schema.TABLE_CONSTRAINTS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = ?
+	`, constraint, "ADD CONSTRAINT "+constraint+" "+definition)
+}
+
+// alterIfMissing runs ALTER TABLE table change unless check counts name in
+// the schema already: MySQL 8.0 has no ADD ... IF NOT EXISTS.
+func alterIfMissing(table, check, name, change string) schemaStep {
+	return func(s *server, ctx context.Context) error {
+		var count int
+		if err := s.db.QueryRowContext(ctx, check, table, name).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		_, err := s.db.ExecContext(ctx, "ALTER TABLE "+table+" "+change)
+		return err
+	}
+}
+
+// The schema migrations repeat the statements of mysql/init.sql, which
+// creates new databases; a schema change goes to both.
+var (
+	migrateActCloneSource = schemaMigration(
+		addColumn("act", "source_act_id", "INT NULL AFTER id"),
+		addConstraint("act", "fk_act_source", "FOREIGN KEY (source_act_id) REFERENCES act(id) ON DELETE SET NULL"),
+	)
+
+	migrateOrganizationRegistry = schemaMigration(
+		createTable(`
+			CREATE TABLE IF NOT EXISTS organizations (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				full_name TEXT NOT NULL,
+				short_name TEXT,
+				ogrn VARCHAR(15) NOT NULL UNIQUE,
+				legal_address TEXT NOT NULL,
+				postal_address TEXT,
+
+				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+			)
+		`),
+		createTable(`
+			CREATE TABLE IF NOT EXISTS leader (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				organization_id INT NOT NULL,
+
+				position VARCHAR(255) NOT NULL,
+				last_name VARCHAR(100) NOT NULL,
+				first_name VARCHAR(100) NOT NULL,
+				middle_name VARCHAR(100) NOT NULL DEFAULT '',
+				initials_name_im VARCHAR(150) NOT NULL DEFAULT '',
+				initials_name_dat VARCHAR(150) NOT NULL DEFAULT '',
+
+				effective_from DATE NOT NULL,
+				effective_to DATE NULL,
+
+				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+				INDEX idx_leader_term (organization_id, effective_from),
+				CONSTRAINT fk_leader_organization
+					FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE RESTRICT
+			)
+		`),
+		createTable(`
+			CREATE TABLE IF NOT EXISTS organization_merge (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				target_id INT NOT NULL,
+				sources JSON NOT NULL,
+				merged_by VARCHAR(255) NOT NULL,
+				merged_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				undone_by VARCHAR(255),
+				undone_at DATETIME NULL
+			)
+		`),
+		createTable(`
+			CREATE TABLE IF NOT EXISTS organization_change (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				organization_id INT NOT NULL,
+				field VARCHAR(32) NOT NULL,
+				old_value TEXT NOT NULL,
+				new_value TEXT NOT NULL,
+				leader JSON NULL,
+				status VARCHAR(16) NOT NULL DEFAULT 'pending',
+				detected_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				reviewed_by VARCHAR(255),
+				reviewed_at DATETIME NULL,
+
+				INDEX idx_organization_change (organization_id, field, status),
+				INDEX idx_organization_change_status (status),
+				CONSTRAINT fk_organization_change
+					FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+			)
+		`),
+		addColumn("act_organization", "organization_id", "INT NULL AFTER act_id"),
+		addIndex("act_organization", "idx_act_organization_ogrn", "(organization_ogrn)"),
+		addConstraint("act_organization", "fk_act_organization_registry",
+			"FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE SET NULL"),
+	)
+
+	migrateActSearch = schemaMigration(
+		createTable(`
+			CREATE TABLE IF NOT EXISTS act_search (
+				act_id INT PRIMARY KEY,
+
+				content TEXT NOT NULL,
+
+				FULLTEXT INDEX ft_act_search_content (content) WITH PARSER ngram,
+
+				CONSTRAINT fk_act_search
+					FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+			)
+		`),
+	)
+
+	migrateInspectionAddressesRaw = schemaMigration(
+		addColumn("act_inspection", "addresses_raw", "JSON NULL AFTER addresses"),
+	)
+
+	migrateFindingsAndDocumentSends = schemaMigration(
+		createTable(`
+			CREATE TABLE IF NOT EXISTS inspection_finding (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				act_id INT NOT NULL,
+				kind ENUM('нарушение', 'недостаток') NOT NULL,
+				area VARCHAR(255) NOT NULL,
+				item VARCHAR(512) NOT NULL DEFAULT '',
+				description TEXT,
+
+				INDEX idx_inspection_finding_act (act_id),
+				INDEX idx_inspection_finding_area (area, item),
+				CONSTRAINT fk_inspection_finding_act
+					FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+			)
+		`),
+		createTable(`
+			CREATE TABLE IF NOT EXISTS document_send (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				act_id INT NOT NULL,
+				document_kind VARCHAR(32) NOT NULL,
+				recipient VARCHAR(255) NOT NULL,
+				subject TEXT NOT NULL,
+				status ENUM('sent', 'failed') NOT NULL,
+				message_id VARCHAR(255) NULL,
+				error TEXT NULL,
+				sent_by TEXT,
+				sent_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+				INDEX idx_document_send_act (act_id, sent_at),
+				CONSTRAINT fk_document_send_act
+					FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+			)
+		`),
+	)
+
+	migrateUserCalendarAndEmail = schemaMigration(
+		addColumn("users", "calendar_token", "VARCHAR(64) NULL UNIQUE AFTER role"),
+		addColumn("users", "email", "VARCHAR(255) NULL AFTER calendar_token"),
+	)
+
+	migrateDeadlines = schemaMigration(
+		createTable(`
+			CREATE TABLE IF NOT EXISTS deadline_status (
+				act_id INT NOT NULL,
+				rule_code VARCHAR(64) NOT NULL,
+
+				completed_at DATETIME NULL,
+				completed_by TEXT,
+				due_notified_at DATETIME NULL,
+				overdue_notified_at DATETIME NULL,
+
+				PRIMARY KEY (act_id, rule_code),
+				CONSTRAINT fk_deadline_status_act
+					FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+			)
+		`),
+		createTable(`
+			CREATE TABLE IF NOT EXISTS reminder_state (
+				id INT PRIMARY KEY,
+				notify_since DATE NOT NULL
+			)
+		`),
+	)
+
+	migrateInspectionPlan = schemaMigration(
+		createTable(`
+			CREATE TABLE IF NOT EXISTS inspection_plan (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				year INT NOT NULL UNIQUE,
+				title TEXT NOT NULL,
+				approved_at DATETIME NULL,
+
+				created_by TEXT,
+				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+			)
+		`),
+		createTable(`
+			CREATE TABLE IF NOT EXISTS inspection_plan_item (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				plan_id INT NOT NULL,
+
+				organization_ogrn VARCHAR(15) NOT NULL,
+				organization_name TEXT,
+				planned_month TINYINT NOT NULL,
+				inspection_type ENUM(
+					'плановая документарная',
+					'плановая выездная'
+				) NOT NULL,
+				employee_id INT NULL,
+
+				act_id INT NULL,
+
+				INDEX idx_plan_item_ogrn (organization_ogrn),
+
+				CONSTRAINT fk_plan_item_plan
+					FOREIGN KEY (plan_id) REFERENCES inspection_plan(id) ON DELETE CASCADE,
+				CONSTRAINT fk_plan_item_employee
+					FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE SET NULL,
+				CONSTRAINT fk_plan_item_act
+					FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE SET NULL
+			)
+		`),
+	)
+
+	migrateDadataCache = schemaMigration(
+		createTable(`
+			CREATE TABLE IF NOT EXISTS dadata_cache (
+				cache_key VARCHAR(255) PRIMARY KEY,
+				response MEDIUMBLOB NOT NULL,
+				fetched_at DATETIME NOT NULL
+			)
+		`),
+	)
+
+	migrateIdempotencyKeys = schemaMigration(
+		createTable(`
+			CREATE TABLE IF NOT EXISTS idempotency_key (
+				scope VARCHAR(255) NOT NULL,
+				idem_key VARCHAR(255) NOT NULL,
+				route VARCHAR(255) NOT NULL,
+				request_hash CHAR(64) NOT NULL,
+				response_status SMALLINT NULL,
+				response_headers JSON,
+				response_body LONGBLOB,
+				created_at DATETIME NOT NULL,
+
+				PRIMARY KEY (scope, idem_key, route),
+				INDEX idx_idempotency_key_created (created_at)
+			)
+		`),
+	)
+)
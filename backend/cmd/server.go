@@ -3,20 +3,81 @@ package main
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"net/http"
 	"sync"
+
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/dadata"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/mail"
 )
 
 type server struct {
-	db       *sql.DB
-	sessions *sessionStore
+	db            *sql.DB
+	sessions      *sessionStore
+	importReports *importReportStore
+	config        serverConfig
+	// mailer is nil when SMTP is not configured.
+	mailer        mail.Sender
+	deadlineRules []deadlineRule
+	// dadata is nil when the DaData keys are not configured.
+	dadata *dadata.Client
+	// parties serves the organization refresh: the DaData client or a stub,
+	// nil when there is neither.
+	parties partyFinder
+	// refreshing is held while the organization refresh runs.
+	refreshing sync.Mutex
+	// idempotency keeps the Idempotency-Key responses of the idempotent
+	// routes.
+	idempotency idempotencyStore
 }
 
-func newServer(db *sql.DB) *server {
-	return &server{
-		db:       db,
-		sessions: newSessionStore(),
+func newServer(db *sql.DB, config serverConfig) *server {
+	srv := &server{
+		db:            db,
+		sessions:      newSessionStore(),
+		importReports: newImportReportStore(),
+		config:        config,
+		deadlineRules: defaultDeadlineRules,
+		idempotency:   sqlIdempotencyStore{db: db, ttl: config.IdempotencyTTL},
+	}
+	if config.SMTP.Host != "" {
+		srv.mailer = mail.NewSMTPSender(config.SMTP)
+	}
+	if config.Dadata.APIKey != "" && config.Dadata.Secret != "" {
+		client, err := dadata.NewClient(config.Dadata, newDadataCache(db, config))
+		if err != nil {
+			log.Println("dadata client error:", err)
+		}
+		srv.dadata = client
 	}
+	switch {
+	case config.DadataStubFile != "":
+		stub, err := dadata.LoadStub(config.DadataStubFile)
+		if err != nil {
+			log.Println("dadata stub error:", err)
+			break
+		}
+		srv.parties = stub
+	case srv.dadata != nil:
+		srv.parties = srv.dadata
+	}
+	return srv
+}
+
+func newDadataCache(db *sql.DB, config serverConfig) dadata.Cache {
+	switch config.DadataCache {
+	case dadataCacheMySQL:
+		return dadata.NewMySQLCache(db, config.DadataCacheTTL)
+	case dadataCacheFile:
+		cache, err := dadata.NewFileCache(config.DadataCacheDir, config.DadataCacheTTL)
+		if err != nil {
+			log.Println("dadata cache disabled:", err)
+			return nil
+		}
+		return cache
+	}
+	return nil
 }
 
 type sessionStore struct {
@@ -63,7 +124,7 @@ func (s *server) withAuth(next func(http.ResponseWriter, *http.Request, user)) h
 	return func(w http.ResponseWriter, r *http.Request) {
 		authUser, err := s.userFromRequest(r)
 		if err != nil {
-			writeError(w, http.StatusUnauthorized, "Необходима авторизация")
+			writeError(w, apierror.ErrUnauthorized)
 			return
 		}
 		next(w, r, authUser)
@@ -73,13 +134,27 @@ func (s *server) withAuth(next func(http.ResponseWriter, *http.Request, user)) h
 func (s *server) requireAdmin(next func(http.ResponseWriter, *http.Request, user)) func(http.ResponseWriter, *http.Request, user) {
 	return func(w http.ResponseWriter, r *http.Request, authUser user) {
 		if authUser.Role != "admin" {
-			writeError(w, http.StatusForbidden, "Недостаточно прав")
+			writeError(w, apierror.ErrForbidden)
 			return
 		}
 		next(w, r, authUser)
 	}
 }
 
+// authenticated and adminOnly adapt the session checks to handlers that do
+// not need the user, such as those of the organization registry.
+func (s *server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return s.withAuth(func(w http.ResponseWriter, r *http.Request, authUser user) {
+		next(w, r)
+	})
+}
+
+func (s *server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return s.withAuth(s.requireAdmin(func(w http.ResponseWriter, r *http.Request, authUser user) {
+		next(w, r)
+	}))
+}
+
 func (s *server) userFromRequest(r *http.Request) (user, error) {
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
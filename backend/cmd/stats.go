@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// statsWorkingDaysExpr counts the working days from the start to the
+// effective end, both inclusive, in SQL: five per whole week plus the
+// remainder looked up by the weekdays of both ends (WEEKDAY: 0 = Monday).
+// It is always counted from the dates: duration_work_days may hold a value
+// typed by hand, e.g. without public holidays.
+const statsWorkingDaysExpr = `(5 * (DATEDIFF(COALESCE(insp.date_early_end, insp.date_end, insp.date_start), insp.date_start) DIV 7)
+			+ MID('1234555512344445123333451222234511112345001234550',
+				7 * WEEKDAY(insp.date_start) + WEEKDAY(COALESCE(insp.date_early_end, insp.date_end, insp.date_start)) + 1, 1))`
+
+// statsGroup describes one breakdown of the statistics: Key groups the
+// inspections and Label, when set, gives the group a readable name.
+type statsGroup struct {
+	Key    string
+	Label  string
+	Join   string
+	Filter string
+	Order  string
+}
+
+var statsGroups = map[string]statsGroup{
+	"form_type": {
+		Key:   "insp.inspection_type",
+		Order: "inspections_count DESC, group_key",
+	},
+	"month": {
+		Key:   "DATE_FORMAT(insp.date_start, '%Y-%m')",
+		Order: "group_key",
+	},
+	"status": {
+		Key:   inspectionStatusExpr,
+		Order: "inspections_count DESC, group_key",
+	},
+	// authorized_persons is a JSON array; JSON_TABLE gives a row per
+	// inspector, so an inspection is counted for each of its inspectors.
+	"inspector": {
+		Key: "TRIM(person.name)",
+		Join: `INNER JOIN JSON_TABLE(insp.authorized_persons, '$[*]'
+			COLUMNS (name VARCHAR(255) PATH '$')) person`,
+		Filter: "TRIM(person.name) <> ''",
+		Order:  "inspections_count DESC, group_key",
+	},
+	"organization": {
+		Key:   "org.organization_ogrn",
+		Label: "MAX(COALESCE(NULLIF(org.organization_short_name, ''), org.organization_full_name))",
+		Order: "inspections_count DESC, group_key",
+	},
+}
+
+// statsGroupOrder is the order of the breakdowns in the full report.
+var statsGroupOrder = []string{"form_type", "month", "status", "inspector", "organization"}
+
+type statsQuery struct {
+	From     time.Time
+	To       time.Time
+	FormType string
+	// Limit caps the number of buckets of a group; zero means no limit.
+	Limit int
+}
+
+type statsBucket struct {
+	Key            string   `json:"key"`
+	Label          string   `json:"label,omitempty"`
+	Count          int      `json:"count"`
+	Completed      int      `json:"completed"`
+	AvgWorkingDays *float64 `json:"avg_working_days"`
+}
+
+type statsTotals struct {
+	Count          int      `json:"count"`
+	Completed      int      `json:"completed"`
+	AvgWorkingDays *float64 `json:"avg_working_days"`
+}
+
+type statsResponse struct {
+	From   string                   `json:"from"`
+	To     string                   `json:"to"`
+	Totals statsTotals              `json:"totals"`
+	Groups map[string][]statsBucket `json:"groups"`
+}
+
+// statsWhere selects the inspections started within the range. Drafts have
+// no start date and never count.
+func statsWhere(query statsQuery) (string, []any) {
+	where := `WHERE insp.date_start IS NOT NULL AND insp.date_start >= ? AND insp.date_start < ?`
+	args := []any{query.From, query.To.AddDate(0, 0, 1)}
+	if query.FormType != "" {
+		where += " AND insp.inspection_type = ?"
+		args = append(args, query.FormType)
+	}
+	return where, args
+}
+
+const statsAggregates = `
+			COUNT(DISTINCT act.id) AS inspections_count,
+			COUNT(DISTINCT CASE WHEN ` + inspectionStatusExpr + ` = 'completed' THEN act.id END),
+			AVG(` + statsWorkingDaysExpr + `)`
+
+func (s *server) statsTotals(ctx context.Context, query statsQuery) (statsTotals, error) {
+	where, args := statsWhere(query)
+	var (
+		totals statsTotals
+		avg    sql.NullFloat64
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+statsAggregates+`
+		`+inspectionFromClause+`
+		`+where, args...).Scan(&totals.Count, &totals.Completed, &avg)
+	if err != nil {
+		return statsTotals{}, err
+	}
+	totals.AvgWorkingDays = roundedAverage(avg)
+	return totals, nil
+}
+
+func (s *server) statsBuckets(ctx context.Context, group statsGroup, query statsQuery) ([]statsBucket, error) {
+	where, args := statsWhere(query)
+	if group.Filter != "" {
+		where += " AND " + group.Filter
+	}
+	label := "''"
+	if group.Label != "" {
+		label = group.Label
+	}
+	sqlQuery := `
+		SELECT
+			` + group.Key + ` AS group_key,
+			` + label + `,` + statsAggregates + `
+		` + inspectionFromClause + `
+		` + group.Join + `
+		` + where + `
+		GROUP BY group_key
+		ORDER BY ` + group.Order
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []statsBucket{}
+	for rows.Next() {
+		var (
+			item statsBucket
+			key  sql.NullString
+			avg  sql.NullFloat64
+		)
+		if err := rows.Scan(&key, &item.Label, &item.Count, &item.Completed, &avg); err != nil {
+			return nil, err
+		}
+		item.Key = key.String
+		item.AvgWorkingDays = roundedAverage(avg)
+		buckets = append(buckets, item)
+	}
+	return buckets, rows.Err()
+}
+
+// roundedAverage keeps one decimal place; AVG of no rows is NULL.
+func roundedAverage(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	rounded := float64(int64(value.Float64*10+0.5)) / 10
+	return &rounded
+}
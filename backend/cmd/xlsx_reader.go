@@ -0,0 +1,195 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errXLSXNoSheets = errors.New("xlsx: workbook has no sheets")
+
+// readXLSXRows returns the cell values of the first worksheet. Shared and
+// inline strings are resolved, numbers are returned as written in the file;
+// empty cells in the middle of a row are kept as "".
+func readXLSXRows(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := xlsxFirstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+	sharedStrings, err := xlsxSharedStrings(files)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetFile, ok := files[sheetPath]
+	if !ok {
+		return nil, errXLSXNoSheets
+	}
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline struct {
+					Text string `xml:"t"`
+					Runs []struct {
+						Text string `xml:"t"`
+					} `xml:"r"`
+				} `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xlsxDecode(sheetFile, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var values []string
+		for position, cell := range row.Cells {
+			column := position
+			if cell.Ref != "" {
+				column = xlsxColumnIndex(cell.Ref)
+			}
+			for len(values) < column {
+				values = append(values, "")
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(strings.TrimSpace(cell.Value))
+				if err != nil || index < 0 || index >= len(sharedStrings) {
+					return nil, errors.New("xlsx: invalid shared string reference")
+				}
+				value = sharedStrings[index]
+			case "inlineStr":
+				value = cell.Inline.Text
+				for _, run := range cell.Inline.Runs {
+					value += run.Text
+				}
+			}
+			values = append(values, value)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+func xlsxFirstSheetPath(files map[string]*zip.File) (string, error) {
+	workbookFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errXLSXNoSheets
+	}
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xlsxDecode(workbookFile, &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errXLSXNoSheets
+	}
+
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xlsxDecode(relsFile, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", errXLSXNoSheets
+}
+
+func xlsxSharedStrings(files map[string]*zip.File) ([]string, error) {
+	file, ok := files["xl/sharedStrings.xml"]
+	if !ok {
+		return nil, nil
+	}
+	var table struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xlsxDecode(file, &table); err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, len(table.Items))
+	for _, item := range table.Items {
+		value := item.Text
+		for _, run := range item.Runs {
+			value += run.Text
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func xlsxDecode(file *zip.File, target any) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return xml.NewDecoder(io.LimitReader(reader, 64<<20)).Decode(target)
+}
+
+// xlsxColumnIndex converts a cell reference such as "AB12" to a zero-based
+// column index.
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		index = index*26 + int(ch-'A'+1)
+	}
+	return index - 1
+}
+
+// xlsxSerialToDate converts a spreadsheet date serial to a calendar date.
+func xlsxSerialToDate(value string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || serial <= 0 || serial > 2958465 {
+		return time.Time{}, false
+	}
+	days := math.Floor(serial)
+	return xlsxEpoch.AddDate(0, 0, int(days)), true
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestXLSXWriteThenRead(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newXLSXStreamWriter(&buf, `Реестр "проверок"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]xlsxCell{
+		{xlsxString("ОГРН"), xlsxString("Организация"), xlsxString("Дата начала"), xlsxString("Рабочих дней")},
+		{xlsxString("1027700132195"), xlsxString(`ООО "Ромашка" & <сыновья>`), xlsxDate("2024-03-01"), xlsxNumber(20)},
+		{xlsxString(""), xlsxString("пропуск в начале"), xlsxDate(""), xlsxNumber(1.5)},
+		{},
+		{xlsxString("  пробелы  "), xlsxString("строка\nвторая")},
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readXLSXRows(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"ОГРН", "Организация", "Дата начала", "Рабочих дней"},
+		{"1027700132195", `ООО "Ромашка" & <сыновья>`, "45352", "20"},
+		{"", "пропуск в начале", "", "1.5"},
+		nil,
+		{"  пробелы  ", "строка\nвторая"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows = %q, want %q", got, want)
+	}
+
+	date, ok := xlsxSerialToDate(got[1][2])
+	if !ok || !date.Equal(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("xlsxSerialToDate(%q) = %v, %v, want 2024-03-01", got[1][2], date, ok)
+	}
+}
+
+func TestXLSXReadRejectsGarbage(t *testing.T) {
+	if _, err := readXLSXRows([]byte("not a zip")); err == nil {
+		t.Error("readXLSXRows accepted a file that is not a workbook")
+	}
+}
+
+func TestXLSXColumns(t *testing.T) {
+	for _, tt := range []struct {
+		index int
+		name  string
+	}{{0, "A"}, {25, "Z"}, {26, "AA"}, {27, "AB"}, {701, "ZZ"}, {702, "AAA"}} {
+		if got := xlsxColumnName(tt.index); got != tt.name {
+			t.Errorf("xlsxColumnName(%d) = %q, want %q", tt.index, got, tt.name)
+		}
+		if got := xlsxColumnIndex(tt.name + "12"); got != tt.index {
+			t.Errorf("xlsxColumnIndex(%q) = %d, want %d", tt.name+"12", got, tt.index)
+		}
+	}
+}
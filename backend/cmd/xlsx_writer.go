@@ -0,0 +1,191 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// xlsxEpoch is the zero point of spreadsheet date serials (1900 date system).
+var xlsxEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+type xlsxCellKind int
+
+const (
+	xlsxCellString xlsxCellKind = iota
+	xlsxCellNumber
+	xlsxCellDate
+)
+
+type xlsxCell struct {
+	Kind   xlsxCellKind
+	String string
+	Number float64
+	Date   time.Time
+}
+
+func xlsxString(value string) xlsxCell {
+	return xlsxCell{Kind: xlsxCellString, String: value}
+}
+
+func xlsxNumber(value float64) xlsxCell {
+	return xlsxCell{Kind: xlsxCellNumber, Number: value}
+}
+
+// xlsxDate returns a date cell for an ISO date string and an empty cell when
+// the value is blank or cannot be parsed.
+func xlsxDate(value string) xlsxCell {
+	parsed := parseDate(value)
+	if parsed == nil {
+		return xlsxString("")
+	}
+	return xlsxCell{Kind: xlsxCellDate, Date: *parsed}
+}
+
+// xlsxStreamWriter writes a single-sheet workbook straight into w. Rows are
+// encoded as they arrive with inline strings, so nothing but the current row
+// is kept in memory.
+type xlsxStreamWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+func newXLSXStreamWriter(w io.Writer, sheetName string) (*xlsxStreamWriter, error) {
+	zipWriter := zip.NewWriter(w)
+	files := []struct {
+		name     string
+		contents string
+	}{
+		{"[Content_Types].xml", xlsxContentTypesXML},
+		{"_rels/.rels", xlsxRootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbookXML, escapeXMLAttr(sheetName))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelsXML},
+		{"xl/styles.xml", xlsxStylesXML},
+	}
+	for _, file := range files {
+		if err := addDocxFile(zipWriter, file.name, file.contents); err != nil {
+			return nil, err
+		}
+	}
+
+	sheetWriter, err := zipWriter.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(sheetWriter)
+	if _, err := sheet.WriteString(xlsxSheetHeaderXML); err != nil {
+		return nil, err
+	}
+	return &xlsxStreamWriter{zip: zipWriter, sheet: sheet}, nil
+}
+
+func (x *xlsxStreamWriter) WriteRow(cells []xlsxCell) error {
+	x.row++
+	var builder strings.Builder
+	fmt.Fprintf(&builder, `<row r="%d">`, x.row)
+	for index, cell := range cells {
+		ref := xlsxColumnName(index) + strconv.Itoa(x.row)
+		switch cell.Kind {
+		case xlsxCellNumber:
+			fmt.Fprintf(&builder, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(cell.Number, 'f', -1, 64))
+		case xlsxCellDate:
+			serial := cell.Date.Sub(xlsxEpoch).Hours() / 24
+			fmt.Fprintf(&builder, `<c r="%s" s="1"><v>%s</v></c>`, ref, strconv.FormatFloat(serial, 'f', -1, 64))
+		default:
+			if cell.String == "" {
+				continue
+			}
+			fmt.Fprintf(&builder, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := writeEscapedXML(&builder, cell.String); err != nil {
+				return err
+			}
+			builder.WriteString(`</t></is></c>`)
+		}
+	}
+	builder.WriteString(`</row>`)
+	_, err := x.sheet.WriteString(builder.String())
+	return err
+}
+
+func (x *xlsxStreamWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetFooterXML); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// xlsxColumnName converts a zero-based column index to A, B, ..., Z, AA, ...
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func escapeXMLAttr(value string) string {
+	var builder strings.Builder
+	_ = writeEscapedXML(&builder, value)
+	return builder.String()
+}
+
+const xlsxContentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="xml" ContentType="application/xml"/>
+  <Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+  <Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+  <Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>
+`
+
+const xlsxRootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>
+`
+
+const xlsxWorkbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets>
+    <sheet name="%s" sheetId="1" r:id="rId1"/>
+  </sheets>
+</workbook>
+`
+
+const xlsxWorkbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>
+`
+
+// Style 1 is the built-in dd.mm.yyyy date format (numFmtId 14).
+const xlsxStylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>
+  <fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+  <borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+  <cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+  <cellXfs count="2">
+    <xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+    <xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+  </cellXfs>
+</styleSheet>
+`
+
+const xlsxSheetHeaderXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetFooterXML = `</sheetData></worksheet>`
@@ -0,0 +1,110 @@
+// Package address brings the addresses of inspected premises to one written
+// form: no postal code, "г.", "ул." and "д." with a dot and a space, parts
+// separated by ", ".
+package address
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// postalIndex is a Russian postal code at the start of an address part.
+var postalIndex = regexp.MustCompile(`^\d{6}(\s+|$)`)
+
+// gluedType finds an abbreviation glued to the name: "г.Москва", "д.5".
+var gluedType = regexp.MustCompile(`(?i)(^|\s)(г|ул|д)\.(\S+)`)
+
+// compoundType is the rest of a compound abbreviation such as "г.о." or
+// "г.п.", which must stay glued.
+var compoundType = regexp.MustCompile(`^\pL\.`)
+
+// types maps the spellings of an address element type to its canonical
+// abbreviation. House types are only recognized before a number.
+var types = map[string]string{
+	"г":     "г.",
+	"гор":   "г.",
+	"город": "г.",
+	"ул":    "ул.",
+	"улица": "ул.",
+}
+
+var houseTypes = map[string]string{
+	"д":   "д.",
+	"дом": "д.",
+}
+
+// Normalize returns the address without the postal code and with the type
+// abbreviations written the same way. It returns "" for a blank address.
+func Normalize(value string) string {
+	var parts []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.Join(strings.Fields(part), " ")
+		part = strings.TrimSpace(postalIndex.ReplaceAllString(part, ""))
+		if part == "" {
+			continue
+		}
+		parts = append(parts, normalizeTypes(part))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func normalizeTypes(part string) string {
+	part = splitGluedTypes(part)
+	words := strings.Fields(part)
+	// The last word is a name, never a type.
+	for i := 0; i < len(words)-1; i++ {
+		key := strings.ToLower(strings.TrimSuffix(words[i], "."))
+		if canonical, ok := types[key]; ok {
+			words[i] = canonical
+			continue
+		}
+		if canonical, ok := houseTypes[key]; ok && startsWithDigit(words[i+1]) {
+			words[i] = canonical
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func splitGluedTypes(part string) string {
+	return gluedType.ReplaceAllStringFunc(part, func(match string) string {
+		groups := gluedType.FindStringSubmatch(match)
+		if compoundType.MatchString(groups[3]) {
+			return match
+		}
+		return groups[1] + groups[2] + ". " + groups[3]
+	})
+}
+
+func startsWithDigit(word string) bool {
+	for _, ch := range word {
+		return unicode.IsDigit(ch)
+	}
+	return false
+}
+
+// NormalizeList normalizes each address and drops blank ones and repeats,
+// keeping the first spelling of each address in the original order.
+func NormalizeList(values []string) []string {
+	var (
+		normalized []string
+		seen       = make(map[string]bool)
+	)
+	for _, value := range values {
+		item := Normalize(value)
+		if item == "" {
+			continue
+		}
+		key := dedupeKey(item)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, item)
+	}
+	return normalized
+}
+
+func dedupeKey(value string) string {
+	return strings.ReplaceAll(strings.ToLower(value), "ё", "е")
+}
@@ -0,0 +1,66 @@
+package address
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"postal code", "443001, г. Самара, ул. Ленина, д. 5", "г. Самара, ул. Ленина, д. 5"},
+		{"postal code alone in a part", "443001 , Самара", "Самара"},
+		{"postal code glued to the city", "443001 г.Самара", "г. Самара"},
+		{"long number is not a postal code", "4430011, Самара", "4430011, Самара"},
+		{"glued types", "г.Москва, ул.Тверская, д.5", "г. Москва, ул. Тверская, д. 5"},
+		{"spelled-out types", "город Москва, улица Тверская, дом 5", "г. Москва, ул. Тверская, д. 5"},
+		{"types without a dot", "г Москва, ул Тверская, д 5", "г. Москва, ул. Тверская, д. 5"},
+		{"compound type stays glued", "г.о. Самара, ул. Ленина", "г.о. Самара, ул. Ленина"},
+		{"glued compound type", "г.о.Самара", "г.о.Самара"},
+		{"village is not a house", "д Ивановка, ул Садовая, д 3", "д Ивановка, ул. Садовая, д. 3"},
+		{"house with a dot", "д. 12", "д. 12"},
+		{"extra spaces and empty parts", "  г.  Москва ,, ул.   Тверская ", "г. Москва, ул. Тверская"},
+		{"blank", " , ", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.value); got != tt.want {
+			t.Errorf("%s: Normalize(%q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeList(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{
+			name:   "same address spelled differently",
+			values: []string{"г.Москва, ул.Тверская, д.5", "101000, г. Москва, ул. Тверская, д. 5"},
+			want:   []string{"г. Москва, ул. Тверская, д. 5"},
+		},
+		{
+			name:   "case and ё",
+			values: []string{"г. Королёв, ул. Ленина", "Г. КОРОЛЕВ, УЛ. ЛЕНИНА"},
+			want:   []string{"г. Королёв, ул. Ленина"},
+		},
+		{
+			name:   "order kept, blanks dropped",
+			values: []string{"ул. Садовая, д. 1", "", "ул. Лесная, д. 2", "ул. Садовая, д. 1"},
+			want:   []string{"ул. Садовая, д. 1", "ул. Лесная, д. 2"},
+		},
+		{
+			name:   "nothing left",
+			values: []string{"", " , "},
+		},
+	}
+	for _, tt := range tests {
+		if got := NormalizeList(tt.values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: NormalizeList(%q) = %q, want %q", tt.name, tt.values, got, tt.want)
+		}
+	}
+}
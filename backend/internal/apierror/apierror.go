@@ -0,0 +1,75 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidJSON      = "invalid_json"
+	CodeInvalidID        = "invalid_id"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeValidationFailed = "validation_failed"
+	CodeInternal         = "internal_error"
+)
+
+// Error is the body of every failed API response. Code is stable and meant
+// for programs; Message is the Russian text shown to users; Details carries
+// optional structured data such as per-field validation errors.
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// WithDetails returns a copy of e carrying details, so shared error values
+// are never mutated.
+func (e *Error) WithDetails(details any) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// WithMessage returns a copy of e with another user-facing message.
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+var (
+	ErrInvalidJSON  = New(http.StatusBadRequest, CodeInvalidJSON, "Некорректный формат запроса")
+	ErrInvalidID    = New(http.StatusBadRequest, CodeInvalidID, "Некорректный идентификатор")
+	ErrUnauthorized = New(http.StatusUnauthorized, CodeUnauthorized, "Необходима авторизация")
+	ErrForbidden    = New(http.StatusForbidden, CodeForbidden, "Недостаточно прав")
+	ErrNotFound     = New(http.StatusNotFound, CodeNotFound, "Ресурс не найден")
+	ErrValidation   = New(http.StatusUnprocessableEntity, CodeValidationFailed, "Проверьте правильность заполнения полей")
+)
+
+// BadRequest is a 400 error with a specific message.
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// Internal is a 500 error; the message tells the user which action failed.
+func Internal(message string) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message)
+}
+
+func Write(w http.ResponseWriter, err *Error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(err.Status)
+	_ = json.NewEncoder(w).Encode(err)
+}
@@ -0,0 +1,53 @@
+package apierror
+
+import "net/http"
+
+// Errors specific to the application resources. Codes are part of the API
+// and must not change once published.
+var (
+	ErrInspectionNotFound   = New(http.StatusNotFound, "inspection_not_found", "Проверка не найдена")
+	ErrUserNotFound         = New(http.StatusNotFound, "user_not_found", "Пользователь не найден")
+	ErrEmployeeNotFound     = New(http.StatusNotFound, "employee_not_found", "Сотрудник не найден")
+	ErrOrganizationNotFound = New(http.StatusNotFound, "organization_not_found", "Организация не найдена")
+	ErrReportNotFound       = New(http.StatusNotFound, "report_not_found", "Отчет не найден")
+	ErrLeaderNotFound       = New(http.StatusNotFound, "leader_not_found", "Руководитель не найден")
+	ErrPlanNotFound         = New(http.StatusNotFound, "plan_not_found", "План проверок не найден")
+	ErrPlanItemNotFound     = New(http.StatusNotFound, "plan_item_not_found", "Позиция плана не найдена")
+	ErrDeadlineRuleNotFound = New(http.StatusNotFound, "deadline_rule_not_found", "Правило срока не найдено")
+	ErrDocumentKindNotFound = New(http.StatusNotFound, "document_kind_not_found", "Неизвестный вид документа")
+	ErrMergeNotFound        = New(http.StatusNotFound, "merge_not_found", "Объединение не найдено")
+	ErrChangeNotFound       = New(http.StatusNotFound, "organization_change_not_found", "Изменение не найдено")
+
+	ErrInvalidCredentials    = New(http.StatusUnauthorized, "invalid_credentials", "Неверный логин или пароль")
+	ErrAccessDenied          = New(http.StatusForbidden, "access_denied", "Доступ запрещен")
+	ErrLoginTaken            = New(http.StatusConflict, "login_taken", "Логин уже занят")
+	ErrOrganizationExists    = New(http.StatusConflict, "organization_exists", "Организация с таким ОГРН уже есть в справочнике")
+	ErrOrganizationInUse     = New(http.StatusConflict, "organization_in_use", "У организации есть руководители: удалите их или объедините организацию с другой")
+	ErrLeaderPeriodOverlap   = New(http.StatusConflict, "leader_period_overlap", "В эти даты у организации уже есть руководитель")
+	ErrMergeUndone           = New(http.StatusConflict, "merge_undone", "Объединение уже отменено")
+	ErrMergeUndoConflict     = New(http.StatusConflict, "merge_undo_conflict", "ОГРН объединенной организации снова занят в справочнике")
+	ErrChangeReviewed        = New(http.StatusConflict, "organization_change_reviewed", "Изменение уже рассмотрено")
+	ErrRefreshInProgress     = New(http.StatusConflict, "organization_refresh_in_progress", "Обновление справочника из Dadata уже выполняется")
+	ErrCannotDeleteSelf      = New(http.StatusConflict, "cannot_delete_self", "Нельзя удалить текущего пользователя")
+	ErrPlanYearTaken         = New(http.StatusConflict, "plan_year_taken", "План на этот год уже существует")
+	ErrPlanItemHasInspection = New(http.StatusConflict, "plan_item_has_inspection", "По позиции плана уже создана проверка")
+	ErrInspectorConflict     = New(http.StatusConflict, "inspector_conflict", "Инспектор уже занят на другой выездной проверке в эти даты")
+
+	ErrFileMissing       = New(http.StatusBadRequest, "file_missing", "Файл не передан")
+	ErrFileUnreadable    = New(http.StatusBadRequest, "file_unreadable", "Не удалось прочитать файл")
+	ErrFileInvalid       = New(http.StatusBadRequest, "file_invalid", "Не удалось разобрать файл")
+	ErrUnsupportedFormat = New(http.StatusBadRequest, "unsupported_format", "Поддерживаются форматы xlsx и csv")
+	ErrUnknownFormType   = New(http.StatusBadRequest, "unknown_form_type", "Неизвестная форма проверки")
+	ErrInvalidOGRN       = New(http.StatusBadRequest, "invalid_ogrn", "Некорректный ОГРН (13 цифр) или ОГРНИП (15 цифр)")
+	ErrInvalidPartyID    = New(http.StatusBadRequest, "invalid_party_id", "Укажите корректный ИНН, ОГРН или ОГРНИП")
+
+	ErrIdempotencyKeyInvalid   = New(http.StatusBadRequest, "idempotency_key_invalid", "Ключ идемпотентности длиннее 255 символов")
+	ErrIdempotencyKeyReused    = New(http.StatusUnprocessableEntity, "idempotency_key_reused", "Ключ идемпотентности уже использован для другого запроса")
+	ErrIdempotencyInProgress   = New(http.StatusConflict, "idempotency_in_progress", "Запрос с этим ключом идемпотентности еще выполняется")
+	ErrIdempotencyBodyTooLarge = New(http.StatusRequestEntityTooLarge, "idempotency_body_too_large", "Запрос с ключом идемпотентности больше 1 МБ")
+
+	ErrDadataNotConfigured = New(http.StatusServiceUnavailable, "dadata_not_configured", "Dadata ключи не настроены")
+	ErrDadataUnavailable   = New(http.StatusBadGateway, "dadata_unavailable", "Не удалось получить данные из Dadata")
+	ErrMailNotConfigured   = New(http.StatusServiceUnavailable, "mail_not_configured", "Отправка почты не настроена")
+	ErrMailDeliveryFailed  = New(http.StatusBadGateway, "mail_delivery_failed", "Не удалось отправить письмо ни одному получателю")
+)
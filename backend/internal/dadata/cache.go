@@ -0,0 +1,114 @@
+package dadata
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache keeps raw API responses. Get reports ok=false for missing and
+// expired entries.
+type Cache interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+type noCache struct{}
+
+func (noCache) Get(context.Context, string) ([]byte, bool, error) { return nil, false, nil }
+func (noCache) Set(context.Context, string, []byte) error         { return nil }
+
+// FileCache stores each response in its own file under Dir; the file
+// modification time tells its age.
+type FileCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+func NewFileCache(dir string, ttl time.Duration) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCache{Dir: dir, TTL: ttl}, nil
+}
+
+func (c *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *FileCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if time.Since(info.ModTime()) > c.TTL {
+		return nil, false, nil
+	}
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set writes through a temporary file so readers never see a partial entry.
+func (c *FileCache) Set(ctx context.Context, key string, value []byte) error {
+	tmp, err := os.CreateTemp(c.Dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// MySQLCache stores responses in the dadata_cache table.
+type MySQLCache struct {
+	DB  *sql.DB
+	TTL time.Duration
+}
+
+func NewMySQLCache(db *sql.DB, ttl time.Duration) *MySQLCache {
+	return &MySQLCache{DB: db, TTL: ttl}
+}
+
+func (c *MySQLCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var value []byte
+	err := c.DB.QueryRowContext(ctx, `
+		SELECT response FROM dadata_cache
+		WHERE cache_key=? AND fetched_at >= ?
+	`, key, time.Now().Add(-c.TTL)).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *MySQLCache) Set(ctx context.Context, key string, value []byte) error {
+	_, err := c.DB.ExecContext(ctx, `
+		INSERT INTO dadata_cache (cache_key, response, fetched_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE response=VALUES(response), fetched_at=VALUES(fetched_at)
+	`, key, value, time.Now())
+	return err
+}
@@ -0,0 +1,141 @@
+package dadata
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewFileCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := cache.Get(ctx, "party:1"); ok || err != nil {
+		t.Fatalf("missing entry: ok=%v err=%v", ok, err)
+	}
+	if err := cache.Set(ctx, "party:1", []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	value, ok, err := cache.Get(ctx, "party:1")
+	if err != nil || !ok || string(value) != `{"a":1}` {
+		t.Fatalf("fresh entry: %q ok=%v err=%v", value, ok, err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(cache.path("party:1"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := cache.Get(ctx, "party:1"); ok || err != nil {
+		t.Fatalf("expired entry: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestMySQLCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	store := &cacheTable{rows: make(map[string]cacheRow)}
+	db := sql.OpenDB(store)
+	defer db.Close()
+	cache := NewMySQLCache(db, time.Hour)
+
+	if _, ok, err := cache.Get(ctx, "party:1"); ok || err != nil {
+		t.Fatalf("missing entry: ok=%v err=%v", ok, err)
+	}
+	if err := cache.Set(ctx, "party:1", []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	value, ok, err := cache.Get(ctx, "party:1")
+	if err != nil || !ok || string(value) != `{"a":1}` {
+		t.Fatalf("fresh entry: %q ok=%v err=%v", value, ok, err)
+	}
+
+	store.age("party:1", 2*time.Hour)
+	if _, ok, err := cache.Get(ctx, "party:1"); ok || err != nil {
+		t.Fatalf("expired entry: ok=%v err=%v", ok, err)
+	}
+}
+
+// cacheTable is a database/sql driver holding dadata_cache in memory. It
+// understands the two statements of MySQLCache only.
+type cacheTable struct {
+	mu   sync.Mutex
+	rows map[string]cacheRow
+}
+
+type cacheRow struct {
+	response  []byte
+	fetchedAt time.Time
+}
+
+func (t *cacheTable) age(key string, by time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	row := t.rows[key]
+	row.fetchedAt = row.fetchedAt.Add(-by)
+	t.rows[key] = row
+}
+
+func (t *cacheTable) Connect(context.Context) (driver.Conn, error) { return cacheConn{t}, nil }
+func (t *cacheTable) Driver() driver.Driver                        { return nil }
+
+type cacheConn struct{ table *cacheTable }
+
+func (c cacheConn) Prepare(query string) (driver.Stmt, error) {
+	return cacheStmt{table: c.table, query: strings.TrimSpace(query)}, nil
+}
+func (c cacheConn) Close() error { return nil }
+func (c cacheConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type cacheStmt struct {
+	table *cacheTable
+	query string
+}
+
+func (s cacheStmt) Close() error  { return nil }
+func (s cacheStmt) NumInput() int { return -1 }
+
+func (s cacheStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if !strings.HasPrefix(s.query, "INSERT INTO dadata_cache") {
+		return nil, errors.New("unexpected statement: " + s.query)
+	}
+	s.table.mu.Lock()
+	defer s.table.mu.Unlock()
+	s.table.rows[args[0].(string)] = cacheRow{response: args[1].([]byte), fetchedAt: args[2].(time.Time)}
+	return driver.RowsAffected(1), nil
+}
+
+func (s cacheStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT response FROM dadata_cache") {
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	s.table.mu.Lock()
+	defer s.table.mu.Unlock()
+	rows := &cacheRows{}
+	if row, ok := s.table.rows[args[0].(string)]; ok && !row.fetchedAt.Before(args[1].(time.Time)) {
+		rows.values = append(rows.values, row.response)
+	}
+	return rows, nil
+}
+
+type cacheRows struct{ values [][]byte }
+
+func (r *cacheRows) Columns() []string { return []string{"response"} }
+func (r *cacheRows) Close() error      { return nil }
+
+func (r *cacheRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
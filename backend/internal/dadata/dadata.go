@@ -0,0 +1,319 @@
+// Package dadata is a client of the DaData suggestions API. Responses are
+// cached, requests are rate limited and retried on server errors.
+package dadata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultBaseURL = "https://suggestions.dadata.ru/suggestions/api/4_1/rs"
+
+var ErrNotConfigured = errors.New("dadata: API key and secret are required")
+
+// StatusError is a non-2xx response of DaData.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("dadata: status %d: %s", e.StatusCode, e.Body)
+}
+
+// Config describes the API endpoint and the client behaviour. BaseURL may
+// point at a stand-in server; RateLimit is in requests per second, zero
+// means unlimited; Retries is how many times a 5xx, 429 or network error
+// is retried, with the delay starting at RetryDelay and doubling each time.
+type Config struct {
+	BaseURL    string
+	APIKey     string
+	Secret     string
+	Timeout    time.Duration
+	RateLimit  float64
+	Retries    int
+	RetryDelay time.Duration
+}
+
+type Client struct {
+	config  Config
+	http    *http.Client
+	cache   Cache
+	limiter *limiter
+}
+
+// NewClient returns a client of the API described by config. A nil cache
+// disables caching.
+func NewClient(config Config, cache Cache) (*Client, error) {
+	if config.APIKey == "" || config.Secret == "" {
+		return nil, ErrNotConfigured
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultBaseURL
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 200 * time.Millisecond
+	}
+	if cache == nil {
+		cache = noCache{}
+	}
+	return &Client{
+		config:  config,
+		http:    &http.Client{Timeout: config.Timeout},
+		cache:   cache,
+		limiter: newLimiter(config.RateLimit),
+	}, nil
+}
+
+// Party is an organization or individual entrepreneur as DaData returns it.
+type Party struct {
+	Value string    `json:"value"`
+	Data  PartyData `json:"data"`
+}
+
+// Party types and branch types.
+const (
+	PartyLegal      = "LEGAL"
+	PartyIndividual = "INDIVIDUAL"
+	BranchMain      = "MAIN"
+	BranchBranch    = "BRANCH"
+)
+
+type PartyData struct {
+	INN         string `json:"inn"`
+	KPP         string `json:"kpp"`
+	OGRN        string `json:"ogrn"`
+	OKPO        string `json:"okpo"`
+	Type        string `json:"type"`
+	BranchType  string `json:"branch_type"`
+	BranchCount int    `json:"branch_count"`
+	Name        struct {
+		FullWithOpf  string `json:"full_with_opf"`
+		ShortWithOpf string `json:"short_with_opf"`
+	} `json:"name"`
+	Address struct {
+		Value string `json:"value"`
+	} `json:"address"`
+	Management *struct {
+		Post string `json:"post"`
+		Name string `json:"name"`
+	} `json:"management"`
+	// FIO is set for individual entrepreneurs.
+	FIO *struct {
+		Surname    string `json:"surname"`
+		Name       string `json:"name"`
+		Patronymic string `json:"patronymic"`
+	} `json:"fio"`
+}
+
+type partyResponse struct {
+	Suggestions []Party `json:"suggestions"`
+}
+
+// Address is a suggested address; Value has no postal code.
+type Address struct {
+	Value             string `json:"value"`
+	UnrestrictedValue string `json:"unrestricted_value"`
+	Data              struct {
+		PostalCode string `json:"postal_code"`
+		FiasID     string `json:"fias_id"`
+	} `json:"data"`
+}
+
+type addressResponse struct {
+	Suggestions []Address `json:"suggestions"`
+}
+
+// FindPartyByID looks an organization up by INN, OGRN or ОГРНИП. The head
+// organization comes first. The response is cached under the number.
+func (c *Client) FindPartyByID(ctx context.Context, id string) ([]Party, error) {
+	var response partyResponse
+	if err := c.call(ctx, "findById/party", id, map[string]any{"query": id}, &response); err != nil {
+		return nil, err
+	}
+	return response.Suggestions, nil
+}
+
+// RefreshPartyByID is FindPartyByID that always asks the API and stores the
+// fresh response in the cache, for jobs that look for changes.
+func (c *Client) RefreshPartyByID(ctx context.Context, id string) ([]Party, error) {
+	var response partyResponse
+	if err := c.fetch(ctx, "findById/party", "findById/party:"+id, map[string]any{"query": id}, &response); err != nil {
+		return nil, err
+	}
+	return response.Suggestions, nil
+}
+
+// FindBranches returns up to count branches of the organization with the
+// INN.
+func (c *Client) FindBranches(ctx context.Context, inn string, count int) ([]Party, error) {
+	var response partyResponse
+	request := map[string]any{"query": inn, "branch_type": BranchBranch, "count": count}
+	if err := c.call(ctx, "findById/party", fmt.Sprintf("%s:branches:%d", inn, count), request, &response); err != nil {
+		return nil, err
+	}
+	return response.Suggestions, nil
+}
+
+// SuggestParty returns organizations matching free text, for autocomplete.
+// Suggestions are not cached.
+func (c *Client) SuggestParty(ctx context.Context, query string, count int) ([]Party, error) {
+	var response partyResponse
+	if err := c.call(ctx, "suggest/party", "", map[string]any{"query": query, "count": count}, &response); err != nil {
+		return nil, err
+	}
+	return response.Suggestions, nil
+}
+
+// SuggestAddress returns addresses matching free text, for autocomplete.
+func (c *Client) SuggestAddress(ctx context.Context, query string, count int) ([]Address, error) {
+	var response addressResponse
+	if err := c.call(ctx, "suggest/address", "", map[string]any{"query": query, "count": count}, &response); err != nil {
+		return nil, err
+	}
+	return response.Suggestions, nil
+}
+
+// call posts request to method and decodes the response into result. A
+// non-empty cacheKey serves the response from the cache when possible and
+// stores fresh responses there.
+func (c *Client) call(ctx context.Context, method, cacheKey string, request, result any) error {
+	if cacheKey != "" {
+		cacheKey = method + ":" + cacheKey
+		if body, ok, err := c.cache.Get(ctx, cacheKey); err == nil && ok {
+			if err := json.Unmarshal(body, result); err == nil {
+				return nil
+			}
+		}
+	}
+	return c.fetch(ctx, method, cacheKey, request, result)
+}
+
+// fetch posts request to method, decodes the response into result and
+// stores it in the cache under a non-empty cacheKey.
+func (c *Client) fetch(ctx context.Context, method, cacheKey string, request, result any) error {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	body, err := c.post(ctx, method, requestBody)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("dadata: decode %s response: %w", method, err)
+	}
+	if cacheKey != "" {
+		// A cache failure only costs a repeated request later.
+		_ = c.cache.Set(ctx, cacheKey, body)
+	}
+	return nil
+}
+
+// post sends the request, retrying network errors, 5xx responses and 429
+// Too Many Requests with exponential backoff.
+func (c *Client) post(ctx context.Context, method string, requestBody []byte) ([]byte, error) {
+	delay := c.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		body, err := c.postOnce(ctx, method, requestBody)
+		if err == nil {
+			return body, nil
+		}
+		var statusErr *StatusError
+		retryable := !errors.As(err, &statusErr) ||
+			statusErr.StatusCode >= http.StatusInternalServerError ||
+			statusErr.StatusCode == http.StatusTooManyRequests
+		if !retryable || attempt >= c.config.Retries || ctx.Err() != nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) postOnce(ctx context.Context, method string, requestBody []byte) ([]byte, error) {
+	if err := c.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.BaseURL+"/"+method, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Authorization", "Token "+c.config.APIKey)
+	request.Header.Set("X-Secret", c.config.Secret)
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, &StatusError{StatusCode: response.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
+
+// limiter spaces requests evenly at the configured rate.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(perSecond float64) *limiter {
+	if perSecond <= 0 {
+		return &limiter{}
+	}
+	return &limiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the next request may be sent.
+func (l *limiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,187 @@
+package dadata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stand-in answers with the statuses in order, then with the last one, and
+// remembers when each request came.
+type standIn struct {
+	mu       sync.Mutex
+	statuses []int
+	body     string
+	times    []time.Time
+	paths    []string
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.Copy(io.Discard, r.Body)
+	s.mu.Lock()
+	status := s.statuses[min(len(s.times), len(s.statuses)-1)]
+	s.times = append(s.times, time.Now())
+	s.paths = append(s.paths, r.URL.Path)
+	s.mu.Unlock()
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, s.body)
+}
+
+func (s *standIn) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.times)
+}
+
+const partyBody = `{"suggestions":[{"value":"ООО \"Ромашка\"","data":{"ogrn":"1027700132195","inn":"7707083893"}}]}`
+
+func newTestClient(t *testing.T, handler http.Handler, config Config, cache Cache) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	config.BaseURL = server.URL
+	config.APIKey = "key"
+	config.Secret = "secret"
+	if config.RetryDelay == 0 {
+		config.RetryDelay = time.Millisecond
+	}
+	client, err := NewClient(config, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusTooManyRequests} {
+		stand := &standIn{statuses: []int{status, status, http.StatusOK}, body: partyBody}
+		client := newTestClient(t, stand, Config{Retries: 3, RetryDelay: 10 * time.Millisecond}, nil)
+
+		parties, err := client.FindPartyByID(context.Background(), "1027700132195")
+		if err != nil {
+			t.Fatalf("status %d: %v", status, err)
+		}
+		if len(parties) != 1 || parties[0].Data.INN != "7707083893" {
+			t.Fatalf("status %d: unexpected parties %+v", status, parties)
+		}
+		if got := stand.requests(); got != 3 {
+			t.Fatalf("status %d: %d requests, want 3", status, got)
+		}
+		// The second delay doubles the first.
+		if gap := stand.times[2].Sub(stand.times[1]); gap < 20*time.Millisecond {
+			t.Errorf("status %d: second retry after %v, want at least 20ms", status, gap)
+		}
+	}
+}
+
+func TestClientGivesUpAfterRetries(t *testing.T) {
+	stand := &standIn{statuses: []int{http.StatusServiceUnavailable}}
+	client := newTestClient(t, stand, Config{Retries: 2}, nil)
+
+	_, err := client.FindPartyByID(context.Background(), "1027700132195")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want status 503", err)
+	}
+	if got := stand.requests(); got != 3 {
+		t.Fatalf("%d requests, want 3", got)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden} {
+		stand := &standIn{statuses: []int{status}}
+		client := newTestClient(t, stand, Config{Retries: 3}, nil)
+
+		_, err := client.FindPartyByID(context.Background(), "1027700132195")
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+			t.Fatalf("err = %v, want status %d", err, status)
+		}
+		if got := stand.requests(); got != 1 {
+			t.Fatalf("status %d: %d requests, want 1", status, got)
+		}
+	}
+}
+
+func TestClientSpacesRequests(t *testing.T) {
+	stand := &standIn{statuses: []int{http.StatusOK}, body: `{"suggestions":[]}`}
+	client := newTestClient(t, stand, Config{RateLimit: 20}, nil)
+
+	for i := 0; i < 4; i++ {
+		if _, err := client.SuggestParty(context.Background(), "ромашка", 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i < len(stand.times); i++ {
+		// 20 per second is one request per 50ms; allow for timer jitter.
+		if gap := stand.times[i].Sub(stand.times[i-1]); gap < 45*time.Millisecond {
+			t.Errorf("request %d came %v after the previous one, want 50ms", i, gap)
+		}
+	}
+}
+
+func TestLimiterStopsOnCancel(t *testing.T) {
+	l := newLimiter(1)
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestClientServesFromCache(t *testing.T) {
+	stand := &standIn{statuses: []int{http.StatusOK}, body: partyBody}
+	cache, err := NewFileCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, stand, Config{}, cache)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.FindPartyByID(context.Background(), "1027700132195"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := stand.requests(); got != 1 {
+		t.Fatalf("%d requests, want 1", got)
+	}
+	if stand.paths[0] != "/findById/party" {
+		t.Errorf("path = %q", stand.paths[0])
+	}
+}
+
+func TestClientRefreshBypassesCache(t *testing.T) {
+	stand := &standIn{statuses: []int{http.StatusOK}, body: partyBody}
+	cache, err := NewFileCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, stand, Config{}, cache)
+
+	ctx := context.Background()
+	if _, err := client.FindPartyByID(ctx, "1027700132195"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.RefreshPartyByID(ctx, "1027700132195"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stand.requests(); got != 2 {
+		t.Fatalf("%d requests, want 2", got)
+	}
+	// The refreshed response serves later lookups.
+	if _, err := client.FindPartyByID(ctx, "1027700132195"); err != nil {
+		t.Fatal(err)
+	}
+	if got := stand.requests(); got != 2 {
+		t.Fatalf("%d requests after refresh, want 2", got)
+	}
+}
@@ -32,6 +32,11 @@ type Leader struct {
 	InitialsNameIm  string `json:"initials_name_im,omitempty"`
 	InitialsNameDat string `json:"initials_name_dat,omitempty"`
 
+	// EffectiveFrom and EffectiveTo bound the term in office, both days
+	// inclusive; an empty EffectiveTo means the leader is still in charge.
+	EffectiveFrom string  `json:"effective_from"`
+	EffectiveTo   *string `json:"effective_to,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
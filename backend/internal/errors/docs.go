@@ -3,6 +3,9 @@ package errors
 import "errors"
 
 var (
-	ErrLeaderNotFound = errors.New("leader not found")
+	ErrLeaderNotFound       = errors.New("leader not found")
 	ErrOrganizationNotFound = errors.New("organization not found")
-)
\ No newline at end of file
+	ErrOrganizationExists   = errors.New("organization with this OGRN already exists")
+	ErrLeaderPeriodOverlap  = errors.New("leader term overlaps another leader of the organization")
+	ErrOrganizationInUse    = errors.New("organization has leader records")
+)
@@ -0,0 +1,257 @@
+// Package mail sends plain-text email with optional attachments over SMTP.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TLSNone     = "none"
+	TLSStartTLS = "starttls"
+	TLSImplicit = "tls"
+)
+
+var ErrNoRecipients = errors.New("mail: no recipients")
+
+// Config describes the SMTP server. TLS is one of TLSNone (plain SMTP, e.g.
+// a local fake such as Mailpit), TLSStartTLS or TLSImplicit.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLS      string
+	Timeout  time.Duration
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type Message struct {
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Sender delivers a message and returns its Message-ID.
+type Sender interface {
+	Send(ctx context.Context, msg Message) (string, error)
+}
+
+type SMTPSender struct {
+	config Config
+}
+
+func NewSMTPSender(config Config) *SMTPSender {
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &SMTPSender{config: config}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) (string, error) {
+	if len(msg.To) == 0 {
+		return "", ErrNoRecipients
+	}
+	messageID := newMessageID(s.config.From)
+	data, err := buildMessage(s.config.From, msg, messageID, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	client, err := s.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	if s.config.TLS == TLSStartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return "", err
+		}
+	}
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return "", err
+		}
+	}
+	if err := client.Mail(addressOnly(s.config.From)); err != nil {
+		return "", err
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(addressOnly(to)); err != nil {
+			return "", err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return messageID, client.Quit()
+}
+
+func (s *SMTPSender) dial(ctx context.Context) (*smtp.Client, error) {
+	address := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	dialer := &net.Dialer{}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.config.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.config.Host}}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// buildMessage renders msg as RFC 5322 text: a base64 text/plain body, or
+// multipart/mixed when there are attachments.
+func buildMessage(from string, msg Message, messageID string, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	headers := []string{
+		"From: " + encodeAddress(from),
+		"To: " + encodeAddressList(msg.To),
+		"Subject: " + mime.BEncoding.Encode("utf-8", stripNewlines(msg.Subject)),
+		"Date: " + date.Format(time.RFC1123Z),
+		"Message-ID: " + messageID,
+		"MIME-Version: 1.0",
+	}
+	for _, header := range headers {
+		buf.WriteString(header + "\r\n")
+	}
+
+	if len(msg.Attachments) == 0 {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64(&buf, []byte(msg.Body))
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&buf)
+	buf.WriteString("Content-Type: multipart/mixed; boundary=" + writer.Boundary() + "\r\n\r\n")
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, []byte(msg.Body))
+
+	for _, attachment := range msg.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, attachment.Data)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64-encoded in lines of 76 characters.
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		_, _ = w.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	_, _ = w.Write([]byte(encoded + "\r\n"))
+}
+
+func encodeAddressList(addresses []string) string {
+	encoded := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		encoded = append(encoded, encodeAddress(address))
+	}
+	return strings.Join(encoded, ", ")
+}
+
+// encodeAddress encodes the display name of "Name <user@host>" for headers.
+func encodeAddress(address string) string {
+	address = stripNewlines(address)
+	open := strings.LastIndex(address, "<")
+	if open <= 0 {
+		return address
+	}
+	name := strings.TrimSpace(address[:open])
+	return mime.BEncoding.Encode("utf-8", name) + " " + address[open:]
+}
+
+// addressOnly returns the bare user@host of "Name <user@host>".
+func addressOnly(address string) string {
+	address = stripNewlines(address)
+	if open := strings.LastIndex(address, "<"); open >= 0 {
+		if end := strings.Index(address[open:], ">"); end > 0 {
+			return address[open+1 : open+end]
+		}
+	}
+	return strings.TrimSpace(address)
+}
+
+func stripNewlines(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+func newMessageID(from string) string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	domain := "localhost"
+	if at := strings.LastIndex(addressOnly(from), "@"); at >= 0 {
+		domain = addressOnly(from)[at+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(buf), domain)
+}
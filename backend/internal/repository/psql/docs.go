@@ -3,12 +3,24 @@ package psql
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
 	e "github.com/DexScen/DocGenerationWebApp/backend/internal/errors"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
+const organizationColumns = `
+	id,
+	full_name,
+	COALESCE(short_name, ''),
+	ogrn,
+	legal_address,
+	COALESCE(postal_address, ''),
+	created_at,
+	updated_at
+`
+
 type Docs struct {
 	db *sql.DB
 }
@@ -18,18 +30,7 @@ func NewDocs(db *sql.DB) *Docs {
 }
 
 func (d *Docs) GetAllOrganizations(ctx context.Context) ([]domain.Organization, error) {
-	query := `
-		SELECT
-			id,
-			full_name,
-			short_name,
-			ogrn,
-			legal_address,
-			postal_address,
-			created_at,
-			updated_at
-		FROM organizations
-	`
+	query := `SELECT ` + organizationColumns + ` FROM organizations ORDER BY full_name`
 
 	rows, err := d.db.QueryContext(ctx, query)
 	if err != nil {
@@ -40,17 +41,8 @@ func (d *Docs) GetAllOrganizations(ctx context.Context) ([]domain.Organization,
 	orgs := make([]domain.Organization, 0)
 
 	for rows.Next() {
-		var org domain.Organization
-		if err := rows.Scan(
-			&org.ID,
-			&org.FullName,
-			&org.ShortName,
-			&org.OGRN,
-			&org.LegalAddress,
-			&org.PostalAddress,
-			&org.CreatedAt,
-			&org.UpdatedAt,
-		); err != nil {
+		org, err := scanOrganization(rows)
+		if err != nil {
 			return nil, err
 		}
 		orgs = append(orgs, org)
@@ -63,40 +55,139 @@ func (d *Docs) GetAllOrganizations(ctx context.Context) ([]domain.Organization,
 	return orgs, nil
 }
 
-func (d *Docs) PostNewOrganization(ctx context.Context, org domain.Organization) error {
+func (d *Docs) GetOrganizationByOGRN(ctx context.Context, ogrn string) (domain.Organization, error) {
+	query := `SELECT ` + organizationColumns + ` FROM organizations WHERE ogrn = ?`
+
+	org, err := scanOrganization(d.db.QueryRowContext(ctx, query, ogrn))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Organization{}, e.ErrOrganizationNotFound
+	}
+	return org, err
+}
+
+func (d *Docs) getOrganizationByID(ctx context.Context, id int64) (domain.Organization, error) {
+	query := `SELECT ` + organizationColumns + ` FROM organizations WHERE id = ?`
+
+	org, err := scanOrganization(d.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Organization{}, e.ErrOrganizationNotFound
+	}
+	return org, err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrganization(row rowScanner) (domain.Organization, error) {
+	var org domain.Organization
+	err := row.Scan(
+		&org.ID,
+		&org.FullName,
+		&org.ShortName,
+		&org.OGRN,
+		&org.LegalAddress,
+		&org.PostalAddress,
+		&org.CreatedAt,
+		&org.UpdatedAt,
+	)
+	return org, err
+}
+
+// PostNewOrganization adds the organization to the registry. When its OGRN
+// is already there the stored record is returned unchanged, so repeating the
+// request does not fail; registry data is only changed through
+// PutOrganizationByID and the DaData review queue.
+func (d *Docs) PostNewOrganization(ctx context.Context, org domain.Organization) (domain.Organization, error) {
 	query := `
 		INSERT INTO organizations
 			(full_name, short_name, ogrn, legal_address, postal_address, created_at, updated_at)
 		VALUES
-			(?, ?, ?, ?, ?, now(), now())
+			(?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), now(), now())
+		ON DUPLICATE KEY UPDATE
+			id = LAST_INSERT_ID(id)
 	`
 
-	_, err := d.db.ExecContext(ctx, query,
+	res, err := d.db.ExecContext(ctx, query,
 		org.FullName,
 		org.ShortName,
 		org.OGRN,
 		org.LegalAddress,
 		org.PostalAddress,
 	)
-	return err
+	if err != nil {
+		return domain.Organization{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return domain.Organization{}, err
+	}
+	return d.getOrganizationByID(ctx, id)
+}
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// isForeignKeyViolation reports a reference to a missing parent row.
+func isForeignKeyViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1452
+}
+
+// isRowReferenced reports a delete of a parent row that other rows still
+// reference.
+func isRowReferenced(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1451
+}
+
+const leaderColumns = `
+	id,
+	organization_id,
+	position,
+	last_name,
+	first_name,
+	middle_name,
+	initials_name_im,
+	initials_name_dat,
+	DATE_FORMAT(effective_from, '%Y-%m-%d'),
+	DATE_FORMAT(effective_to, '%Y-%m-%d'),
+	created_at,
+	updated_at
+`
+
+func scanLeader(row rowScanner) (domain.Leader, error) {
+	var (
+		l  domain.Leader
+		to sql.NullString
+	)
+	err := row.Scan(
+		&l.ID,
+		&l.OrganizationID,
+		&l.Position,
+		&l.LastName,
+		&l.FirstName,
+		&l.MiddleName,
+		&l.InitialsNameIm,
+		&l.InitialsNameDat,
+		&l.EffectiveFrom,
+		&to,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	)
+	if to.Valid {
+		l.EffectiveTo = &to.String
+	}
+	return l, err
 }
 
+// GetLeadersByOrgID returns every leader the organization has had, the
+// latest term first.
 func (d *Docs) GetLeadersByOrgID(ctx context.Context, orgID int) ([]domain.Leader, error) {
-	query := `
-        SELECT
-            id,
-            organization_id,
-            position,
-            last_name,
-            first_name,
-            middle_name,
-            initials_name_im,
-            initials_name_dat,
-            created_at,
-            updated_at
-        FROM leader
-        WHERE organization_id = ?
-    `
+	query := `SELECT ` + leaderColumns + ` FROM leader WHERE organization_id = ? ORDER BY effective_from DESC, id DESC`
 
 	rows, err := d.db.QueryContext(ctx, query, orgID)
 	if err != nil {
@@ -104,22 +195,10 @@ func (d *Docs) GetLeadersByOrgID(ctx context.Context, orgID int) ([]domain.Leade
 	}
 	defer rows.Close()
 
-	var leaders []domain.Leader
+	leaders := make([]domain.Leader, 0)
 
 	for rows.Next() {
-		var l domain.Leader
-		err := rows.Scan(
-			&l.ID,
-			&l.OrganizationID,
-			&l.Position,
-			&l.LastName,
-			&l.FirstName,
-			&l.MiddleName,
-			&l.InitialsNameIm,
-			&l.InitialsNameDat,
-			&l.CreatedAt,
-			&l.UpdatedAt,
-		)
+		l, err := scanLeader(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -133,7 +212,71 @@ func (d *Docs) GetLeadersByOrgID(ctx context.Context, orgID int) ([]domain.Leade
 	return leaders, nil
 }
 
-func (d *Docs) PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_id int) error {
+// GetLeaderOnDate returns the leader in charge of the organization on date
+// (YYYY-MM-DD).
+func (d *Docs) GetLeaderOnDate(ctx context.Context, orgID int, date string) (domain.Leader, error) {
+	query := `SELECT ` + leaderColumns + `
+		FROM leader
+		WHERE organization_id = ?
+			AND effective_from <= ?
+			AND (effective_to IS NULL OR effective_to >= ?)
+		ORDER BY effective_from DESC, id DESC
+		LIMIT 1
+	`
+
+	l, err := scanLeader(d.db.QueryRowContext(ctx, query, orgID, date, date))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Leader{}, e.ErrLeaderNotFound
+	}
+	return l, err
+}
+
+func (d *Docs) getLeaderByID(ctx context.Context, queryer rowQueryer, id int64) (domain.Leader, error) {
+	query := `SELECT ` + leaderColumns + ` FROM leader WHERE id = ?`
+
+	l, err := scanLeader(queryer.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Leader{}, e.ErrLeaderNotFound
+	}
+	return l, err
+}
+
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// PostLeaderByOrgID adds a leader to the organization and returns the stored
+// record, or ErrOrganizationNotFound when there is no such organization. When
+// the new term is open, the open term of the previous leader ends the day
+// before it starts; any other overlap with an existing term is
+// ErrLeaderPeriodOverlap.
+func (d *Docs) PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_id int) (created domain.Leader, err error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Leader{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Only a new open term replaces the current leader; a term with an end
+	// date is a past record and must fit between the existing ones.
+	if leader.EffectiveTo == nil {
+		_, err = tx.ExecContext(ctx, `
+            UPDATE leader
+            SET effective_to = DATE_SUB(?, INTERVAL 1 DAY)
+            WHERE organization_id = ? AND effective_to IS NULL AND effective_from < ?
+        `, leader.EffectiveFrom, org_id, leader.EffectiveFrom)
+		if err != nil {
+			return domain.Leader{}, err
+		}
+	}
+	if err = checkLeaderOverlap(ctx, tx, leader, org_id, 0); err != nil {
+		return domain.Leader{}, err
+	}
+
 	query := `
         INSERT INTO leader (
             organization_id,
@@ -143,14 +286,16 @@ func (d *Docs) PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_
             middle_name,
             initials_name_im,
             initials_name_dat,
+            effective_from,
+            effective_to,
             created_at,
             updated_at
         ) VALUES (
-            ?, ?, ?, ?, ?, ?, ?, NOW(), NOW()
+            ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW()
         )
     `
 
-	_, err := d.db.ExecContext(ctx, query,
+	res, err := tx.ExecContext(ctx, query,
 		org_id,
 		leader.Position,
 		leader.LastName,
@@ -158,41 +303,127 @@ func (d *Docs) PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_
 		leader.MiddleName,
 		leader.InitialsNameIm,
 		leader.InitialsNameDat,
+		leader.EffectiveFrom,
+		leader.EffectiveTo,
 	)
-	return err
-}
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			err = e.ErrOrganizationNotFound
+		}
+		return domain.Leader{}, err
+	}
 
-func (d *Docs) DeleteOrganizationByID(ctx context.Context, org_id int) error {
-	query := `
-        DELETE FROM organizations
-        WHERE id = ?
-    `
+	id, err := res.LastInsertId()
+	if err != nil {
+		return domain.Leader{}, err
+	}
+	if created, err = d.getLeaderByID(ctx, tx, id); err != nil {
+		return domain.Leader{}, err
+	}
+	return created, tx.Commit()
+}
 
-	res, err := d.db.ExecContext(ctx, query, org_id)
+// PutLeaderByID replaces the data and term of a leader.
+func (d *Docs) PutLeaderByID(ctx context.Context, leader domain.Leader, id int) (updated domain.Leader, err error) {
+	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return domain.Leader{}, err
 	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
 
-	rowsAffected, err := res.RowsAffected()
+	current, err := d.getLeaderByID(ctx, tx, int64(id))
 	if err != nil {
-		return err
+		return domain.Leader{}, err
+	}
+	if err = checkLeaderOverlap(ctx, tx, leader, int(current.OrganizationID), id); err != nil {
+		return domain.Leader{}, err
 	}
 
-	if rowsAffected == 0 {
-		return e.ErrOrganizationNotFound
+	_, err = tx.ExecContext(ctx, `
+        UPDATE leader
+        SET
+            position = ?,
+            last_name = ?,
+            first_name = ?,
+            middle_name = ?,
+            initials_name_im = ?,
+            initials_name_dat = ?,
+            effective_from = ?,
+            effective_to = ?,
+            updated_at = NOW()
+        WHERE id = ?
+    `,
+		leader.Position,
+		leader.LastName,
+		leader.FirstName,
+		leader.MiddleName,
+		leader.InitialsNameIm,
+		leader.InitialsNameDat,
+		leader.EffectiveFrom,
+		leader.EffectiveTo,
+		id,
+	)
+	if err != nil {
+		return domain.Leader{}, err
 	}
+	if updated, err = d.getLeaderByID(ctx, tx, int64(id)); err != nil {
+		return domain.Leader{}, err
+	}
+	return updated, tx.Commit()
+}
 
+// checkLeaderOverlap returns ErrLeaderPeriodOverlap when the term of leader
+// intersects the term of another leader of the organization than exceptID.
+func checkLeaderOverlap(ctx context.Context, tx *sql.Tx, leader domain.Leader, orgID, exceptID int) error {
+	var count int
+	err := tx.QueryRowContext(ctx, `
+        SELECT COUNT(*)
+        FROM leader
+        WHERE organization_id = ?
+            AND id <> ?
+            AND effective_from <= COALESCE(?, '9999-12-31')
+            AND COALESCE(effective_to, '9999-12-31') >= ?
+    `, orgID, exceptID, leader.EffectiveTo, leader.EffectiveFrom).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return e.ErrLeaderPeriodOverlap
+	}
 	return nil
 }
 
-func (d *Docs) DeleteLeaderByID(ctx context.Context, id int) error {
+// RetireLeaderByID ends the term of a leader today. The record stays, so
+// acts and lookups for dates in the past still find who was in charge.
+func (d *Docs) RetireLeaderByID(ctx context.Context, id int) (domain.Leader, error) {
+	_, err := d.db.ExecContext(ctx, `
+        UPDATE leader
+        SET effective_to = GREATEST(effective_from, CURDATE()), updated_at = NOW()
+        WHERE id = ? AND (effective_to IS NULL OR effective_to > CURDATE())
+    `, id)
+	if err != nil {
+		return domain.Leader{}, err
+	}
+	return d.getLeaderByID(ctx, d.db, int64(id))
+}
+
+// DeleteOrganizationByID deletes a registry record. A record with leaders is
+// ErrOrganizationInUse: their terms are the history of who signed the acts.
+func (d *Docs) DeleteOrganizationByID(ctx context.Context, org_id int) error {
 	query := `
-        DELETE FROM leader
+        DELETE FROM organizations
         WHERE id = ?
     `
 
-	res, err := d.db.ExecContext(ctx, query, id)
+	res, err := d.db.ExecContext(ctx, query, org_id)
 	if err != nil {
+		if isRowReferenced(err) {
+			err = e.ErrOrganizationInUse
+		}
 		return err
 	}
 
@@ -202,26 +433,27 @@ func (d *Docs) DeleteLeaderByID(ctx context.Context, id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return e.ErrLeaderNotFound
+		return e.ErrOrganizationNotFound
 	}
 
 	return nil
 }
 
-func (d *Docs) PutOrganizationByID(ctx context.Context, org domain.Organization, org_id int) error {
+// PutOrganizationByID replaces the registry record and returns it as stored.
+func (d *Docs) PutOrganizationByID(ctx context.Context, org domain.Organization, org_id int) (domain.Organization, error) {
 	query := `
         UPDATE organizations
         SET
             full_name = ?,
-            short_name = ?,
+            short_name = NULLIF(?, ''),
             ogrn = ?,
             legal_address = ?,
-            postal_address = ?,
+            postal_address = NULLIF(?, ''),
             updated_at = NOW()
         WHERE id = ?
     `
 
-	res, err := d.db.ExecContext(ctx, query,
+	_, err := d.db.ExecContext(ctx, query,
 		org.FullName,
 		org.ShortName,
 		org.OGRN,
@@ -230,19 +462,15 @@ func (d *Docs) PutOrganizationByID(ctx context.Context, org domain.Organization,
 		org_id,
 	)
 	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return e.ErrOrganizationNotFound
+		if isDuplicateEntry(err) {
+			return domain.Organization{}, e.ErrOrganizationExists
+		}
+		return domain.Organization{}, err
 	}
 
-	return nil
+	// Reading the record back also tells a missing row from one saved with
+	// the same values, for which MySQL reports no affected rows.
+	return d.getOrganizationByID(ctx, int64(org_id))
 }
 
 func (d *Docs) GetAllInspectionsForHistory(
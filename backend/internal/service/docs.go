@@ -10,13 +10,16 @@ import (
 
 type DocsRepository interface {
 	GetAllOrganizations(ctx context.Context) ([]domain.Organization, error)
-	PostNewOrganization(ctx context.Context, org domain.Organization) error
+	GetOrganizationByOGRN(ctx context.Context, ogrn string) (domain.Organization, error)
+	PostNewOrganization(ctx context.Context, org domain.Organization) (domain.Organization, error)
 	DeleteOrganizationByID(ctx context.Context, org_id int) error
-	PutOrganizationByID(ctx context.Context, org domain.Organization, org_id int) error
+	PutOrganizationByID(ctx context.Context, org domain.Organization, org_id int) (domain.Organization, error)
 
 	GetLeadersByOrgID(ctx context.Context, org_id int) ([]domain.Leader, error)
-	PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_id int) error
-	DeleteLeaderByID(ctx context.Context, id int) error
+	GetLeaderOnDate(ctx context.Context, org_id int, date string) (domain.Leader, error)
+	PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_id int) (domain.Leader, error)
+	PutLeaderByID(ctx context.Context, leader domain.Leader, id int) (domain.Leader, error)
+	RetireLeaderByID(ctx context.Context, id int) (domain.Leader, error)
 
 	GetAllInspectionsForHistory(ctx context.Context) ([]domain.InspectionHistoryItem, error)
 	GetInspectionByID(ctx context.Context, id int) (domain.Inspection, error)
@@ -36,7 +39,11 @@ func (d *Docs) GetAllOrganizations(ctx context.Context) ([]domain.Organization,
 	return d.repo.GetAllOrganizations(ctx)
 }
 
-func (d *Docs) PostNewOrganization(ctx context.Context, org domain.Organization) error {
+func (d *Docs) GetOrganizationByOGRN(ctx context.Context, ogrn string) (domain.Organization, error) {
+	return d.repo.GetOrganizationByOGRN(ctx, ogrn)
+}
+
+func (d *Docs) PostNewOrganization(ctx context.Context, org domain.Organization) (domain.Organization, error) {
 	return d.repo.PostNewOrganization(ctx, org)
 }
 
@@ -44,34 +51,38 @@ func (d *Docs) GetLeadersByOrgID(ctx context.Context, org_id int) ([]domain.Lead
 	return d.repo.GetLeadersByOrgID(ctx, org_id)
 }
 
-func (d *Docs) PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_id int) error {
+func (d *Docs) GetLeaderOnDate(ctx context.Context, org_id int, date string) (domain.Leader, error) {
+	return d.repo.GetLeaderOnDate(ctx, org_id, date)
+}
+
+func (d *Docs) PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_id int) (domain.Leader, error) {
 	return d.repo.PostLeaderByOrgID(ctx, leader, org_id)
 }
 
 func (d *Docs) DeleteOrganizationByID(ctx context.Context, org_id int) error {
 	err := d.repo.DeleteOrganizationByID(ctx, org_id)
-	if errors.Is(err, e.ErrOrganizationNotFound){
+	if errors.Is(err, e.ErrOrganizationNotFound) {
 		return nil
 	}
 	return err
 }
 
-func (d *Docs) DeleteLeaderByID(ctx context.Context, id int) error{
-	err := d.repo.DeleteLeaderByID(ctx, id)
-	if errors.Is(err, e.ErrLeaderNotFound){
-		return nil
-	}
-	return err
+func (d *Docs) PutLeaderByID(ctx context.Context, leader domain.Leader, id int) (domain.Leader, error) {
+	return d.repo.PutLeaderByID(ctx, leader, id)
 }
 
-func (d *Docs) PutOrganizationByID(ctx context.Context, org domain.Organization, org_id int) error{
+func (d *Docs) RetireLeaderByID(ctx context.Context, id int) (domain.Leader, error) {
+	return d.repo.RetireLeaderByID(ctx, id)
+}
+
+func (d *Docs) PutOrganizationByID(ctx context.Context, org domain.Organization, org_id int) (domain.Organization, error) {
 	return d.repo.PutOrganizationByID(ctx, org, org_id)
 }
 
-func (d *Docs) GetAllInspectionsForHistory(ctx context.Context) ([]domain.InspectionHistoryItem, error){
+func (d *Docs) GetAllInspectionsForHistory(ctx context.Context) ([]domain.InspectionHistoryItem, error) {
 	return d.repo.GetAllInspectionsForHistory(ctx)
 }
 
 func (d *Docs) GetInspectionByID(ctx context.Context, id int) (domain.Inspection, error) {
 	return d.repo.GetInspectionByID(ctx, id)
-}
\ No newline at end of file
+}
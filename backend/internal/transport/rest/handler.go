@@ -7,21 +7,28 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/apierror"
 	"github.com/DexScen/DocGenerationWebApp/backend/internal/domain"
 	e "github.com/DexScen/DocGenerationWebApp/backend/internal/errors"
+	"github.com/DexScen/DocGenerationWebApp/backend/internal/validation"
 	"github.com/gorilla/mux"
 )
 
 type Docs interface {
 	GetAllOrganizations(ctx context.Context) ([]domain.Organization, error)
-	PostNewOrganization(ctx context.Context, org domain.Organization) error
+	GetOrganizationByOGRN(ctx context.Context, ogrn string) (domain.Organization, error)
+	PostNewOrganization(ctx context.Context, org domain.Organization) (domain.Organization, error)
 	DeleteOrganizationByID(ctx context.Context, org_id int) error
-	PutOrganizationByID(ctx context.Context, org domain.Organization, org_id int) error
+	PutOrganizationByID(ctx context.Context, org domain.Organization, org_id int) (domain.Organization, error)
 
 	GetLeadersByOrgID(ctx context.Context, org_id int) ([]domain.Leader, error)
-	PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_id int) error
-	DeleteLeaderByID(ctx context.Context, id int) error
+	GetLeaderOnDate(ctx context.Context, org_id int, date string) (domain.Leader, error)
+	PostLeaderByOrgID(ctx context.Context, leader domain.Leader, org_id int) (domain.Leader, error)
+	PutLeaderByID(ctx context.Context, leader domain.Leader, id int) (domain.Leader, error)
+	RetireLeaderByID(ctx context.Context, id int) (domain.Leader, error)
 
 	GetAllInspectionsForHistory(ctx context.Context) ([]domain.InspectionHistoryItem, error)
 	GetInspectionByID(ctx context.Context, id int) (domain.Inspection, error)
@@ -37,39 +44,38 @@ func NewDocs(docs Docs) *Handler {
 	}
 }
 
-func (h *Handler) InitRouter() *mux.Router {
-	r := mux.NewRouter().StrictSlash(true)
-	r.Use(loggingMiddleware)
-	r.Use(corsMiddleware)
-
-	links := r.PathPrefix("").Subrouter()
-	{
-		links.HandleFunc("/organizations", h.GetAllOrganizations).Methods(http.MethodGet)
-		links.HandleFunc("/organizations", h.PostNewOrganization).Methods(http.MethodPost)
-		links.HandleFunc("/organizations/{id}", h.DeleteOrganizationByID).Methods(http.MethodDelete)
-		links.HandleFunc("/organizations/{id}", h.PutOrganizationByID).Methods(http.MethodPut)
-
-		links.HandleFunc("/leaders/{id}", h.DeleteLeaderByID).Methods(http.MethodDelete)
-		links.HandleFunc("/leaders/{id}", h.GetLeadersByOrgID).Methods(http.MethodGet) // ORG_ID а не leaderID
-		links.HandleFunc("/leaders/{id}", h.PostLeaderByOrgID).Methods(http.MethodPost)
-
-		links.HandleFunc("/inspections", h.GetAllInspectionsForHistory).Methods(http.MethodGet)
-		links.HandleFunc("/inspections/{id}", h.GetInspectionByID).Methods(http.MethodGet)
-	}
-	return r
+// Middleware wraps the handler of a route, e.g. to check the session.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Register mounts the organization registry and its leaders on r. Every route
+// is wrapped by auth; the ones changing or deleting registry records by admin
+// instead. Creating an organization is also wrapped by idempotent. The inspection history of this layer reads the former inspection
+// tables and is not mounted: /inspections is served from the act tables.
+func (h *Handler) Register(r *mux.Router, auth, admin, idempotent Middleware) {
+	r.HandleFunc("/organizations", auth(h.GetAllOrganizations)).Methods(http.MethodGet)
+	r.HandleFunc("/organizations", idempotent(auth(h.PostNewOrganization))).Methods(http.MethodPost)
+	r.HandleFunc("/organizations/{ogrn:[0-9]{13,15}}", auth(h.GetOrganizationByOGRN)).Methods(http.MethodGet)
+	r.HandleFunc("/organizations/{id:[0-9]+}", admin(h.DeleteOrganizationByID)).Methods(http.MethodDelete)
+	r.HandleFunc("/organizations/{id:[0-9]+}", admin(h.PutOrganizationByID)).Methods(http.MethodPut)
+
+	r.HandleFunc("/organizations/{id:[0-9]+}/leaders", auth(h.GetLeadersByOrgID)).Methods(http.MethodGet)
+	r.HandleFunc("/organizations/{id:[0-9]+}/leaders", auth(h.PostLeaderByOrgID)).Methods(http.MethodPost)
+	r.HandleFunc("/organizations/{id:[0-9]+}/leaders/current", auth(h.GetLeaderOnDate)).Methods(http.MethodGet)
+	r.HandleFunc("/leaders/{id:[0-9]+}", admin(h.PutLeaderByID)).Methods(http.MethodPut)
+	r.HandleFunc("/leaders/{id:[0-9]+}", admin(h.RetireLeaderByID)).Methods(http.MethodDelete)
 }
 
 func (h *Handler) GetAllOrganizations(w http.ResponseWriter, r *http.Request) {
-	list, err := h.docsService.GetAllOrganizations(context.TODO())
+	list, err := h.docsService.GetAllOrganizations(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetAllOrganizations error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить организации"))
 		return
 	}
 
 	if jsonResp, err := json.Marshal(list); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetAllOrganizations error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить организации"))
 		return
 	} else {
 		w.Header().Set("Content-Type", "application/json")
@@ -78,23 +84,81 @@ func (h *Handler) GetAllOrganizations(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *Handler) GetOrganizationByOGRN(w http.ResponseWriter, r *http.Request) {
+	ogrn := mux.Vars(r)["ogrn"]
+	if !validation.OGRN(ogrn) {
+		apierror.Write(w, apierror.BadRequest("Некорректный ОГРН"))
+		return
+	}
+
+	org, err := h.docsService.GetOrganizationByOGRN(r.Context(), ogrn)
+	if err != nil {
+		if errors.Is(err, e.ErrOrganizationNotFound) {
+			apierror.Write(w, apierror.ErrOrganizationNotFound)
+			return
+		}
+		log.Println("GetOrganizationByOGRN error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить организацию"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, org)
+}
+
 func (h *Handler) PostNewOrganization(w http.ResponseWriter, r *http.Request) {
 	var org domain.Organization
 	if err := json.NewDecoder(r.Body).Decode(&org); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("PostNewOrganization error:", err)
+		apierror.Write(w, apierror.ErrInvalidJSON)
+		return
+	}
+	if err := validateOrganization(&org); err != nil {
+		apierror.Write(w, apierror.ErrValidation.WithDetails(err))
 		return
 	}
 
-	err := h.docsService.PostNewOrganization(context.TODO(), org)
+	saved, err := h.docsService.PostNewOrganization(r.Context(), org)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("PostNewOrganization error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось сохранить организацию"))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	// A repeat with a known OGRN answers like the first request, with the
+	// stored record.
+	writeJSON(w, http.StatusCreated, saved)
+}
+
+// validateOrganization trims the fields of a registry record and checks the
+// required ones and the OGRN checksum.
+func validateOrganization(org *domain.Organization) validation.Errors {
+	org.FullName = strings.TrimSpace(org.FullName)
+	org.ShortName = strings.TrimSpace(org.ShortName)
+	org.OGRN = strings.TrimSpace(org.OGRN)
+	org.LegalAddress = strings.TrimSpace(org.LegalAddress)
+	org.PostalAddress = strings.TrimSpace(org.PostalAddress)
+
+	v := validation.New()
+	v.Required("full_name", org.FullName, "Укажите полное наименование")
+	if v.Required("ogrn", org.OGRN, "Укажите ОГРН или ОГРНИП") && !validation.OGRN(org.OGRN) {
+		v.Add("ogrn", validation.CodeChecksum, "Некорректный ОГРН (13 цифр) или ОГРНИП (15 цифр)")
+	}
+	v.Required("legal_address", org.LegalAddress, "Укажите юридический адрес")
+	return fieldErrors(v)
+}
+
+func fieldErrors(v *validation.Validator) validation.Errors {
+	var errs validation.Errors
+	if errors.As(v.Err(), &errs) {
+		return errs
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
 }
 
 func (h *Handler) GetLeadersByOrgID(w http.ResponseWriter, r *http.Request) {
@@ -102,27 +166,19 @@ func (h *Handler) GetLeadersByOrgID(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetLeadersByOrgID error:", err)
+		apierror.Write(w, apierror.ErrInvalidID)
 		return
 	}
 
-	leaders, err := h.docsService.GetLeadersByOrgID(context.TODO(), id)
+	leaders, err := h.docsService.GetLeadersByOrgID(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetLeadersByOrgID error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить руководителей"))
 		return
 	}
 
-	if jsonResp, err := json.Marshal(leaders); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("GetLeadersByOrgID error:", err)
-		return
-	} else {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(jsonResp)
-	}
+	writeJSON(w, http.StatusOK, leaders)
 }
 
 func (h *Handler) PostLeaderByOrgID(w http.ResponseWriter, r *http.Request) {
@@ -130,66 +186,171 @@ func (h *Handler) PostLeaderByOrgID(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("PostLeaderByOrgID error:", err)
+		apierror.Write(w, apierror.ErrInvalidID)
 		return
 	}
 
 	var leader domain.Leader
 	if err := json.NewDecoder(r.Body).Decode(&leader); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("PostLeaderByOrgID error:", err)
+		apierror.Write(w, apierror.ErrInvalidJSON)
+		return
+	}
+	if err := validateLeader(&leader); err != nil {
+		apierror.Write(w, apierror.ErrValidation.WithDetails(err))
 		return
 	}
 
-	if err := h.docsService.PostLeaderByOrgID(context.TODO(), leader, id); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("PostLeaderByOrgID error:", err)
+	created, err := h.docsService.PostLeaderByOrgID(r.Context(), leader, id)
+	if err != nil {
+		writeLeaderError(w, "PostLeaderByOrgID", err, "Не удалось сохранить руководителя")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, http.StatusCreated, created)
 }
 
-func (h *Handler) DeleteOrganizationByID(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
-	id, err := strconv.Atoi(idStr)
+// GetLeaderOnDate returns the leader in charge on ?on=YYYY-MM-DD, today by
+// default.
+func (h *Handler) GetLeaderOnDate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("DeleteOrganizationByID error:", err)
+		apierror.Write(w, apierror.ErrInvalidID)
+		return
+	}
+	date := strings.TrimSpace(r.URL.Query().Get("on"))
+	if date == "" {
+		date = time.Now().Format(validation.DateLayout)
+	} else if _, err := time.Parse(validation.DateLayout, date); err != nil {
+		apierror.Write(w, apierror.BadRequest("Дата должна быть в формате ГГГГ-ММ-ДД"))
 		return
 	}
 
-	if err := h.docsService.DeleteOrganizationByID(context.TODO(), id); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("DeleteOrganizationByID error:", err)
+	leader, err := h.docsService.GetLeaderOnDate(r.Context(), id, date)
+	if err != nil {
+		if errors.Is(err, e.ErrLeaderNotFound) {
+			apierror.Write(w, apierror.ErrLeaderNotFound)
+			return
+		}
+		log.Println("GetLeaderOnDate error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить руководителя"))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusGone)
+	writeJSON(w, http.StatusOK, leader)
 }
 
-func (h *Handler) DeleteLeaderByID(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) PutLeaderByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, apierror.ErrInvalidID)
+		return
+	}
+
+	var leader domain.Leader
+	if err := json.NewDecoder(r.Body).Decode(&leader); err != nil {
+		apierror.Write(w, apierror.ErrInvalidJSON)
+		return
+	}
+	if err := validateLeader(&leader); err != nil {
+		apierror.Write(w, apierror.ErrValidation.WithDetails(err))
+		return
+	}
+
+	updated, err := h.docsService.PutLeaderByID(r.Context(), leader, id)
+	if err != nil {
+		writeLeaderError(w, "PutLeaderByID", err, "Не удалось обновить руководителя")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// RetireLeaderByID ends the term of the leader; the record is kept for the
+// acts of the past.
+func (h *Handler) RetireLeaderByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, apierror.ErrInvalidID)
+		return
+	}
+
+	leader, err := h.docsService.RetireLeaderByID(r.Context(), id)
+	if err != nil {
+		writeLeaderError(w, "RetireLeaderByID", err, "Не удалось завершить полномочия руководителя")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, leader)
+}
+
+func writeLeaderError(w http.ResponseWriter, action string, err error, message string) {
+	switch {
+	case errors.Is(err, e.ErrLeaderNotFound):
+		apierror.Write(w, apierror.ErrLeaderNotFound)
+	case errors.Is(err, e.ErrOrganizationNotFound):
+		apierror.Write(w, apierror.ErrOrganizationNotFound)
+	case errors.Is(err, e.ErrLeaderPeriodOverlap):
+		apierror.Write(w, apierror.ErrLeaderPeriodOverlap)
+	default:
+		log.Println(action+" error:", err)
+		apierror.Write(w, apierror.Internal(message))
+	}
+}
+
+func validateLeader(leader *domain.Leader) validation.Errors {
+	leader.Position = strings.TrimSpace(leader.Position)
+	leader.LastName = strings.TrimSpace(leader.LastName)
+	leader.FirstName = strings.TrimSpace(leader.FirstName)
+	leader.MiddleName = strings.TrimSpace(leader.MiddleName)
+	leader.InitialsNameIm = strings.TrimSpace(leader.InitialsNameIm)
+	leader.InitialsNameDat = strings.TrimSpace(leader.InitialsNameDat)
+
+	v := validation.New()
+	v.Required("position", leader.Position, "Укажите должность")
+	v.Required("last_name", leader.LastName, "Укажите фамилию")
+	v.Required("first_name", leader.FirstName, "Укажите имя")
+
+	leader.EffectiveFrom = strings.TrimSpace(leader.EffectiveFrom)
+	var from, to *time.Time
+	if v.Required("effective_from", leader.EffectiveFrom, "Укажите дату вступления в должность") {
+		from = v.Date("effective_from", leader.EffectiveFrom)
+	}
+	if leader.EffectiveTo != nil {
+		value := strings.TrimSpace(*leader.EffectiveTo)
+		if value == "" {
+			leader.EffectiveTo = nil
+		} else {
+			leader.EffectiveTo = &value
+			to = v.Date("effective_to", value)
+		}
+	}
+	v.NotBefore("effective_to", to, from, "Дата окончания полномочий раньше даты вступления в должность")
+	return fieldErrors(v)
+}
+
+func (h *Handler) DeleteOrganizationByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("DeleteLeaderByID error:", err)
+		log.Println("DeleteOrganizationByID error:", err)
+		apierror.Write(w, apierror.ErrInvalidID)
 		return
 	}
 
-	if err := h.docsService.DeleteLeaderByID(context.TODO(), id); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println("DeleteLeaderByID error:", err)
+	if err := h.docsService.DeleteOrganizationByID(r.Context(), id); err != nil {
+		if errors.Is(err, e.ErrOrganizationInUse) {
+			apierror.Write(w, apierror.ErrOrganizationInUse)
+			return
+		}
+		log.Println("DeleteOrganizationByID error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось удалить организацию"))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusGone)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *Handler) PutOrganizationByID(w http.ResponseWriter, r *http.Request) {
@@ -197,45 +358,52 @@ func (h *Handler) PutOrganizationByID(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("PutOrganizationByID error:", err)
+		apierror.Write(w, apierror.ErrInvalidID)
 		return
 	}
 
 	var org domain.Organization
 	if err := json.NewDecoder(r.Body).Decode(&org); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("PutOrganizationByID error:", err)
+		apierror.Write(w, apierror.ErrInvalidJSON)
+		return
+	}
+	if err := validateOrganization(&org); err != nil {
+		apierror.Write(w, apierror.ErrValidation.WithDetails(err))
 		return
 	}
 
-	if err := h.docsService.PutOrganizationByID(context.TODO(), org, id); err != nil { //err no such id
-		if errors.Is(err, e.ErrOrganizationNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+	updated, err := h.docsService.PutOrganizationByID(r.Context(), org, id)
+	if err != nil {
+		if errors.Is(err, e.ErrOrganizationExists) {
+			apierror.Write(w, apierror.ErrOrganizationExists)
+			return
+		} else if errors.Is(err, e.ErrOrganizationNotFound) {
 			log.Println("PutOrganization error:", err)
+			apierror.Write(w, apierror.ErrOrganizationNotFound)
 			return
 		} else {
-			w.WriteHeader(http.StatusInternalServerError)
 			log.Println("PutOrganization error:", err)
+			apierror.Write(w, apierror.Internal("Не удалось обновить организацию"))
 			return
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, http.StatusOK, updated)
 }
 
-func (h *Handler) GetAllInspectionsForHistory(w http.ResponseWriter, r *http.Request){
-	list, err := h.docsService.GetAllInspectionsForHistory(context.TODO())
+func (h *Handler) GetAllInspectionsForHistory(w http.ResponseWriter, r *http.Request) {
+	list, err := h.docsService.GetAllInspectionsForHistory(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetAllInspectionsForHistory error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить проверки"))
 		return
 	}
 
 	if jsonResp, err := json.Marshal(list); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetAllInspectionsForHistory error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить проверки"))
 		return
 	} else {
 		w.Header().Set("Content-Type", "application/json")
@@ -244,30 +412,30 @@ func (h *Handler) GetAllInspectionsForHistory(w http.ResponseWriter, r *http.Req
 	}
 }
 
-func (h *Handler) GetInspectionByID(w http.ResponseWriter, r *http.Request){
+func (h *Handler) GetInspectionByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetInspectionByID error:", err)
+		apierror.Write(w, apierror.ErrInvalidID)
 		return
 	}
 
-	inspection, err := h.docsService.GetInspectionByID(context.TODO(), id)
+	inspection, err := h.docsService.GetInspectionByID(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetInspectionByID error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить проверку"))
 		return
 	}
 
 	if jsonResp, err := json.Marshal(inspection); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("GetInspectionByID error:", err)
+		apierror.Write(w, apierror.Internal("Не удалось загрузить проверку"))
 		return
 	} else {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write(jsonResp)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,31 @@
+package validation
+
+// INN checks a 10-digit ИНН of an organization or a 12-digit ИНН of a
+// person including the control digits: the weighted sum of the preceding
+// digits modulo 11, taken modulo 10.
+func INN(value string) bool {
+	digits := make([]int, 0, len(value))
+	for _, ch := range value {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+		digits = append(digits, int(ch-'0'))
+	}
+	switch len(digits) {
+	case 10:
+		return innControl(digits[:9], []int{2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[9]
+	case 12:
+		return innControl(digits[:10], []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[10] &&
+			innControl(digits[:11], []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == digits[11]
+	default:
+		return false
+	}
+}
+
+func innControl(digits, weights []int) int {
+	sum := 0
+	for i, digit := range digits {
+		sum += digit * weights[i]
+	}
+	return sum % 11 % 10
+}
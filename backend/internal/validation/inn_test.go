@@ -0,0 +1,27 @@
+package validation
+
+import "testing"
+
+func TestINN(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"7707083893", true},
+		{"7830002293", true},
+		{"500100732259", true},
+		{"773370857141", true},
+		{"7707083894", false},   // wrong control digit
+		{"500100732269", false}, // wrong first control digit of 12
+		{"500100732258", false}, // wrong second control digit of 12
+		{"770708389", false},
+		{"77070838931", false},
+		{"770708389a", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := INN(tt.value); got != tt.want {
+			t.Errorf("INN(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
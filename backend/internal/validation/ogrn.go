@@ -0,0 +1,29 @@
+package validation
+
+// OGRN checks a 13-digit ОГРН or a 15-digit ОГРНИП including its control
+// digit: the remainder of the leading digits divided by 11 (ОГРН) or 13
+// (ОГРНИП), taken modulo 10, must equal the last digit.
+func OGRN(value string) bool {
+	var divisor uint64
+	switch len(value) {
+	case 13:
+		divisor = 11
+	case 15:
+		divisor = 13
+	default:
+		return false
+	}
+
+	var number uint64
+	for _, ch := range value[:len(value)-1] {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+		number = number*10 + uint64(ch-'0')
+	}
+	last := value[len(value)-1]
+	if last < '0' || last > '9' {
+		return false
+	}
+	return number%divisor%10 == uint64(last-'0')
+}
@@ -0,0 +1,26 @@
+package validation
+
+import "testing"
+
+func TestOGRN(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"1027700132195", true},
+		{"1027739642281", true},
+		{"304500116000157", true},
+		{"1027700132196", false},   // wrong control digit
+		{"304500116000158", false}, // wrong control digit of ОГРНИП
+		{"102770013219", false},    // 12 digits
+		{"10277001321950", false},  // 14 digits
+		{"102770013219a", false},
+		{"10277001321 5", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := OGRN(tt.value); got != tt.want {
+			t.Errorf("OGRN(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package validation
+
+import (
+	"strings"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+const (
+	CodeRequired      = "required"
+	CodeInvalidFormat = "invalid_format"
+	CodeInvalidValue  = "invalid_value"
+	CodeChecksum      = "checksum"
+	CodeOutOfRange    = "out_of_range"
+)
+
+// FieldError describes a problem with a single field. Field is the JSON path
+// of the value in the request body, e.g. "inspection.period.startDate".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is returned by Validator.Err when at least one check failed.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, item := range e {
+		messages = append(messages, item.Field+": "+item.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator collects field errors so that every problem of a request is
+// reported at once.
+type Validator struct {
+	errors Errors
+}
+
+func New() *Validator {
+	return &Validator{}
+}
+
+func (v *Validator) Add(field, code, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Code: code, Message: message})
+}
+
+// HasError reports whether field already has an error, so dependent checks can
+// be skipped.
+func (v *Validator) HasError(field string) bool {
+	for _, item := range v.errors {
+		if item.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) Required(field, value, message string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, CodeRequired, message)
+		return false
+	}
+	return true
+}
+
+func (v *Validator) OneOf(field, value string, allowed []string, message string) bool {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return true
+		}
+	}
+	v.Add(field, CodeInvalidValue, message)
+	return false
+}
+
+// Date parses an optional YYYY-MM-DD value. Blank values return nil without
+// an error; malformed ones are reported.
+func (v *Validator) Date(field, value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.Parse(DateLayout, value)
+	if err != nil {
+		v.Add(field, CodeInvalidFormat, "Некорректная дата, ожидается ГГГГ-ММ-ДД")
+		return nil
+	}
+	return &parsed
+}
+
+// NotBefore reports an error on field when value is earlier than bound. Nil
+// dates are not compared.
+func (v *Validator) NotBefore(field string, value, bound *time.Time, message string) {
+	if value == nil || bound == nil {
+		return
+	}
+	if value.Before(*bound) {
+		v.Add(field, CodeOutOfRange, message)
+	}
+}
+
+// NotAfter reports an error on field when value is later than bound. Nil
+// dates are not compared.
+func (v *Validator) NotAfter(field string, value, bound *time.Time, message string) {
+	if value == nil || bound == nil {
+		return
+	}
+	if value.After(*bound) {
+		v.Add(field, CodeOutOfRange, message)
+	}
+}
+
+func (v *Validator) Err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
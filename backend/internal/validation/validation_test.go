@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidatorDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		invalid bool
+	}{
+		{value: "2024-02-29", want: "2024-02-29"},
+		{value: " 2024-03-01 ", want: "2024-03-01"},
+		{value: ""},
+		{value: "   "},
+		{value: "2023-02-29", invalid: true},
+		{value: "01.03.2024", invalid: true},
+		{value: "2024-3-1", invalid: true},
+	}
+	for _, tt := range tests {
+		v := New()
+		got := v.Date("date", tt.value)
+		if tt.invalid != v.HasError("date") {
+			t.Errorf("Date(%q): error = %v, want %v", tt.value, v.HasError("date"), tt.invalid)
+		}
+		switch {
+		case tt.want == "" && got != nil:
+			t.Errorf("Date(%q) = %v, want nil", tt.value, got)
+		case tt.want != "" && (got == nil || got.Format(DateLayout) != tt.want):
+			t.Errorf("Date(%q) = %v, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	v := New()
+	v.Date("date", "bad")
+	var errs Errors
+	if !errors.As(v.Err(), &errs) || errs[0].Code != CodeInvalidFormat {
+		t.Errorf("Date error = %v, want code %s", v.Err(), CodeInvalidFormat)
+	}
+}
+
+func TestValidatorNotBefore(t *testing.T) {
+	day := func(value string) *time.Time {
+		parsed, _ := time.Parse(DateLayout, value)
+		return &parsed
+	}
+	tests := []struct {
+		name         string
+		value, bound *time.Time
+		invalid      bool
+	}{
+		{"later", day("2024-03-02"), day("2024-03-01"), false},
+		{"same day", day("2024-03-01"), day("2024-03-01"), false},
+		{"earlier", day("2024-02-29"), day("2024-03-01"), true},
+		{"no value", nil, day("2024-03-01"), false},
+		{"no bound", day("2024-03-01"), nil, false},
+	}
+	for _, tt := range tests {
+		v := New()
+		v.NotBefore("end", tt.value, tt.bound, "Дата окончания раньше даты начала")
+		if got := v.Err() != nil; got != tt.invalid {
+			t.Errorf("%s: error = %v, want %v", tt.name, got, tt.invalid)
+		}
+		var errs Errors
+		if tt.invalid && (!errors.As(v.Err(), &errs) || errs[0].Field != "end" || errs[0].Code != CodeOutOfRange) {
+			t.Errorf("%s: error = %#v, want end/%s", tt.name, v.Err(), CodeOutOfRange)
+		}
+	}
+}
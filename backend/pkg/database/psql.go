@@ -1,29 +0,0 @@
-package database
-
-import (
-	"database/sql"
-	"fmt"
-)
-
-type ConnectionInfo struct {
-	Host     string
-	Port     int
-	Username string
-	DBName   string
-	SSLMode  string
-	Password string
-}
-
-func NewPostgresConnection(info ConnectionInfo) (*sql.DB, error) {
-	db, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%d user=%s dbname=%s sslmode=%s password=%s",
-		info.Host, info.Port, info.Username, info.DBName, info.SSLMode, info.Password))
-	if err != nil {
-		return nil, err
-	}
-
-	if err := db.Ping(); err != nil {
-		return nil, err
-	}
-
-	return db, nil
-}
\ No newline at end of file
@@ -206,6 +206,7 @@
       const message = data?.message || "Ошибка запроса";
       const error = new Error(message);
       error.status = response.status;
+      error.fields = Array.isArray(data?.details) ? data.details : [];
       throw error;
     }
     return data;
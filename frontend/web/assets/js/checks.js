@@ -117,6 +117,8 @@
   const downloadDocxBtn = document.getElementById("downloadDocxBtn");
   const downloadPdfBtn = document.getElementById("downloadPdfBtn");
   const downloadHint = document.getElementById("downloadModalHint");
+  const sendRecipientsInput = document.getElementById("sendDocumentRecipients");
+  const sendDocumentBtn = document.getElementById("sendDocumentBtn");
   const yearInput = document.getElementById("checksYearFilter");
   const ogrnInput = document.getElementById("checksOgrnFilter");
   const paginationTopEl = document.getElementById("checksPaginationTop");
@@ -248,6 +250,51 @@
     });
   }
 
+  async function sendInspectionDocument(item) {
+    const inspectionId = getInspectionId(item);
+    if (!inspectionId) {
+      throw new Error("Не выбрана проверка для отправки документа.");
+    }
+    const recipients = (sendRecipientsInput?.value || "")
+      .split(/[,;\s]+/)
+      .map((value) => value.trim())
+      .filter(Boolean);
+    return window.Api.request(`/inspections/${inspectionId}/documents/act/send`, {
+      method: "POST",
+      body: JSON.stringify({ recipients }),
+    });
+  }
+
+  if (sendDocumentBtn) {
+    sendDocumentBtn.addEventListener("click", async () => {
+      if (!selectedInspection) {
+        window.AppDialog?.openDialog?.("Выберите проверку для отправки документа.", "Отправка");
+        return;
+      }
+      sendDocumentBtn.disabled = true;
+      try {
+        const result = await sendInspectionDocument(selectedInspection);
+        let message = result.failed
+          ? `Отправлено: ${result.sent}, не удалось: ${result.failed}.`
+          : `Документ отправлен (${result.sent}).`;
+        if (result.not_logged) {
+          message += `\nНе записано в журнал отправки: ${result.not_logged}.`;
+        }
+        if (sendRecipientsInput) sendRecipientsInput.value = "";
+        closeDownloadModal();
+        window.AppDialog?.openDialog?.(message, "Отправка");
+      } catch (error) {
+        const details = (error.fields || [])
+          .map((item) => item.message || item.error)
+          .filter(Boolean);
+        const message = [error.message || "Ошибка отправки документа.", ...details].join("\n");
+        window.AppDialog?.openDialog?.(message, "Отправка");
+      } finally {
+        sendDocumentBtn.disabled = false;
+      }
+    });
+  }
+
   function formatCheckTitle(item) {
     const number = item?.inspection?.number ? `№${item.inspection.number}` : "Без номера";
     const orgName = item?.organization?.shortName || "Без сокращенного наименования";
@@ -298,9 +345,13 @@
     const inspectors = Array.isArray(item?.inspection?.inspectors)
       ? item.inspection.inspectors.join(", ") || "—"
       : item?.inspection?.inspectors || "—";
+    const lastInspected = item?.last_inspected_on
+      ? formatDate(item.last_inspected_on)
+      : "не проверялась";
     return [
       { label: "Форма проверки", value: formType },
       { label: "Период проверки", value: periodText },
+      { label: "Предыдущая проверка", value: lastInspected },
       { label: "Представители", value: representatives },
       { label: "Проверяющие", value: inspectors },
     ];
@@ -419,6 +470,14 @@
         }
       });
 
+      const findingsBtn = document.createElement("button");
+      findingsBtn.className = "btn btn--ghost";
+      findingsBtn.type = "button";
+      findingsBtn.textContent = "Нарушения";
+      findingsBtn.addEventListener("click", () => {
+        window.FindingsModal?.open?.(item);
+      });
+
       const downloadBtn = document.createElement("button");
       downloadBtn.className = "btn btn--ghost";
       downloadBtn.type = "button";
@@ -429,6 +488,7 @@
 
       actions.appendChild(openBtn);
       actions.appendChild(editBtn);
+      actions.appendChild(findingsBtn);
       actions.appendChild(downloadBtn);
       actions.appendChild(deleteBtn);
 
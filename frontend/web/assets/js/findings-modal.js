@@ -0,0 +1,189 @@
+(function () {
+  const backdrop = document.getElementById("findingsModalBackdrop");
+  if (!backdrop) return;
+
+  const closeBtn = document.getElementById("findingsModalClose");
+  const cancelBtn = document.getElementById("findingsCancelBtn");
+  const saveBtn = document.getElementById("findingsSaveBtn");
+  const addBtn = document.getElementById("findingsAddBtn");
+  const listEl = document.getElementById("findingsList");
+  const hintEl = document.getElementById("findingsModalHint");
+  const areaOptions = document.getElementById("findingsAreaOptions");
+
+  const KINDS = ["нарушение", "недостаток"];
+
+  let inspectionId = null;
+  // Пункты уровня 2 по названию области — подсказки для поля «Пункт».
+  let areaItems = new Map();
+
+  function setHint(text) {
+    if (hintEl) hintEl.textContent = text;
+  }
+
+  async function loadAreas() {
+    try {
+      const data = await window.Api.request("/verification-areas");
+      const items = Array.isArray(data?.items) ? data.items : [];
+      areaItems = new Map();
+      items.forEach((area) => {
+        const name = (area?.level1 || "").trim();
+        if (!name) return;
+        const level2 = Array.isArray(area?.level2) ? area.level2 : [];
+        areaItems.set(
+          name,
+          level2.map((item) => (typeof item === "string" ? item : item?.name || "")).filter(Boolean)
+        );
+      });
+    } catch (error) {
+      console.warn("Не удалось загрузить области проверки", error);
+    }
+    if (!areaOptions) return;
+    areaOptions.innerHTML = "";
+    areaItems.forEach((_items, name) => {
+      const option = document.createElement("option");
+      option.value = name;
+      areaOptions.appendChild(option);
+    });
+  }
+
+  function fillItemOptions(datalist, area) {
+    datalist.innerHTML = "";
+    (areaItems.get(area.trim()) || []).forEach((name) => {
+      const option = document.createElement("option");
+      option.value = name;
+      datalist.appendChild(option);
+    });
+  }
+
+  function addRow(finding = {}) {
+    const row = document.createElement("div");
+    row.className = "findings-row";
+
+    const kind = document.createElement("select");
+    kind.className = "input";
+    kind.dataset.field = "kind";
+    KINDS.forEach((value) => {
+      const option = document.createElement("option");
+      option.value = value;
+      option.textContent = value;
+      kind.appendChild(option);
+    });
+    kind.value = KINDS.includes(finding.kind) ? finding.kind : KINDS[0];
+
+    const area = document.createElement("input");
+    area.className = "input";
+    area.dataset.field = "area";
+    area.placeholder = "Область проверки";
+    area.autocomplete = "off";
+    area.setAttribute("list", "findingsAreaOptions");
+    area.value = finding.area || "";
+
+    const itemOptions = document.createElement("datalist");
+    itemOptions.id = `findingsItemOptions-${Date.now()}-${Math.random().toString(16).slice(2)}`;
+    const item = document.createElement("input");
+    item.className = "input";
+    item.dataset.field = "item";
+    item.placeholder = "Пункт";
+    item.autocomplete = "off";
+    item.setAttribute("list", itemOptions.id);
+    item.value = finding.item || "";
+    fillItemOptions(itemOptions, area.value);
+    area.addEventListener("change", () => fillItemOptions(itemOptions, area.value));
+
+    const removeBtn = document.createElement("button");
+    removeBtn.className = "btn btn--ghost";
+    removeBtn.type = "button";
+    removeBtn.textContent = "Удалить";
+    removeBtn.addEventListener("click", () => row.remove());
+
+    const description = document.createElement("textarea");
+    description.className = "input input--multiline";
+    description.dataset.field = "description";
+    description.placeholder = "Описание";
+    description.value = finding.description || "";
+
+    row.append(kind, area, item, removeBtn, itemOptions, description);
+    listEl.appendChild(row);
+  }
+
+  function collectFindings() {
+    return Array.from(listEl.querySelectorAll(".findings-row"))
+      .map((row) => {
+        const value = (field) => (row.querySelector(`[data-field="${field}"]`)?.value || "").trim();
+        return {
+          kind: value("kind"),
+          area: value("area"),
+          item: value("item"),
+          description: value("description")
+        };
+      })
+      .filter((item) => item.area || item.item || item.description);
+  }
+
+  function describeFieldError(item) {
+    const match = /^items\[(\d+)\]/.exec(item.field || "");
+    const prefix = match ? `Строка ${Number(match[1]) + 1}: ` : "";
+    return prefix + (item.message || "");
+  }
+
+  async function open(inspection) {
+    if (!inspection?.id || !listEl) return;
+    inspectionId = inspection.id;
+    listEl.innerHTML = "";
+    setHint("Загрузка…");
+    backdrop.hidden = false;
+    if (window.ModalScroll?.lock) {
+      window.ModalScroll.lock();
+    } else {
+      document.documentElement.classList.add("is-dialog-open");
+    }
+
+    await loadAreas();
+    try {
+      const data = await window.Api.request(`/inspections/${inspectionId}/findings`);
+      const items = Array.isArray(data?.items) ? data.items : [];
+      items.forEach(addRow);
+      setHint(items.length ? "" : "Нарушений и недостатков не внесено.");
+    } catch (error) {
+      setHint(error.message || "Не удалось загрузить нарушения.");
+    }
+  }
+
+  function close() {
+    backdrop.hidden = true;
+    inspectionId = null;
+    if (window.ModalScroll?.unlock) {
+      window.ModalScroll.unlock();
+    } else {
+      document.documentElement.classList.remove("is-dialog-open");
+    }
+  }
+
+  async function save() {
+    if (!inspectionId) return;
+    saveBtn.disabled = true;
+    try {
+      await window.Api.request(`/inspections/${inspectionId}/findings`, {
+        method: "PUT",
+        body: JSON.stringify({ items: collectFindings() })
+      });
+      close();
+    } catch (error) {
+      const details = (error.fields || []).map(describeFieldError).filter(Boolean);
+      const message = [error.message || "Ошибка сохранения нарушений.", ...details].join("\n");
+      window.AppDialog?.openDialog?.(message, "Нарушения");
+    } finally {
+      saveBtn.disabled = false;
+    }
+  }
+
+  addBtn?.addEventListener("click", () => {
+    setHint("");
+    addRow();
+  });
+  saveBtn?.addEventListener("click", save);
+  closeBtn?.addEventListener("click", close);
+  cancelBtn?.addEventListener("click", close);
+
+  window.FindingsModal = { open, close };
+})();
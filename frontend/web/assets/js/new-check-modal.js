@@ -105,6 +105,22 @@
     return value || "";
   }
 
+  // Адреса сами содержат запятые, поэтому в поле они разделены переводом
+  // строки (или точкой с запятой).
+  function parseAddressList(value) {
+    return String(value || "")
+      .split(/[\n;]/)
+      .map((item) => item.trim())
+      .filter(Boolean);
+  }
+
+  function formatAddressList(value) {
+    if (Array.isArray(value)) {
+      return value.join("\n");
+    }
+    return value || "";
+  }
+
   function parseListFromName(formRef, name) {
     if (!formRef) return [];
     const field = formRef.elements[name];
@@ -227,22 +243,24 @@
     appendBossRoleOption(normalized);
   }
 
-  async function fetchOrganizationByOgrn(ogrn) {
+  async function fetchOrganizationByOgrn(query) {
     if (!window.Api?.request) {
       throw new Error("Не настроен клиент API.");
     }
     return await window.Api.request("/dadata/organization", {
       method: "POST",
-      body: JSON.stringify({ ogrn })
+      body: JSON.stringify({ query })
     });
   }
 
+  // ИНН (10 или 12 цифр), ОГРН (13) или ОГРНИП (15).
   async function handleOgrnAutofill(rawValue) {
     const ogrnValue = normalizeSpace(rawValue);
-    if (!/^\d{13}$/.test(ogrnValue)) return;
+    if (!/^(\d{10}|\d{12}|\d{13}|\d{15})$/.test(ogrnValue)) return;
     try {
       const data = await fetchOrganizationByOgrn(ogrnValue);
       if (!data) return;
+      if (data.ogrn) setFieldValue(form, "ogrn", data.ogrn);
       setFieldValue(form, "orgName", data.name ?? "");
       setFieldValue(form, "orgShortName", data.shortName ?? "");
       setFieldValue(form, "legal_adress", data.legalAddress ?? "");
@@ -252,11 +270,74 @@
     } catch (error) {
       console.warn("Не удалось получить данные по ОГРН", error);
       if (window.AppDialog?.openDialog) {
-        window.AppDialog.openDialog("Не удалось получить данные по ОГРН. Проверьте номер и попробуйте снова.");
+        window.AppDialog.openDialog("Не удалось получить данные по ИНН или ОГРН. Проверьте номер и попробуйте снова.");
       }
     }
   }
 
+  // Подсказки организаций по наименованию: ОГРН выбранной подсказки
+  // запоминается, чтобы заполнить остальные поля.
+  const orgSuggestionOgrns = new Map();
+  let orgSuggestTimer = null;
+
+  async function updateOrgNameSuggestions(datalist, rawValue) {
+    const query = normalizeSpace(rawValue);
+    if (query.length < 3 || !window.Api?.request) return;
+    try {
+      const list = await window.Api.request("/dadata/organizations/suggest", {
+        method: "POST",
+        body: JSON.stringify({ query, count: 10 })
+      });
+      datalist.innerHTML = "";
+      orgSuggestionOgrns.clear();
+      (list || []).forEach((item) => {
+        if (!item.value || !item.ogrn) return;
+        orgSuggestionOgrns.set(item.value, item.ogrn);
+        const option = document.createElement("option");
+        option.value = item.value;
+        option.label = `ИНН ${item.inn}, ${item.legalAddress}`;
+        datalist.appendChild(option);
+      });
+    } catch (error) {
+      console.warn("Не удалось получить подсказки организаций", error);
+    }
+  }
+
+  // Подсказки адресов проверки: выбранный адрес добавляется в список
+  // адресов. Индекс сервер не возвращает.
+  const addressSuggestions = new Set();
+  let addressSuggestTimer = null;
+
+  async function updateAddressSuggestions(datalist, rawValue) {
+    const query = normalizeSpace(rawValue);
+    if (query.length < 3 || !window.Api?.request) return;
+    try {
+      const list = await window.Api.request("/dadata/addresses/suggest", {
+        method: "POST",
+        body: JSON.stringify({ query, count: 10 })
+      });
+      datalist.innerHTML = "";
+      addressSuggestions.clear();
+      (list || []).forEach((item) => {
+        if (!item.value) return;
+        addressSuggestions.add(item.value);
+        const option = document.createElement("option");
+        option.value = item.value;
+        datalist.appendChild(option);
+      });
+    } catch (error) {
+      console.warn("Не удалось получить подсказки адресов", error);
+    }
+  }
+
+  function addAddress(value) {
+    const field = form?.elements["addressNoIndex"];
+    if (!field) return;
+    const list = parseAddressList(field.value);
+    if (!list.includes(value)) list.push(value);
+    field.value = formatAddressList(list);
+  }
+
   function parseDateValue(value) {
     if (!value) return null;
     const date = new Date(value);
@@ -264,22 +345,28 @@
     return new Date(date.getFullYear(), date.getMonth(), date.getDate());
   }
 
-  function calculateDays(startValue, endValue) {
+  // Рабочие дни (пн–пт) от начала до окончания включительно. Поле можно
+  // исправить, например убрав праздники: сервер сохраняет введенное значение
+  // и считает дни сам, только если поле пустое.
+  function calculateWorkingDays(startValue, endValue) {
     const start = parseDateValue(startValue);
     const end = parseDateValue(endValue);
     if (!start || !end) return "";
     if (end < start) return "";
-    const msInDay = 24 * 60 * 60 * 1000;
-    const diff = Math.round((end - start) / msInDay);
-    return String(diff + 1);
+    let count = 0;
+    for (const day = new Date(start); day <= end; day.setDate(day.getDate() + 1)) {
+      const weekday = day.getDay();
+      if (weekday !== 0 && weekday !== 6) count += 1;
+    }
+    return String(count);
   }
 
   function updateDaysField() {
     if (!form) return;
     const startValue = getFieldValue(form, "startDate");
     const endValue = getFieldValue(form, "endDate");
-    const daysValue = calculateDays(startValue, endValue);
-    setFieldValue(form, "days", daysValue);
+    const earlyEndValue = getFieldValue(form, "earlyEndDate");
+    setFieldValue(form, "days", calculateWorkingDays(startValue, earlyEndValue || endValue));
   }
 
   function setPostalSameState(isChecked) {
@@ -307,14 +394,18 @@
     setFieldValue(form, "checkFormType", data.inspection?.formType);
     setFieldValue(form, "mzOrderNo", data.inspection?.mzOrder?.number);
     setFieldValue(form, "mzOrderDate", data.inspection?.mzOrder?.date);
+    setFieldValue(form, "mzOrderName", data.inspection?.mzOrder?.name);
+    setFieldValue(form, "chomiazOrderNo", data.inspection?.chomiazOrder?.number);
+    setFieldValue(form, "chomiazOrderDate", data.inspection?.chomiazOrder?.date);
     setFieldValue(form, "checkNo", data.inspection?.number);
     setFieldValue(form, "startDate", data.inspection?.period?.startDate);
     setFieldValue(form, "endDate", data.inspection?.period?.endDate);
+    setFieldValue(form, "earlyEndDate", data.inspection?.period?.earlyEndDate);
     setFieldValue(form, "days", data.inspection?.period?.days);
     setFieldValue(form, "letterNoLeft", data.inspection?.letter?.numberLeft);
     setFieldValue(form, "letterNoRight", data.inspection?.letter?.numberRight);
     setFieldValue(form, "letterDate", data.inspection?.letter?.date);
-    setFieldValue(form, "addressNoIndex", formatListValue(data.inspection?.addressNoIndex));
+    setFieldValue(form, "addressNoIndex", formatAddressList(data.inspection?.addressNoIndex));
     setFieldValue(form, "representative", formatListValue(data.inspection?.representative));
     if (authorizedInput) {
       authorizedInput.value = (data.inspection?.inspectors || []).join(", ");
@@ -364,12 +455,18 @@
         formType: normalizeSpace(getFieldValue(formRef, "checkFormType")),
         mzOrder: {
           number: normalizeSpace(getFieldValue(formRef, "mzOrderNo")),
-          date: normalizeSpace(getFieldValue(formRef, "mzOrderDate"))
+          date: normalizeSpace(getFieldValue(formRef, "mzOrderDate")),
+          name: normalizeSpace(getFieldValue(formRef, "mzOrderName"))
+        },
+        chomiazOrder: {
+          number: normalizeSpace(getFieldValue(formRef, "chomiazOrderNo")),
+          date: normalizeSpace(getFieldValue(formRef, "chomiazOrderDate"))
         },
         number: normalizeSpace(getFieldValue(formRef, "checkNo")),
         period: {
           startDate: normalizeSpace(getFieldValue(formRef, "startDate")),
           endDate: normalizeSpace(getFieldValue(formRef, "endDate")),
+          earlyEndDate: normalizeSpace(getFieldValue(formRef, "earlyEndDate")),
           days: normalizeSpace(getFieldValue(formRef, "days"))
         },
         letter: {
@@ -377,7 +474,7 @@
           numberRight: normalizeSpace(getFieldValue(formRef, "letterNoRight")),
           date: normalizeSpace(getFieldValue(formRef, "letterDate"))
         },
-        addressNoIndex: parseListFromName(formRef, "addressNoIndex"),
+        addressNoIndex: parseAddressList(getFieldValue(formRef, "addressNoIndex")),
         representative: parseListFromName(formRef, "representative"),
 
         inspectors: parseListFromField(authorizedInput),
@@ -409,6 +506,14 @@
     if (start && end && end < start) {
       return "Дата окончания проверки не может быть раньше даты начала.";
     }
+    const earlyEndValue = getFieldValue(form, "earlyEndDate");
+    const earlyEnd = parseDateValue(earlyEndValue);
+    if (earlyEndValue && !earlyEnd) {
+      return "Проверьте корректность даты досрочного окончания.";
+    }
+    if (earlyEnd && ((start && earlyEnd < start) || (end && earlyEnd > end))) {
+      return "Досрочное окончание должно быть в пределах срока проверки.";
+    }
     return null;
   }
 
@@ -425,8 +530,9 @@
   function hasAnyInspectionData(payload) {
     const i = payload.inspection;
     return (
-      i.formType || i.mzOrder.number || i.mzOrder.date || i.number ||
-      i.period.startDate || i.period.endDate || i.period.days ||
+      i.formType || i.mzOrder.number || i.mzOrder.date || i.mzOrder.name || i.number ||
+      i.chomiazOrder.number || i.chomiazOrder.date ||
+      i.period.startDate || i.period.endDate || i.period.earlyEndDate || i.period.days ||
       i.letter.numberLeft || i.letter.numberRight || i.letter.date ||
       (i.addressNoIndex && i.addressNoIndex.length) ||
       (i.representative && i.representative.length) ||
@@ -454,9 +560,9 @@
       }
 
       const ogrnValue = getFieldValue(form, "ogrn");
-      if (ogrnValue && !/^\d{13}$/.test(ogrnValue)) {
-        if (window.AppDialog?.openDialog) window.AppDialog.openDialog("ОГРН должен содержать 13 цифр.");
-        else alert("ОГРН должен содержать 13 цифр.");
+      if (ogrnValue && !/^(\d{13}|\d{15})$/.test(ogrnValue)) {
+        if (window.AppDialog?.openDialog) window.AppDialog.openDialog("ОГРН должен содержать 13 цифр, ОГРНИП — 15.");
+        else alert("ОГРН должен содержать 13 цифр, ОГРНИП — 15.");
         return;
       }
 
@@ -493,21 +599,28 @@
         else alert("Номер проверки должен быть уникальным.");
         return;
       }
+      let saved = null;
       try {
         if (existingId) {
-          await window.ChecksStore?.update?.(existingId, payload);
+          saved = await window.ChecksStore?.update?.(existingId, payload);
         } else {
-          await window.ChecksStore?.create?.(payload);
+          saved = await window.ChecksStore?.create?.(payload);
         }
         window.dispatchEvent(new CustomEvent("checks:updated"));
       } catch (error) {
+        const details = (error.fields || []).map((item) => item.message).filter(Boolean);
+        const message = [error.message || "Ошибка сохранения проверки.", ...details].join("\n");
         if (window.AppDialog?.openDialog) {
-          window.AppDialog.openDialog(error.message || "Ошибка сохранения проверки.", "Проверки");
+          window.AppDialog.openDialog(message, "Проверки");
         }
         return;
       }
 
       closeModal();
+      const conflicts = (saved?.conflicts || []).map((item) => item.message).filter(Boolean);
+      if (conflicts.length && window.AppDialog?.openDialog) {
+        window.AppDialog.openDialog(["Проверка сохранена, но инспекторы заняты:", ...conflicts].join("\n"), "Проверки");
+      }
     });
   }
 
@@ -528,6 +641,7 @@
   if (form) {
     const startField = form.elements["startDate"];
     const endField = form.elements["endDate"];
+    const earlyEndField = form.elements["earlyEndDate"];
     if (startField instanceof HTMLInputElement) {
       startField.addEventListener("change", updateDaysField);
       startField.addEventListener("input", updateDaysField);
@@ -536,6 +650,10 @@
       endField.addEventListener("change", updateDaysField);
       endField.addEventListener("input", updateDaysField);
     }
+    if (earlyEndField instanceof HTMLInputElement) {
+      earlyEndField.addEventListener("change", updateDaysField);
+      earlyEndField.addEventListener("input", updateDaysField);
+    }
   }
 
   if (form) {
@@ -545,6 +663,34 @@
         handleOgrnAutofill(ogrnField.value);
       });
     }
+
+    const orgNameField = form.elements["orgName"];
+    const orgNameDatalist = document.getElementById("orgNameOptions");
+    if (orgNameField instanceof HTMLInputElement && orgNameDatalist) {
+      orgNameField.addEventListener("input", () => {
+        const ogrn = orgSuggestionOgrns.get(orgNameField.value);
+        if (ogrn) {
+          handleOgrnAutofill(ogrn);
+          return;
+        }
+        clearTimeout(orgSuggestTimer);
+        orgSuggestTimer = setTimeout(() => updateOrgNameSuggestions(orgNameDatalist, orgNameField.value), 300);
+      });
+    }
+
+    const addressSearchField = form.elements["addressSearch"];
+    const addressDatalist = document.getElementById("addressOptions");
+    if (addressSearchField instanceof HTMLInputElement && addressDatalist) {
+      addressSearchField.addEventListener("input", () => {
+        if (addressSuggestions.has(addressSearchField.value)) {
+          addAddress(addressSearchField.value);
+          addressSearchField.value = "";
+          return;
+        }
+        clearTimeout(addressSuggestTimer);
+        addressSuggestTimer = setTimeout(() => updateAddressSuggestions(addressDatalist, addressSearchField.value), 300);
+      });
+    }
   }
 
   if (bossRoleInput && bossRoleDatalist) {
@@ -4,15 +4,95 @@ SET time_zone = '+00:00';
 CREATE TABLE act (
     id INT AUTO_INCREMENT PRIMARY KEY,
 
+    -- проверка, скопированная в эту (POST /inspections/{id}/clone)
+    source_act_id INT NULL,
+
     created_by TEXT,
     updated_by TEXT,
 
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_act_source
+        FOREIGN KEY (source_act_id) REFERENCES act(id) ON DELETE SET NULL
+);
+
+-- справочник организаций, одна запись на ОГРН
+CREATE TABLE organizations (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    full_name TEXT NOT NULL,
+    short_name TEXT,
+    ogrn VARCHAR(15) NOT NULL UNIQUE,
+    legal_address TEXT NOT NULL,
+    postal_address TEXT,
+
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
+-- руководители организаций из справочника; записи не удаляются, у прежних
+-- руководителей закрывается срок полномочий (effective_to включительно)
+CREATE TABLE leader (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    organization_id INT NOT NULL,
+
+    position VARCHAR(255) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    first_name VARCHAR(100) NOT NULL,
+    middle_name VARCHAR(100) NOT NULL DEFAULT '',
+    initials_name_im VARCHAR(150) NOT NULL DEFAULT '',
+    initials_name_dat VARCHAR(150) NOT NULL DEFAULT '',
+
+    effective_from DATE NOT NULL,
+    effective_to DATE NULL,
+
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_leader_term (organization_id, effective_from),
+    -- история руководителей не удаляется вместе с организацией
+    CONSTRAINT fk_leader_organization
+        FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE RESTRICT
+);
+
+-- журнал объединения дублей справочника; sources хранит удаленные записи,
+-- ссылки актов и руководителей на них и их расхождения с Dadata, чтобы
+-- объединение можно было отменить
+CREATE TABLE organization_merge (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    target_id INT NOT NULL,
+    sources JSON NOT NULL,
+    merged_by VARCHAR(255) NOT NULL,
+    merged_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    undone_by VARCHAR(255),
+    undone_at DATETIME NULL
+);
+
+-- расхождения справочника с Dadata, найденные фоновым обновлением; pending
+-- ждут решения администратора, отклоненное значение повторно не предлагается.
+-- leader хранит нового руководителя, когда field = 'leader'
+CREATE TABLE organization_change (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    organization_id INT NOT NULL,
+    field VARCHAR(32) NOT NULL,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL,
+    leader JSON NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    detected_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_by VARCHAR(255),
+    reviewed_at DATETIME NULL,
+
+    INDEX idx_organization_change (organization_id, field, status),
+    INDEX idx_organization_change_status (status),
+    CONSTRAINT fk_organization_change
+        FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+-- данные организации на дату проверки; organization_id ссылается на справочник
 CREATE TABLE act_organization (
     act_id INT PRIMARY KEY,
+    organization_id INT NULL,
 
     organization_full_name TEXT NOT NULL,
     organization_short_name TEXT,
@@ -20,8 +100,11 @@ CREATE TABLE act_organization (
     organization_legal_address TEXT NOT NULL,
     organization_postal_address TEXT,
 
+    INDEX idx_act_organization_ogrn (organization_ogrn),
     CONSTRAINT fk_act_organization
-        FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+        FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE,
+    CONSTRAINT fk_act_organization_registry
+        FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE SET NULL
 );
 
 CREATE TABLE act_head (
@@ -68,6 +151,8 @@ CREATE TABLE act_inspection (
 
     -- JSON-массивы
     addresses JSON NOT NULL DEFAULT (JSON_ARRAY()),
+    -- адреса в том виде, в каком их ввели; NULL, если ADDRESS_KEEP_RAW выключен
+    addresses_raw JSON NULL,
     authorized_persons JSON NOT NULL DEFAULT (JSON_ARRAY()),
     signatories JSON NOT NULL DEFAULT (JSON_ARRAY()),
     representatives JSON NOT NULL DEFAULT (JSON_ARRAY()),
@@ -76,12 +161,88 @@ CREATE TABLE act_inspection (
         FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
 );
 
+-- Поисковый индекс по реестру: склеенные наименования, адреса, ФИО руководителя
+-- и номера. Заполняется приложением при сохранении проверки.
+CREATE TABLE act_search (
+    act_id INT PRIMARY KEY,
+
+    content TEXT NOT NULL,
+
+    FULLTEXT INDEX ft_act_search_content (content) WITH PARSER ngram,
+
+    CONSTRAINT fk_act_search
+        FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+);
+
+-- выявленные при проверке нарушения и недостатки (для годового отчета)
+CREATE TABLE inspection_finding (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    act_id INT NOT NULL,
+    kind ENUM('нарушение', 'недостаток') NOT NULL,
+    -- область проверки (верхний уровень справочника) и конкретный пункт
+    area VARCHAR(255) NOT NULL,
+    item VARCHAR(512) NOT NULL DEFAULT '',
+    description TEXT,
+
+    INDEX idx_inspection_finding_act (act_id),
+    INDEX idx_inspection_finding_area (area, item),
+    CONSTRAINT fk_inspection_finding_act
+        FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+);
+
+-- журнал отправки документов по почте (POST /inspections/{id}/documents/{kind}/send)
+CREATE TABLE document_send (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    act_id INT NOT NULL,
+    document_kind VARCHAR(32) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    status ENUM('sent', 'failed') NOT NULL,
+    message_id VARCHAR(255) NULL,
+    error TEXT NULL,
+    sent_by TEXT,
+    sent_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_document_send_act (act_id, sent_at),
+    CONSTRAINT fk_document_send_act
+        FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+);
+
 CREATE TABLE users (
     id INT AUTO_INCREMENT PRIMARY KEY,
     fio TEXT NOT NULL,
     login VARCHAR(255) NOT NULL UNIQUE,
     password TEXT NOT NULL,
-    role VARCHAR(32) NOT NULL DEFAULT 'user'
+    role VARCHAR(32) NOT NULL DEFAULT 'user',
+
+    -- секрет в URL календаря .ics (GET /api/calendar/feed/{token}.ics)
+    calendar_token VARCHAR(64) NULL UNIQUE,
+
+    -- адрес для напоминаний о сроках
+    email VARCHAR(255) NULL
+);
+
+-- отметки по срокам проверки (правила в cmd/deadlines.go):
+-- выполнение и отправленные напоминания
+CREATE TABLE deadline_status (
+    act_id INT NOT NULL,
+    rule_code VARCHAR(64) NOT NULL,
+
+    completed_at DATETIME NULL,
+    completed_by TEXT,
+    due_notified_at DATETIME NULL,
+    overdue_notified_at DATETIME NULL,
+
+    PRIMARY KEY (act_id, rule_code),
+    CONSTRAINT fk_deadline_status_act
+        FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE CASCADE
+);
+
+-- день первой рассылки напоминаний; сроки, просроченные раньше него, не
+-- рассылаются
+CREATE TABLE reminder_state (
+    id INT PRIMARY KEY,
+    notify_since DATE NOT NULL
 );
 
 CREATE TABLE employees (
@@ -89,6 +250,44 @@ CREATE TABLE employees (
     fio TEXT NOT NULL
 );
 
+-- Годовой план плановых проверок и его позиции
+CREATE TABLE inspection_plan (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    year INT NOT NULL UNIQUE,
+    title TEXT NOT NULL,
+    approved_at DATETIME NULL,
+
+    created_by TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE inspection_plan_item (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    plan_id INT NOT NULL,
+
+    organization_ogrn VARCHAR(15) NOT NULL,
+    organization_name TEXT,
+    planned_month TINYINT NOT NULL,
+    inspection_type ENUM(
+        'плановая документарная',
+        'плановая выездная'
+    ) NOT NULL,
+    employee_id INT NULL,
+
+    -- проверка, созданная по этой позиции (POST /plans/{id}/items/{itemId}/inspection)
+    act_id INT NULL,
+
+    INDEX idx_plan_item_ogrn (organization_ogrn),
+
+    CONSTRAINT fk_plan_item_plan
+        FOREIGN KEY (plan_id) REFERENCES inspection_plan(id) ON DELETE CASCADE,
+    CONSTRAINT fk_plan_item_employee
+        FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE SET NULL,
+    CONSTRAINT fk_plan_item_act
+        FOREIGN KEY (act_id) REFERENCES act(id) ON DELETE SET NULL
+);
+
 CREATE TABLE info (
     shortcut TEXT,
     definition TEXT,
@@ -112,6 +311,35 @@ CREATE TABLE verification_areas_store (
     updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
+-- кэш ответов DaData; ключ - метод API и ОГРН
+CREATE TABLE dadata_cache (
+    cache_key VARCHAR(255) PRIMARY KEY,
+    response MEDIUMBLOB NOT NULL,
+    fetched_at DATETIME NOT NULL
+);
+
+-- ответы на POST-запросы с заголовком Idempotency-Key; пустой response_status
+-- означает, что первый запрос еще выполняется
+CREATE TABLE idempotency_key (
+    scope VARCHAR(255) NOT NULL,
+    idem_key VARCHAR(255) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    response_status SMALLINT NULL,
+    response_headers JSON,
+    response_body LONGBLOB,
+    created_at DATETIME NOT NULL,
+
+    PRIMARY KEY (scope, idem_key, route),
+    INDEX idx_idempotency_key_created (created_at)
+);
+
+-- миграции, уже примененные сервером к этой базе (cmd/migrations.go)
+CREATE TABLE schema_migration (
+    name VARCHAR(255) PRIMARY KEY,
+    applied_at DATETIME NOT NULL
+);
+
 INSERT INTO users (fio, login, password, role)
 VALUES
 ('Администратор', 'admin', 'admin', 'admin'),